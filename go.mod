@@ -20,7 +20,9 @@ require (
 	github.com/pkg/errors v0.8.0 // indirect
 	github.com/rollbar/rollbar-go v1.1.0
 	github.com/sirupsen/logrus v1.2.0
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974
 	golang.org/x/oauth2 v0.0.0-20181203162652-d668ce993890
+	modernc.org/sqlite v1.17.3
 )
 
-go 1.13
+go 1.16
@@ -27,6 +27,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Subcommands, e.g. `scumbago links canonicalize`, run and exit without connecting to IRC.
+	if flag.NArg() > 0 {
+		err := bot.RunCLI(os.Stdout, flag.Args())
+		bot.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, os.Interrupt)
 	go func() {
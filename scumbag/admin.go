@@ -1,16 +1,17 @@
 package scumbag
 
 import (
-	"database/sql"
 	"strings"
 
 	irc "github.com/fluffle/goirc/client"
 )
 
 const (
-	cmdIgnore   = "ignore"
-	cmdUnignore = "unignore"
-	cmdNick     = "nick"
+	cmdIgnore     = "ignore"
+	cmdUnignore   = "unignore"
+	cmdNick       = "nick"
+	cmdPurgeLinks = "purge-links"
+	cmdDBStatus   = "db-status"
 )
 
 // AdminCommand handles bot admin.
@@ -47,6 +48,11 @@ func (cmd *AdminCommand) Run(args ...string) {
 
 	fields := strings.Fields(args[0])
 
+	if len(fields) == 1 && fields[0] == cmdDBStatus {
+		cmd.bot.Msg(cmd.conn, channel, "%s", cmd.bot.databaseStatus())
+		return
+	}
+
 	if len(fields) > 1 {
 		command := fields[0]
 		commandArgs := strings.Join(fields[1:], " ")
@@ -59,6 +65,8 @@ func (cmd *AdminCommand) Run(args ...string) {
 		case cmdNick:
 			client := cmd.bot.ircClients[cmd.conn.Config().Server]
 			client.Nick(commandArgs)
+		case cmdPurgeLinks:
+			cmd.purgeLinks(cmd.conn.Config().Server, channel, commandArgs)
 		}
 	} else {
 		cmd.bot.Log.WithField("args", args).Error("AdminCommand.Run(): Could not get command args")
@@ -66,23 +74,32 @@ func (cmd *AdminCommand) Run(args ...string) {
 }
 
 func (cmd *AdminCommand) ignoreNick(server, channel, nick string) {
-	var nickMatch string
-	err := cmd.bot.DB.QueryRow("SELECT nick FROM ignored_nicks WHERE server=$1 AND nick=$2;", server, nick).Scan(&nickMatch)
-	if err == sql.ErrNoRows {
-		// New nick ignore; create one.
-		if _, insertErr := cmd.bot.DB.Exec("INSERT INTO ignored_nicks(server, nick, created_at) VALUES($1, $2, $3) RETURNING id;", server, nick, cmd.line.Time); insertErr != nil {
-			cmd.bot.LogError("AdminCommand.ignoreNick()", insertErr)
-		} else {
-			cmd.bot.Msg(cmd.conn, channel, "Ignoring: "+nick)
-		}
+	ignored, err := cmd.bot.Ignores.IgnoreNick(server, nick, cmd.line.Time)
+	if err != nil {
+		cmd.bot.LogError("AdminCommand.ignoreNick()", err)
+	} else if ignored {
+		cmd.bot.Msg(cmd.conn, channel, "Ignoring: "+nick)
 	}
 }
 
 func (cmd *AdminCommand) unignoreNick(server, channel, nick string) {
-	_, err := cmd.bot.DB.Exec("DELETE FROM ignored_nicks WHERE server=$1 AND nick=$2;", server, nick)
-	if err != nil {
+	if err := cmd.bot.Ignores.UnignoreNick(server, nick); err != nil {
 		cmd.bot.LogError("AdminCommand.unignoreNick()", err)
 	} else {
 		cmd.bot.Msg(cmd.conn, channel, "Unignoring: "+nick)
 	}
 }
+
+func (cmd *AdminCommand) purgeLinks(server, channel, nick string) {
+	deleted, err := cmd.bot.PurgeLinks(&LinkPurge{
+		LinkFilter:  LinkFilter{Server: server, Nick: nick},
+		RequestedBy: cmd.line.Nick,
+		Reason:      purgeReasonAdmin,
+	})
+	if err != nil {
+		cmd.bot.LogError("AdminCommand.purgeLinks()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "Deleted %d links from %s.", deleted, nick)
+}
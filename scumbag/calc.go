@@ -0,0 +1,771 @@
+package scumbag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	// Integers are exact up to this many bits.
+	calcMaxBits      = 65536
+	calcMaxDigits    = 300
+	calcMaxFactorial = 1000
+	calcMaxLength    = 400
+	// Variables kept for each user, including "ans".
+	calcMaxVariables = 20
+
+	calcAnswer = "ans"
+	calcVars   = "vars"
+)
+
+var calcHelp = []string{
+	cmdCalc + " <expression> -- calculate, e.g. (1 + 2) * 3, 2^100, 20!, sqrt(2), sin(pi/2), log(8, 2), 0xff | 0b1010",
+	cmdCalc + " <expression> in hex|bin|oct -- show an integer result in another base",
+	cmdCalc + " x = <expression> -- set a variable of your own, used as x later; the last result is " + calcAnswer,
+	cmdCalc + " " + calcVars + " -- list your variables",
+}
+
+var (
+	errCalcDivideByZero = errors.New("Division by zero.")
+	errCalcTooBig       = errors.New("That's too big.")
+	errCalcNaN          = errors.New("Not a number.")
+)
+
+// calcBaseRegexp matches a trailing "in hex" (or "to binary", ...) asking for another base.
+var calcBaseRegexp = regexp.MustCompile(`(?i)\s+(?:in|to|as)\s+(hex|hexadecimal|bin|binary|oct|octal|dec|decimal)\s*$`)
+
+var calcConstants = map[string]float64{
+	"pi":  math.Pi,
+	"tau": 2 * math.Pi,
+	"e":   math.E,
+	"phi": math.Phi,
+}
+
+// Functions of one float; see calcCall() for the rest.
+var calcFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"cbrt":  math.Cbrt,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log2":  math.Log2,
+	"log10": math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"sinh":  math.Sinh,
+	"cosh":  math.Cosh,
+	"tanh":  math.Tanh,
+}
+
+// calcValue is an exact integer or, once something isn't one, a float.
+type calcValue struct {
+	// nil for floats.
+	i *big.Int
+	f float64
+}
+
+func calcInt(i *big.Int) (calcValue, error) {
+	if i.BitLen() > calcMaxBits {
+		return calcValue{}, errCalcTooBig
+	}
+	return calcValue{i: i}, nil
+}
+
+func calcFloat(f float64) (calcValue, error) {
+	switch {
+	case math.IsNaN(f):
+		return calcValue{}, errCalcNaN
+	case math.IsInf(f, 0):
+		return calcValue{}, errCalcTooBig
+	}
+	return calcValue{f: f}, nil
+}
+
+func (v calcValue) float() float64 {
+	if v.i == nil {
+		return v.f
+	}
+	f, _ := new(big.Float).SetInt(v.i).Float64()
+	return f
+}
+
+// String shows the value in decimal, in scientific notation if it has more
+// than calcMaxDigits digits.
+func (v calcValue) String() string {
+	if v.i == nil {
+		return strconv.FormatFloat(v.f, 'g', 12, 64)
+	}
+
+	digits := v.i.String()
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= calcMaxDigits {
+		return sign + digits
+	}
+	return fmt.Sprintf("%s%s.%se+%d (%d digits)", sign, digits[:1], digits[1:12], len(digits)-1, len(digits))
+}
+
+// format shows the value in base 2, 8, 10 or 16. Other bases have no
+// scientific notation, so past calcMaxDigits digits they're an error.
+func (v calcValue) format(base int) (string, error) {
+	if base == 10 {
+		return v.String(), nil
+	}
+	if v.i == nil {
+		return "", errors.New("Only integers can be shown in another base.")
+	}
+
+	prefix := map[int]string{2: "0b", 8: "0o", 16: "0x"}[base]
+	text := v.i.Text(base)
+	if len(strings.TrimPrefix(text, "-")) > calcMaxDigits {
+		return "", errCalcTooBig
+	}
+	if strings.HasPrefix(text, "-") {
+		return "-" + prefix + text[1:], nil
+	}
+	return prefix + text, nil
+}
+
+type calcToken struct {
+	// 'n'umber, 'i'dentifier or 'o'perator; 0 past the end.
+	kind  byte
+	text  string
+	value calcValue
+}
+
+// lexCalc splits `expr` into tokens. Numbers can be decimal, 1.5e3, or
+// 0x/0b/0o integers.
+func lexCalc(expr string) ([]calcToken, error) {
+	var tokens []calcToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+
+		case isDigit(c) || (c == '.' && i+1 < len(expr) && isDigit(expr[i+1])):
+			j := i
+			if c == '0' && i+1 < len(expr) && strings.ContainsRune("xXbBoO", rune(expr[i+1])) {
+				for j = i + 2; j < len(expr) && (isDigit(expr[j]) || isLetter(expr[j])); j++ {
+				}
+				n, ok := new(big.Int).SetString(strings.ToLower(expr[i:j]), 0)
+				if !ok {
+					return nil, fmt.Errorf("Bad number: %s", expr[i:j])
+				}
+				tokens = append(tokens, calcToken{kind: 'n', text: expr[i:j], value: calcValue{i: n}})
+				i = j
+				continue
+			}
+
+			isFloat := false
+			for ; j < len(expr) && (isDigit(expr[j]) || expr[j] == '.'); j++ {
+				isFloat = isFloat || expr[j] == '.'
+			}
+			if j < len(expr) && (expr[j] == 'e' || expr[j] == 'E') {
+				k := j + 1
+				if k < len(expr) && (expr[k] == '+' || expr[k] == '-') {
+					k++
+				}
+				if k < len(expr) && isDigit(expr[k]) {
+					for j = k; j < len(expr) && isDigit(expr[j]); j++ {
+					}
+					isFloat = true
+				}
+			}
+
+			text := expr[i:j]
+			token := calcToken{kind: 'n', text: text}
+			if isFloat {
+				f, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("Bad number: %s", text)
+				}
+				if token.value, err = calcFloat(f); err != nil {
+					return nil, err
+				}
+			} else {
+				token.value.i, _ = new(big.Int).SetString(text, 10)
+			}
+			tokens = append(tokens, token)
+			i = j
+
+		case isLetter(c) || c == '_':
+			j := i
+			for ; j < len(expr) && (isLetter(expr[j]) || isDigit(expr[j]) || expr[j] == '_'); j++ {
+			}
+			tokens = append(tokens, calcToken{kind: 'i', text: strings.ToLower(expr[i:j])})
+			i = j
+
+		default:
+			if i+1 < len(expr) {
+				if op := expr[i : i+2]; op == "**" || op == "<<" || op == ">>" {
+					tokens = append(tokens, calcToken{kind: 'o', text: op})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/%^()!,=&|", rune(c)) {
+				return nil, fmt.Errorf("Unexpected %q.", c)
+			}
+			tokens = append(tokens, calcToken{kind: 'o', text: string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// calcReserved returns true for the names of constants and functions, which can't be variables.
+func calcReserved(name string) bool {
+	if _, ok := calcConstants[name]; ok {
+		return true
+	}
+	if _, ok := calcFunctions[name]; ok {
+		return true
+	}
+	switch name {
+	case "abs", "floor", "ceil", "round", "trunc", "min", "max", "log", calcVars:
+		return true
+	}
+	return false
+}
+
+// evalCalc evaluates `expr` with the user's `vars`. For "x = <expression>",
+// `assigned` is "x".
+func evalCalc(expr string, vars map[string]calcValue) (value calcValue, assigned string, err error) {
+	if len(expr) > calcMaxLength {
+		return calcValue{}, "", errors.New("That's too long.")
+	}
+
+	tokens, err := lexCalc(expr)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	if len(tokens) <= 0 {
+		return calcValue{}, "", errors.New("Calculate what?")
+	}
+
+	parser := &calcParser{tokens: tokens, vars: vars}
+	if len(tokens) > 2 && tokens[0].kind == 'i' && tokens[1].text == "=" {
+		assigned = tokens[0].text
+		if calcReserved(assigned) {
+			return calcValue{}, "", fmt.Errorf("%s can't be a variable.", assigned)
+		}
+		parser.pos = 2
+	}
+
+	if value, err = parser.expr(); err != nil {
+		return calcValue{}, "", err
+	}
+	if token := parser.peek(); token.kind != 0 {
+		return calcValue{}, "", fmt.Errorf("Unexpected %q.", token.text)
+	}
+	return value, assigned, nil
+}
+
+// calcParser evaluates tokens as it parses them, lowest precedence first:
+// |, &, << >>, + -, * / %, unary -, ^ (right associative), postfix !.
+type calcParser struct {
+	tokens []calcToken
+	pos    int
+	vars   map[string]calcValue
+}
+
+func (p *calcParser) peek() calcToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return calcToken{}
+}
+
+// accept consumes the next token if it's one of `ops`, and returns it.
+func (p *calcParser) accept(ops ...string) string {
+	token := p.peek()
+	if token.kind != 'o' {
+		return ""
+	}
+	for _, op := range ops {
+		if token.text == op {
+			p.pos++
+			return op
+		}
+	}
+	return ""
+}
+
+// binary parses left associative `ops` between operands parsed by `next`.
+func (p *calcParser) binary(next func() (calcValue, error), ops ...string) (calcValue, error) {
+	left, err := next()
+	if err != nil {
+		return left, err
+	}
+
+	for {
+		op := p.accept(ops...)
+		if op == "" {
+			return left, nil
+		}
+
+		right, err := next()
+		if err != nil {
+			return right, err
+		}
+		if left, err = calcOperate(op, left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *calcParser) expr() (calcValue, error) {
+	return p.binary(p.bitAnd, "|")
+}
+
+func (p *calcParser) bitAnd() (calcValue, error) {
+	return p.binary(p.shift, "&")
+}
+
+func (p *calcParser) shift() (calcValue, error) {
+	return p.binary(p.sum, "<<", ">>")
+}
+
+func (p *calcParser) sum() (calcValue, error) {
+	return p.binary(p.product, "+", "-")
+}
+
+func (p *calcParser) product() (calcValue, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+func (p *calcParser) unary() (calcValue, error) {
+	switch p.accept("-", "+") {
+	case "-":
+		value, err := p.unary()
+		if err != nil {
+			return value, err
+		}
+		if value.i != nil {
+			return calcValue{i: new(big.Int).Neg(value.i)}, nil
+		}
+		return calcValue{f: -value.f}, nil
+	case "+":
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *calcParser) power() (calcValue, error) {
+	base, err := p.postfix()
+	if err != nil {
+		return base, err
+	}
+	if p.accept("^", "**") == "" {
+		return base, nil
+	}
+
+	exponent, err := p.unary()
+	if err != nil {
+		return exponent, err
+	}
+	return calcOperate("^", base, exponent)
+}
+
+func (p *calcParser) postfix() (calcValue, error) {
+	value, err := p.primary()
+	for err == nil && p.accept("!") != "" {
+		value, err = calcFactorial(value)
+	}
+	return value, err
+}
+
+func (p *calcParser) primary() (calcValue, error) {
+	token := p.peek()
+	p.pos++
+
+	switch {
+	case token.kind == 0:
+		return calcValue{}, errors.New("Unexpected end.")
+
+	case token.kind == 'n':
+		return token.value, nil
+
+	case token.kind == 'i':
+		if p.accept("(") != "" {
+			var args []calcValue
+			if p.accept(")") == "" {
+				for {
+					arg, err := p.expr()
+					if err != nil {
+						return arg, err
+					}
+					args = append(args, arg)
+					if p.accept(",") == "" {
+						break
+					}
+				}
+				if p.accept(")") == "" {
+					return calcValue{}, errors.New("Missing ).")
+				}
+			}
+			return calcCall(token.text, args)
+		}
+
+		if value, ok := p.vars[token.text]; ok {
+			return value, nil
+		}
+		if f, ok := calcConstants[token.text]; ok {
+			return calcValue{f: f}, nil
+		}
+		return calcValue{}, fmt.Errorf("Unknown variable %q.", token.text)
+
+	case token.text == "(":
+		value, err := p.expr()
+		if err != nil {
+			return value, err
+		}
+		if p.accept(")") == "" {
+			return calcValue{}, errors.New("Missing ).")
+		}
+		return value, nil
+	}
+
+	return calcValue{}, fmt.Errorf("Unexpected %q.", token.text)
+}
+
+// calcOperate applies a binary operator, keeping integers exact where it can.
+func calcOperate(op string, a, b calcValue) (calcValue, error) {
+	if a.i != nil && b.i != nil {
+		switch op {
+		case "+":
+			return calcInt(new(big.Int).Add(a.i, b.i))
+		case "-":
+			return calcInt(new(big.Int).Sub(a.i, b.i))
+		case "*":
+			return calcInt(new(big.Int).Mul(a.i, b.i))
+		case "/":
+			if b.i.Sign() == 0 {
+				return calcValue{}, errCalcDivideByZero
+			}
+			quotient, remainder := new(big.Int).QuoRem(a.i, b.i, new(big.Int))
+			if remainder.Sign() == 0 {
+				return calcInt(quotient)
+			}
+		case "%":
+			if b.i.Sign() == 0 {
+				return calcValue{}, errCalcDivideByZero
+			}
+			return calcInt(new(big.Int).Rem(a.i, b.i))
+		case "^", "**":
+			if b.i.Sign() >= 0 {
+				if a.i.CmpAbs(big.NewInt(1)) <= 0 {
+					if a.i.Sign() < 0 && b.i.Bit(0) == 1 {
+						return calcValue{i: big.NewInt(-1)}, nil
+					}
+					if a.i.Sign() == 0 && b.i.Sign() > 0 {
+						return calcValue{i: big.NewInt(0)}, nil
+					}
+					return calcValue{i: big.NewInt(1)}, nil
+				}
+				if !b.i.IsInt64() || b.i.Int64() > calcMaxBits || int64(a.i.BitLen()-1)*b.i.Int64() > calcMaxBits {
+					return calcValue{}, errCalcTooBig
+				}
+				return calcInt(new(big.Int).Exp(a.i, b.i, nil))
+			}
+		case "&":
+			return calcValue{i: new(big.Int).And(a.i, b.i)}, nil
+		case "|":
+			return calcValue{i: new(big.Int).Or(a.i, b.i)}, nil
+		case "<<", ">>":
+			if b.i.Sign() < 0 || !b.i.IsInt64() || b.i.Int64() > calcMaxBits {
+				return calcValue{}, fmt.Errorf("Can't shift by %s.", b.i)
+			}
+			if op == ">>" {
+				return calcValue{i: new(big.Int).Rsh(a.i, uint(b.i.Int64()))}, nil
+			}
+			return calcInt(new(big.Int).Lsh(a.i, uint(b.i.Int64())))
+		}
+	}
+
+	x, y := a.float(), b.float()
+	switch op {
+	case "+":
+		return calcFloat(x + y)
+	case "-":
+		return calcFloat(x - y)
+	case "*":
+		return calcFloat(x * y)
+	case "/":
+		if y == 0 {
+			return calcValue{}, errCalcDivideByZero
+		}
+		return calcFloat(x / y)
+	case "%":
+		if y == 0 {
+			return calcValue{}, errCalcDivideByZero
+		}
+		return calcFloat(math.Mod(x, y))
+	case "^", "**":
+		return calcFloat(math.Pow(x, y))
+	}
+	return calcValue{}, fmt.Errorf("%s needs integers.", op)
+}
+
+func calcFactorial(value calcValue) (calcValue, error) {
+	if value.i == nil || value.i.Sign() < 0 {
+		return calcValue{}, errors.New("Factorials need a whole number.")
+	}
+	if !value.i.IsInt64() || value.i.Int64() > calcMaxFactorial {
+		return calcValue{}, errCalcTooBig
+	}
+	return calcInt(new(big.Int).MulRange(1, value.i.Int64()))
+}
+
+// calcCall calls the function `name`.
+func calcCall(name string, args []calcValue) (calcValue, error) {
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s() takes %d argument(s).", name, n)
+		}
+		return nil
+	}
+
+	switch name {
+	case "abs":
+		if err := arity(1); err != nil {
+			return calcValue{}, err
+		}
+		if args[0].i != nil {
+			return calcValue{i: new(big.Int).Abs(args[0].i)}, nil
+		}
+		return calcFloat(math.Abs(args[0].f))
+
+	case "floor", "ceil", "round", "trunc":
+		if err := arity(1); err != nil {
+			return calcValue{}, err
+		}
+		if args[0].i != nil {
+			return args[0], nil
+		}
+
+		rounded := map[string]func(float64) float64{
+			"floor": math.Floor,
+			"ceil":  math.Ceil,
+			"round": math.Round,
+			"trunc": math.Trunc,
+		}[name](args[0].f)
+		i, _ := big.NewFloat(rounded).Int(nil)
+		return calcInt(i)
+
+	case "min", "max":
+		if len(args) <= 0 {
+			return calcValue{}, fmt.Errorf("%s() takes at least one argument.", name)
+		}
+		best := args[0]
+		for _, arg := range args[1:] {
+			cmp := calcCompare(arg, best)
+			if (name == "min" && cmp < 0) || (name == "max" && cmp > 0) {
+				best = arg
+			}
+		}
+		return best, nil
+
+	case "log":
+		// log(x) is base 10, log(x, base) any other.
+		if len(args) == 2 {
+			return calcFloat(math.Log(args[0].float()) / math.Log(args[1].float()))
+		}
+		if err := arity(1); err != nil {
+			return calcValue{}, err
+		}
+		return calcFloat(math.Log10(args[0].float()))
+	}
+
+	fn, ok := calcFunctions[name]
+	if !ok {
+		return calcValue{}, fmt.Errorf("Unknown function %s().", name)
+	}
+	if err := arity(1); err != nil {
+		return calcValue{}, err
+	}
+	return calcFloat(fn(args[0].float()))
+}
+
+func calcCompare(a, b calcValue) int {
+	if a.i != nil && b.i != nil {
+		return a.i.Cmp(b.i)
+	}
+
+	x, y := a.float(), b.float()
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// calcVariables are each user's variables, kept until the bot restarts.
+type calcVariables struct {
+	sync.Mutex
+	users map[string]map[string]calcValue
+}
+
+func newCalcVariables() *calcVariables {
+	return &calcVariables{users: make(map[string]map[string]calcValue)}
+}
+
+// get returns a copy of the user's variables.
+func (c *calcVariables) get(server, nick string) map[string]calcValue {
+	c.Lock()
+	defer c.Unlock()
+
+	vars := make(map[string]calcValue)
+	for name, value := range c.users[server+" "+strings.ToLower(nick)] {
+		vars[name] = value
+	}
+	return vars
+}
+
+// set sets one of the user's variables, unless they already have calcMaxVariables.
+func (c *calcVariables) set(server, nick, name string, value calcValue) error {
+	c.Lock()
+	defer c.Unlock()
+
+	key := server + " " + strings.ToLower(nick)
+	vars := c.users[key]
+	if vars == nil {
+		vars = make(map[string]calcValue)
+		c.users[key] = vars
+	}
+
+	if _, ok := vars[name]; !ok && len(vars) >= calcMaxVariables {
+		return fmt.Errorf("You can have %d variables at most.", calcMaxVariables)
+	}
+	vars[name] = value
+	return nil
+}
+
+// calc evaluates `expr` for `nick`, who can end it with "in hex" and the like.
+// The result is saved in their "ans" variable.
+func (bot *Scumbag) calc(server, nick, expr string) (string, error) {
+	base := 10
+	if match := calcBaseRegexp.FindStringSubmatch(expr); match != nil {
+		expr = expr[:len(expr)-len(match[0])]
+		base = map[string]int{"hex": 16, "bin": 2, "oct": 8, "dec": 10}[strings.ToLower(match[1])[:3]]
+	}
+
+	value, assigned, err := evalCalc(expr, bot.calcVariables.get(server, nick))
+	if err != nil {
+		return "", err
+	}
+
+	result, err := value.format(base)
+	if err != nil {
+		return "", err
+	}
+
+	if err := bot.calcVariables.set(server, nick, calcAnswer, value); err != nil {
+		return "", err
+	}
+	if assigned != "" {
+		if err := bot.calcVariables.set(server, nick, assigned, value); err != nil {
+			return "", err
+		}
+		return assigned + " = " + result, nil
+	}
+	return result, nil
+}
+
+// CalcCommand evaluates math expressions without leaving the bot.
+type CalcCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewCalcCommand returns a new CalcCommand instance.
+func NewCalcCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *CalcCommand {
+	return &CalcCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *CalcCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("CalcCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	expr := strings.TrimSpace(args[0])
+
+	if expr == calcVars {
+		vars := cmd.bot.calcVariables.get(server, cmd.line.Nick)
+		if len(vars) <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "%s: No variables yet.", cmd.line.Nick)
+			return
+		}
+
+		var names []string
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var list []string
+		for _, name := range names {
+			list = append(list, name+" = "+vars[name].String())
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, strings.Join(list, ", "))
+		return
+	}
+
+	result, err := cmd.bot.calc(server, cmd.line.Nick, expr)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, result)
+}
+
+// Help shows the command help.
+func (cmd *CalcCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("CalcCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range calcHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
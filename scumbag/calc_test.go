@@ -0,0 +1,184 @@
+package scumbag
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEvalCalc(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"10 - 4 - 3", "3"},
+		{"7 / 2", "3.5"},
+		{"8 / 2", "4"},
+		{"7 % 3", "1"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"2 ** 10", "1024"},
+		{"-2 ^ 2", "-4"},
+		{"2 ^ -1", "0.5"},
+		{"2^100", "1267650600228229401496703205376"},
+		{"20!", "2432902008176640000"},
+		{"0!", "1"},
+		{"0xff + 0b1010 + 0o10", "273"},
+		{"0xf0 | 0x0f", "255"},
+		{"0xff & 0x0f", "15"},
+		{"1 << 10", "1024"},
+		{"1024 >> 3", "128"},
+		{"1.5e3", "1500"},
+		{".5 + .25", "0.75"},
+		{"0.1 + 0.2", "0.3"},
+		{"sqrt(16)", "4"},
+		{"sqrt(2)", "1.41421356237"},
+		{"sin(pi / 2)", "1"},
+		{"cos(0)", "1"},
+		{"log(1000)", "3"},
+		{"log(8, 2)", "3"},
+		{"ln(e)", "1"},
+		{"abs(-5)", "5"},
+		{"floor(2.7) + ceil(2.1)", "5"},
+		{"round(2.5)", "3"},
+		{"max(1, 5, 3)", "5"},
+		{"min(2, 1.5)", "1.5"},
+		{"PI * 2", "6.28318530718"},
+		{"x * 2", "84"},
+	} {
+		value, _, err := evalCalc(tc.expr, map[string]calcValue{"x": {i: big.NewInt(42)}})
+		if err != nil {
+			t.Errorf("%q: %s", tc.expr, err)
+			continue
+		}
+
+		if value.String() != tc.expected {
+			t.Errorf("%q: expected %s, got %s", tc.expr, tc.expected, value)
+		}
+	}
+}
+
+func TestEvalCalcErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"1 / 0",
+		"1.5 % 0",
+		"2 ^ 100000",
+		"5000!",
+		"1.5!",
+		"(-1)!",
+		"sqrt(-1)",
+		"nope(1)",
+		"sqrt(1, 2)",
+		"y + 1",
+		"1.5 & 1",
+		"1 << -1",
+		"0xzz",
+		"1 $ 2",
+		"pi = 3",
+		strings.Repeat("1+", calcMaxLength),
+	} {
+		if value, _, err := evalCalc(expr, nil); err == nil {
+			t.Errorf("%q: expected an error, got %s", expr, value)
+		}
+	}
+}
+
+func TestEvalCalcAssignment(t *testing.T) {
+	value, assigned, err := evalCalc("x = 6 * 7", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assigned != "x" || value.String() != "42" {
+		t.Errorf("expected x = 42, got %s = %s", assigned, value)
+	}
+}
+
+func TestCalcValueFormat(t *testing.T) {
+	for _, tc := range []struct {
+		value    calcValue
+		base     int
+		expected string
+	}{
+		{calcValue{i: big.NewInt(255)}, 16, "0xff"},
+		{calcValue{i: big.NewInt(-255)}, 16, "-0xff"},
+		{calcValue{i: big.NewInt(5)}, 2, "0b101"},
+		{calcValue{i: big.NewInt(8)}, 8, "0o10"},
+		{calcValue{i: big.NewInt(8)}, 10, "8"},
+	} {
+		formatted, err := tc.value.format(tc.base)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if formatted != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, formatted)
+		}
+	}
+
+	if _, err := (calcValue{f: 1.5}).format(16); err == nil {
+		t.Error("expected an error formatting a float in hex")
+	}
+
+	bits := calcValue{i: new(big.Int).Lsh(big.NewInt(1), calcMaxDigits)}
+	if formatted, err := bits.format(2); err == nil {
+		t.Errorf("expected an error formatting %d binary digits, got %s", calcMaxDigits+1, formatted)
+	}
+	if _, err := bits.format(16); err != nil {
+		t.Error(err)
+	}
+
+	huge := calcValue{i: new(big.Int).Exp(big.NewInt(10), big.NewInt(calcMaxDigits), nil)}
+	if expected := "1.00000000000e+300 (301 digits)"; huge.String() != expected {
+		t.Errorf("expected %s, got %s", expected, huge)
+	}
+}
+
+func TestBotCalc(t *testing.T) {
+	bot := &Scumbag{calcVariables: newCalcVariables()}
+
+	for _, tc := range []struct {
+		nick     string
+		expr     string
+		expected string
+	}{
+		{"alice", "x = 2 + 3", "x = 5"},
+		{"Alice", "x * 2", "10"},
+		{"alice", "ans + 1", "11"},
+		{"alice", "255 in hex", "0xff"},
+		{"alice", "ans to binary", "0b11111111"},
+	} {
+		result, err := bot.calc("irc.example.com", tc.nick, tc.expr)
+		if err != nil {
+			t.Errorf("%q: %s", tc.expr, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("%q: expected %s, got %s", tc.expr, tc.expected, result)
+		}
+	}
+
+	// ?wolfram falls back to calc(), so other bases must be capped there too.
+	if result, err := bot.calc("irc.example.com", "alice", "2^1000 in binary"); err == nil {
+		t.Errorf("expected an error, got %d characters", len(result))
+	}
+
+	// Variables are per user.
+	if _, err := bot.calc("irc.example.com", "bob", "x"); err == nil {
+		t.Error("expected bob to have no x")
+	}
+
+	for i := 0; i < calcMaxVariables; i++ {
+		_, err := bot.calc("irc.example.com", "carol", "v"+string(rune('a'+i))+" = 1")
+		if i < calcMaxVariables-1 && err != nil {
+			t.Fatal(err)
+		}
+		if i == calcMaxVariables-1 && err == nil {
+			t.Error("expected an error past calcMaxVariables")
+		}
+	}
+}
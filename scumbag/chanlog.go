@@ -0,0 +1,457 @@
+package scumbag
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// Channel log line kinds.
+const (
+	logMessage = "message"
+	logAction  = "action"
+	logJoin    = "join"
+	logPart    = "part"
+	logTopic   = "topic"
+)
+
+const (
+	channelLogInterval = time.Hour
+
+	// How far back, and through how many lines, "<cmdPrefix>grep" searches.
+	grepDays     = 7
+	grepMaxLines = 5000
+	grepLimit    = 5
+
+	grepNickPrefix = "nick:"
+	grepOptOutFlag = "-optout"
+
+	// Irssi's timestamp formats.
+	irssiDayFormat  = "Mon Jan 02 15:04:05 2006"
+	irssiTimeFormat = "15:04"
+
+	cliLogsExport = "export"
+)
+
+var grepHelp = []string{
+	cmdGrep + " </pattern/ or text> [" + grepNickPrefix + "<nick>] -- search the last week of the channel's log",
+	cmdGrep + " " + grepOptOutFlag + " -- stop logging (and saving links from) your nick, and delete your logged lines",
+}
+
+// channelLogEvents are the IRC events logged besides PRIVMSG, which goes through msgHandler().
+var channelLogEvents = []string{"ACTION", "JOIN", "PART", "TOPIC"}
+
+// LogLine is a line in a logged channel.
+type LogLine struct {
+	ID      int64
+	Server  string
+	Channel string
+	Nick    string
+	Kind    string
+	// The message, action, part reason or new topic.
+	Text      string
+	CreatedAt time.Time
+}
+
+// newLogLine returns the line to log from `line`, or nil if it isn't something to log.
+func newLogLine(server string, line *irc.Line) *LogLine {
+	logLine := &LogLine{Server: server, Nick: line.Nick, CreatedAt: line.Time}
+	arg := func(i int) string {
+		if i < len(line.Args) {
+			return line.Args[i]
+		}
+		return ""
+	}
+
+	switch line.Cmd {
+	case "PRIVMSG":
+		if !line.Public() {
+			return nil
+		}
+		logLine.Kind, logLine.Channel, logLine.Text = logMessage, line.Target(), line.Text()
+	case "ACTION":
+		if !line.Public() {
+			return nil
+		}
+		logLine.Kind, logLine.Channel, logLine.Text = logAction, line.Target(), line.Text()
+	case "JOIN":
+		logLine.Kind, logLine.Channel = logJoin, arg(0)
+	case "PART":
+		logLine.Kind, logLine.Channel, logLine.Text = logPart, arg(0), arg(1)
+	case "TOPIC":
+		logLine.Kind, logLine.Channel, logLine.Text = logTopic, arg(0), arg(1)
+	default:
+		return nil
+	}
+
+	if logLine.Nick == "" || logLine.Channel == "" {
+		return nil
+	}
+
+	return logLine
+}
+
+// irssi formats the line the way irssi logs it.
+func (logLine *LogLine) irssi() string {
+	timestamp := logLine.CreatedAt.Format(irssiTimeFormat)
+
+	switch logLine.Kind {
+	case logAction:
+		return fmt.Sprintf("%s  * %s %s", timestamp, logLine.Nick, logLine.Text)
+	case logJoin:
+		return fmt.Sprintf("%s -!- %s has joined %s", timestamp, logLine.Nick, logLine.Channel)
+	case logPart:
+		return fmt.Sprintf("%s -!- %s has left %s [%s]", timestamp, logLine.Nick, logLine.Channel, logLine.Text)
+	case logTopic:
+		return fmt.Sprintf("%s -!- %s changed the topic of %s to: %s", timestamp, logLine.Nick, logLine.Channel, logLine.Text)
+	default:
+		return fmt.Sprintf("%s <%s> %s", timestamp, logLine.Nick, logLine.Text)
+	}
+}
+
+// writeIrssiLog writes one day of a channel's log in irssi's format, which
+// "links import -format irssi" can read back.
+func writeIrssiLog(out io.Writer, day time.Time, lines []*LogLine) error {
+	if _, err := fmt.Fprintf(out, "--- Log opened %s\n", day.Format(irssiDayFormat)); err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(out, line.irssi()); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "--- Log closed %s\n", day.AddDate(0, 0, 1).Add(-time.Second).Format(irssiDayFormat))
+	return err
+}
+
+// loggedChannel returns true if the channel has LogMessages set.
+func loggedChannel(bot *Scumbag, server, channel string) bool {
+	serverConfig, err := bot.Config.Server(server)
+	if err != nil {
+		bot.LogError("loggedChannel()", err)
+		return false
+	}
+
+	channelConfig, ok := serverConfig.Channels[channel]
+	return ok && channelConfig != nil && channelConfig.LogMessages
+}
+
+// channelLogHandler handles the events in channelLogEvents.
+func (bot *Scumbag) channelLogHandler(conn *irc.Conn, line *irc.Line) {
+	go bot.LogChannelLine(conn, line)
+}
+
+// LogChannelLine is called from a goroutine to save `line` if its channel is
+// logged. Ignored nicks aren't logged.
+func (bot *Scumbag) LogChannelLine(conn *irc.Conn, line *irc.Line) {
+	server := conn.Config().Server
+
+	// Most channels aren't logged, so check the config before querying ignored_nicks.
+	logLine := newLogLine(server, line)
+	if logLine == nil || !loggedChannel(bot, server, logLine.Channel) || bot.databaseDown() {
+		return
+	}
+
+	if ignoredNick(bot, server, logLine.Nick) {
+		return
+	}
+
+	if err := bot.ChannelLog.SaveLogLine(logLine); err != nil && !bot.checkDatabase(err) {
+		bot.LogError("LogChannelLine()", err)
+	}
+}
+
+// startChannelLog periodically exports yesterday's logs and deletes lines
+// older than each channel's LogRetentionDays.
+func (bot *Scumbag) startChannelLog() {
+	go func() {
+		ticker := time.NewTicker(channelLogInterval)
+		defer ticker.Stop()
+
+		for {
+			if !bot.databaseDown() {
+				bot.exportChannelLogs(time.Now())
+				bot.enforceLogRetention()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+		}
+	}()
+}
+
+// exportChannelLogs writes the day before `now` of each logged channel to
+// ExportDir, unless it's already there.
+func (bot *Scumbag) exportChannelLogs(now time.Time) {
+	if bot.Config.ChannelLog == nil || bot.Config.ChannelLog.ExportDir == "" {
+		return
+	}
+
+	year, month, day := now.AddDate(0, 0, -1).Date()
+	yesterday := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	for _, serverConfig := range bot.Config.Servers {
+		for channel, channelConfig := range serverConfig.Channels {
+			if channelConfig == nil || !channelConfig.LogMessages {
+				continue
+			}
+
+			file := channelLogFile(bot.Config.ChannelLog.ExportDir, serverConfig.Server, channel, yesterday)
+			if _, err := os.Stat(file); err == nil {
+				continue
+			}
+
+			if err := bot.exportChannelLog(file, serverConfig.Server, channel, yesterday); err != nil {
+				bot.LogError("exportChannelLogs()", err)
+			}
+		}
+	}
+}
+
+// channelLogFile returns where a day of a channel's log is exported.
+func channelLogFile(dir, server, channel string, day time.Time) string {
+	return filepath.Join(dir, server, fmt.Sprintf("%s-%s.log", channel, day.Format("2006-01-02")))
+}
+
+// exportChannelLog writes a day of the channel's log to `file`. Days with nothing logged are skipped.
+func (bot *Scumbag) exportChannelLog(file, server, channel string, day time.Time) error {
+	lines, err := bot.ChannelLog.LogLines(&LinkFilter{Server: server, Channel: channel, Since: day, Until: day.AddDate(0, 0, 1)}, 0)
+	if err != nil || len(lines) <= 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := writeIrssiLog(out, day, lines); err != nil {
+		out.Close()
+		return err
+	}
+
+	bot.Log.WithFields(log.Fields{"file": file, "lines": len(lines)}).Info("Exported channel log.")
+	return out.Close()
+}
+
+func (bot *Scumbag) enforceLogRetention() {
+	for _, serverConfig := range bot.Config.Servers {
+		for channel, channelConfig := range serverConfig.Channels {
+			if channelConfig == nil || channelConfig.LogRetentionDays <= 0 {
+				continue
+			}
+
+			deleted, err := bot.ChannelLog.PurgeLog(&LinkFilter{
+				Server:  serverConfig.Server,
+				Channel: channel,
+				Until:   time.Now().AddDate(0, 0, -channelConfig.LogRetentionDays),
+			})
+			if err != nil {
+				bot.LogError("enforceLogRetention()", err)
+				continue
+			}
+			if deleted > 0 {
+				bot.Log.WithFields(log.Fields{"channel": channel, "deleted": deleted}).Info("Purged channel log.")
+			}
+		}
+	}
+}
+
+func (bot *Scumbag) logsCLI(out io.Writer, args []string) error {
+	if len(args) <= 0 || args[0] != cliLogsExport {
+		return fmt.Errorf("Usage: logs <%s>", cliLogsExport)
+	}
+
+	return bot.exportLogs(out, args[1:])
+}
+
+// exportLogs writes a day of a channel's log in irssi's format to stdout or -out, e.g.
+//
+//	logs export -server irc.example.com:6667 -channel '#scumbag' -date 2020-03-15 -out scumbag.log
+func (bot *Scumbag) exportLogs(out io.Writer, args []string) error {
+	flags := flag.NewFlagSet(cliLogsExport, flag.ContinueOnError)
+	server := flags.String("server", "", "Server the channel is on")
+	channel := flags.String("channel", "", "Channel to export")
+	date := flags.String("date", "", "Day to export (2020-01-31; default yesterday)")
+	outFile := flags.String("out", "", "Output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *server == "" || *channel == "" {
+		return fmt.Errorf("-server and -channel are required")
+	}
+
+	year, month, day := time.Now().AddDate(0, 0, -1).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	if *date != "" {
+		var err error
+		if start, err = time.ParseInLocation("2006-01-02", *date, time.Local); err != nil {
+			return fmt.Errorf("Invalid -date: %s", err)
+		}
+	}
+
+	lines, err := bot.ChannelLog.LogLines(&LinkFilter{Server: *server, Channel: *channel, Since: start, Until: start.AddDate(0, 0, 1)}, 0)
+	if err != nil {
+		return err
+	}
+
+	if *outFile != "" {
+		file, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return writeIrssiLog(out, start, lines)
+}
+
+// GrepCommand searches the channel log.
+type GrepCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewGrepCommand returns a new GrepCommand instance.
+func NewGrepCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *GrepCommand {
+	return &GrepCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *GrepCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("GrepCommand.Run()", err)
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+	if len(fields) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	if len(fields) == 1 && fields[0] == grepOptOutFlag {
+		cmd.optOut(server, channel)
+		return
+	}
+
+	if !loggedChannel(cmd.bot, server, channel) {
+		cmd.bot.Msg(cmd.conn, channel, "I don't log %s.", channel)
+		return
+	}
+
+	var nick string
+	var search []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, grepNickPrefix) && len(field) > len(grepNickPrefix) {
+			nick = strings.TrimPrefix(field, grepNickPrefix)
+		} else {
+			search = append(search, field)
+		}
+	}
+	if len(search) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	pattern, err := searchRegexp(strings.Join(search, " "))
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "Invalid pattern: %s", err)
+		return
+	}
+
+	lines, err := cmd.bot.ChannelLog.LogLines(&LinkFilter{
+		Server:  server,
+		Channel: channel,
+		Since:   cmd.line.Time.AddDate(0, 0, -grepDays),
+		Until:   cmd.line.Time,
+	}, grepMaxLines)
+	if err != nil {
+		cmd.bot.LogError("GrepCommand.Run()", err)
+		return
+	}
+
+	var matches []*LogLine
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if line.Kind != logMessage && line.Kind != logAction {
+			continue
+		}
+		if nick != "" && !strings.EqualFold(line.Nick, nick) {
+			continue
+		}
+		if pattern.MatchString(line.Text) {
+			matches = append(matches, line)
+		}
+	}
+
+	if len(matches) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "Nothing found.")
+		return
+	}
+
+	for i, line := range matches {
+		if i >= grepLimit {
+			cmd.bot.Msg(cmd.conn, channel, "...and %d more.", len(matches)-grepLimit)
+			break
+		}
+		cmd.bot.Msg(cmd.conn, channel, "[%s] %s", line.CreatedAt.Format("Jan 02"), line.irssi())
+	}
+}
+
+// optOut adds the nick to ignored_nicks and deletes everything logged from it on the server.
+func (cmd *GrepCommand) optOut(server, channel string) {
+	nick := cmd.line.Nick
+
+	if _, err := cmd.bot.Ignores.IgnoreNick(server, nick, cmd.line.Time); err != nil {
+		cmd.bot.LogError("GrepCommand.optOut()", err)
+		return
+	}
+
+	deleted, err := cmd.bot.ChannelLog.PurgeLog(&LinkFilter{Server: server, Nick: nick})
+	if err != nil {
+		cmd.bot.LogError("GrepCommand.optOut()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "OK %s, I won't log you or save your links any more, and I deleted %d logged lines. Ask an admin if you change your mind.", nick, deleted)
+}
+
+// Help shows the command help.
+func (cmd *GrepCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("GrepCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range grepHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
@@ -0,0 +1,136 @@
+package scumbag
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+func TestNewLogLine(t *testing.T) {
+	for _, tc := range []struct {
+		cmd      string
+		args     []string
+		expected *LogLine
+	}{
+		{"PRIVMSG", []string{"#scumbag", "hi"}, &LogLine{Channel: "#scumbag", Nick: "bob", Kind: logMessage, Text: "hi"}},
+		{"PRIVMSG", []string{"scumbag", "psst"}, nil},
+		{"ACTION", []string{"#scumbag", "waves"}, &LogLine{Channel: "#scumbag", Nick: "bob", Kind: logAction, Text: "waves"}},
+		{"ACTION", []string{"scumbag", "waves"}, nil},
+		{"JOIN", []string{"#scumbag"}, &LogLine{Channel: "#scumbag", Nick: "bob", Kind: logJoin}},
+		{"PART", []string{"#scumbag", "later"}, &LogLine{Channel: "#scumbag", Nick: "bob", Kind: logPart, Text: "later"}},
+		{"TOPIC", []string{"#scumbag", "new topic"}, &LogLine{Channel: "#scumbag", Nick: "bob", Kind: logTopic, Text: "new topic"}},
+		{"QUIT", []string{"Ping timeout"}, nil},
+	} {
+		now := time.Now()
+		line := &irc.Line{Nick: "bob", Cmd: tc.cmd, Args: tc.args, Time: now}
+
+		logLine := newLogLine("irc.example.com", line)
+		if tc.expected == nil {
+			if logLine != nil {
+				t.Errorf("%s %v: expected nil, got %+v", tc.cmd, tc.args, logLine)
+			}
+			continue
+		}
+
+		tc.expected.Server = "irc.example.com"
+		tc.expected.CreatedAt = now
+		if logLine == nil || *logLine != *tc.expected {
+			t.Errorf("%s %v: expected %+v, got %+v", tc.cmd, tc.args, tc.expected, logLine)
+		}
+	}
+}
+
+func TestWriteIrssiLog(t *testing.T) {
+	day := time.Date(2020, 3, 5, 0, 0, 0, 0, time.Local)
+	at := func(hour, min int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	}
+
+	lines := []*LogLine{
+		{Channel: "#scumbag", Nick: "bob", Kind: logJoin, CreatedAt: at(9, 5)},
+		{Channel: "#scumbag", Nick: "bob", Kind: logMessage, Text: "see https://example.com/", CreatedAt: at(9, 6)},
+		{Channel: "#scumbag", Nick: "alice", Kind: logAction, Text: "waves", CreatedAt: at(12, 0)},
+		{Channel: "#scumbag", Nick: "alice", Kind: logTopic, Text: "new topic", CreatedAt: at(12, 1)},
+		{Channel: "#scumbag", Nick: "bob", Kind: logPart, Text: "later", CreatedAt: at(23, 59)},
+	}
+
+	var out bytes.Buffer
+	if err := writeIrssiLog(&out, day, lines); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `--- Log opened Thu Mar 05 00:00:00 2020
+09:05 -!- bob has joined #scumbag
+09:06 <bob> see https://example.com/
+12:00  * alice waves
+12:01 -!- alice changed the topic of #scumbag to: new topic
+23:59 -!- bob has left #scumbag [later]
+--- Log closed Thu Mar 05 23:59:59 2020
+`
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	// Exports can be imported again.
+	links, err := readLogLinks(&out, linkFormatIrssi, time.Time{})
+	if err != nil || len(links) != 1 || links[0].Nick != "bob" || links[0].URL != "https://example.com/" || !links[0].CreatedAt.Equal(at(9, 6)) {
+		t.Errorf("Wrong links imported: %+v, %v", links, err)
+	}
+}
+
+func TestChannelLogFile(t *testing.T) {
+	day := time.Date(2020, 3, 15, 0, 0, 0, 0, time.Local)
+	expected := "irclogs/irc.example.com:6667/#scumbag-2020-03-15.log"
+
+	if file := channelLogFile("irclogs", "irc.example.com:6667", "#scumbag", day); file != expected {
+		t.Errorf("Expected %s, got %s", expected, file)
+	}
+}
+
+// countedIgnores counts IgnoredNick lookups.
+type countedIgnores struct {
+	*MemoryStore
+	lookups int
+}
+
+func (store *countedIgnores) IgnoredNick(server, nick string) (bool, error) {
+	store.lookups++
+	return store.MemoryStore.IgnoredNick(server, nick)
+}
+
+func TestLogChannelLine(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore()
+	ignores := &countedIgnores{MemoryStore: store}
+	bot.ChannelLog = store
+	bot.Ignores = ignores
+
+	config := irc.NewConfig("scumbag")
+	config.Server = "irc.example.com:6667"
+	conn := irc.Client(config)
+
+	if _, err := store.IgnoreNick(config.Server, "troll", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	bot.LogChannelLine(conn, testLine("alice", "#scumbag", "not logged here"))
+	if ignores.lookups != 0 {
+		t.Errorf("Expected no ignored nick lookup in a channel without LogMessages, got %d", ignores.lookups)
+	}
+
+	bot.LogChannelLine(conn, testLine("alice", "#scumbag_two", "logged"))
+	bot.LogChannelLine(conn, testLine("troll", "#scumbag_two", "ignored"))
+
+	lines, err := store.LogLines(&LinkFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].Nick != "alice" || lines[0].Channel != "#scumbag_two" {
+		t.Errorf("Expected only alice's line in #scumbag_two, got %+v", lines)
+	}
+}
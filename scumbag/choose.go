@@ -0,0 +1,191 @@
+package scumbag
+
+import (
+	"regexp"
+	"strings"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+var chooseHelp = cmdChoose + " <this> | <that> [| ...] -- pick one; commas or \"or\" also separate choices"
+
+var flipHelp = cmdFlip + " -- flip a coin"
+
+var eightBallHelp = cmdEightBall + " <question> -- ask the magic 8-ball"
+
+var chooseOrRegexp = regexp.MustCompile(`(?i)\s+or\s+`)
+
+// The classic Magic 8-Ball's twenty answers.
+var eightBallAnswers = []string{
+	"It is certain.",
+	"It is decidedly so.",
+	"Without a doubt.",
+	"Yes definitely.",
+	"You may rely on it.",
+	"As I see it, yes.",
+	"Most likely.",
+	"Outlook good.",
+	"Yes.",
+	"Signs point to yes.",
+	"Reply hazy, try again.",
+	"Ask again later.",
+	"Better not tell you now.",
+	"Cannot predict now.",
+	"Concentrate and ask again.",
+	"Don't count on it.",
+	"My reply is no.",
+	"My sources say no.",
+	"Outlook not so good.",
+	"Very doubtful.",
+}
+
+// parseChoices splits "a | b | c" into choices, falling back to commas and
+// then " or " when there's no "|".
+func parseChoices(args string) []string {
+	var choices []string
+	for _, split := range []func(string) []string{
+		func(s string) []string { return strings.Split(s, "|") },
+		func(s string) []string { return strings.Split(s, ",") },
+		func(s string) []string { return chooseOrRegexp.Split(s, -1) },
+	} {
+		choices = choices[:0]
+		for _, choice := range split(args) {
+			if choice = strings.TrimSpace(choice); choice != "" {
+				choices = append(choices, choice)
+			}
+		}
+		if len(choices) > 1 {
+			return choices
+		}
+	}
+	return nil
+}
+
+// ChooseCommand picks one of a few choices.
+type ChooseCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewChooseCommand returns a new ChooseCommand instance.
+func NewChooseCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *ChooseCommand {
+	return &ChooseCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *ChooseCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ChooseCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	choices := parseChoices(args[0])
+	if len(choices) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, choices[rng.Intn(len(choices))])
+}
+
+// Help shows the command help.
+func (cmd *ChooseCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ChooseCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, chooseHelp)
+}
+
+// FlipCommand flips a coin.
+type FlipCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewFlipCommand returns a new FlipCommand instance.
+func NewFlipCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *FlipCommand {
+	return &FlipCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *FlipCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("FlipCommand.Run()", err)
+		return
+	}
+
+	side := "heads"
+	if rng.Intn(2) == 1 {
+		side = "tails"
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s flips a coin: %s", cmd.line.Nick, side)
+}
+
+// Help shows the command help.
+func (cmd *FlipCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("FlipCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, flipHelp)
+}
+
+// EightBallCommand answers questions like a Magic 8-Ball.
+type EightBallCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewEightBallCommand returns a new EightBallCommand instance.
+func NewEightBallCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *EightBallCommand {
+	return &EightBallCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *EightBallCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("EightBallCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, eightBallAnswers[rng.Intn(len(eightBallAnswers))])
+}
+
+// Help shows the command help.
+func (cmd *EightBallCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("EightBallCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, eightBallHelp)
+}
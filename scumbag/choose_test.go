@@ -0,0 +1,27 @@
+package scumbag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChoices(t *testing.T) {
+	for _, tc := range []struct {
+		args     string
+		expected []string
+	}{
+		{"pizza | tacos | sushi", []string{"pizza", "tacos", "sushi"}},
+		{"pizza, tacos, sushi", []string{"pizza", "tacos", "sushi"}},
+		{"pizza or tacos", []string{"pizza", "tacos"}},
+		{"pizza OR tacos", []string{"pizza", "tacos"}},
+		{"salt, pepper | vinegar", []string{"salt, pepper", "vinegar"}},
+		{"pizza | | tacos", []string{"pizza", "tacos"}},
+		{"oranges", nil},
+		{"oranges |", nil},
+		{"", nil},
+	} {
+		if choices := parseChoices(tc.args); !reflect.DeepEqual(choices, tc.expected) {
+			t.Errorf("%q: expected %q, got %q", tc.args, tc.expected, choices)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package scumbag
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	cliDB      = "db"
+	cliLinks   = "links"
+	cliLogs    = "logs"
+	cliMigrate = "migrate"
+)
+
+// RunCLI runs a command line subcommand (instead of connecting to IRC), writing output to `out`.
+func (bot *Scumbag) RunCLI(out io.Writer, args []string) error {
+	if len(args) <= 0 {
+		return fmt.Errorf("No command given")
+	}
+
+	switch args[0] {
+	case cliDB:
+		return bot.dbCLI(out, args[1:])
+	case cliLinks:
+		return bot.linksCLI(out, args[1:])
+	case cliLogs:
+		return bot.logsCLI(out, args[1:])
+	case cliMigrate:
+		return bot.migrateCLI(out, args[1:])
+	default:
+		return fmt.Errorf("Unknown command: %s", args[0])
+	}
+}
@@ -11,12 +11,18 @@ type BotConfig struct {
 	Servers      []*ServerConfig
 	Admins       []string
 	LogLevel     string
+	ChannelLog   *ChannelLogConfig
 	Database     *DatabaseConfig
 	IGDB         *IGDBConfig
+	Karma        *KarmaConfig
+	LinkCheck    *LinkCheckConfig
+	Memos        *MemoConfig
 	News         *NewsConfig
 	OMDb         *OMDbConfig
 	OWM          *OWMConfig
+	Reminders    *ReminderConfig
 	Rollbar      *RollbarConfig
+	Trivia       *TriviaConfig
 	Twitter      *TwitterConfig
 	WolframAlpha *WolframAlphaConfig
 }
@@ -30,17 +36,41 @@ type ServerConfig struct {
 }
 
 // DatabaseConfig stores database connection information.
+// Driver is "postgres" (the default) or "sqlite", which only uses Path.
+// AutoMigrate applies pending schema migrations when the bot starts.
+// The pool settings are optional; ConnMaxLifetime is a duration, e.g. "1h".
 type DatabaseConfig struct {
-	Host     string
-	SSL      string
-	Name     string
-	User     string
-	Password string
+	Driver          string
+	Path            string
+	AutoMigrate     bool
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime string
+	Host            string
+	SSL             string
+	Name            string
+	User            string
+	Password        string
 }
 
 // ChannelConfig stores configuration information for a single channel.
 type ChannelConfig struct {
-	SaveURLs bool
+	SaveURLs      bool
+	UnfurlURLs    bool
+	RepostNotice  bool
+	ArchiveURLs   bool
+	RetentionDays int
+	// LogMessages keeps the channel's messages, actions, joins, parts and
+	// topic changes for "<cmdPrefix>grep" and log exports, for LogRetentionDays
+	// if it's set.
+	LogMessages      bool
+	LogRetentionDays int
+}
+
+// ChannelLogConfig stores channel log settings.
+// ExportDir is where each logged channel's daily irssi-format log files are written, if it's set.
+type ChannelLogConfig struct {
+	ExportDir string
 }
 
 // IGDBConfig stores IGDB.com API information.
@@ -48,6 +78,27 @@ type IGDBConfig struct {
 	Key string
 }
 
+// KarmaConfig stores karma settings.
+// Cooldown is how long a nick waits to vote on the same thing again, e.g. "5m".
+type KarmaConfig struct {
+	Cooldown string
+}
+
+// LinkCheckConfig stores dead link checker and archive settings.
+type LinkCheckConfig struct {
+	Interval      string
+	BatchSize     int
+	ArchiveDir    string
+	ArchiveListen string
+	ArchiveURL    string
+}
+
+// MemoConfig stores "<cmdPrefix>tell" settings.
+// Quota is the most undelivered memos one nick can leave.
+type MemoConfig struct {
+	Quota int
+}
+
 // NewsConfig stores News API information.
 type NewsConfig struct {
 	Key string
@@ -63,11 +114,26 @@ type OWMConfig struct {
 	Key string
 }
 
+// ReminderConfig stores "<cmdPrefix>remind" settings.
+// Quota is the most pending reminders one nick can set.
+type ReminderConfig struct {
+	Quota int
+}
+
 // RollbarConfig stores Rollbar config information.
 type RollbarConfig struct {
 	Token string
 }
 
+// TriviaConfig stores "<cmdPrefix>trivia" settings.
+// PacksDir holds the question packs, *.json or *.csv files. Rounds is how many
+// questions a game asks, and QuestionTime how long each is open, e.g. "60s".
+type TriviaConfig struct {
+	PacksDir     string
+	Rounds       int
+	QuestionTime string
+}
+
 // TwitterConfig stores Twitter API information.
 type TwitterConfig struct {
 	AccessToken string
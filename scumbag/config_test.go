@@ -58,16 +58,68 @@ func TestChannelConfigs(t *testing.T) {
 		t.Error("ChannelConfig.SaveURLs not set properly")
 	}
 
+	if channel.UnfurlURLs != true {
+		t.Error("ChannelConfig.UnfurlURLs not set properly")
+	}
+
+	if channel.RepostNotice != true {
+		t.Error("ChannelConfig.RepostNotice not set properly")
+	}
+
+	if channel.ArchiveURLs != true {
+		t.Error("ChannelConfig.ArchiveURLs not set properly")
+	}
+
 	channel = server.Channels["#scumbag_two"]
 	if channel.SaveURLs != false {
 		t.Error("ChannelConfig.SaveURLs not set properly")
 	}
+
+	if channel.UnfurlURLs != false {
+		t.Error("ChannelConfig.UnfurlURLs not set properly")
+	}
+
+	if channel.RetentionDays != 30 {
+		t.Error("ChannelConfig.RetentionDays not set properly")
+	}
+
+	if channel.LogMessages != true {
+		t.Error("ChannelConfig.LogMessages not set properly")
+	}
+
+	if channel.LogRetentionDays != 90 {
+		t.Error("ChannelConfig.LogRetentionDays not set properly")
+	}
+}
+
+func TestChannelLogConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+
+	if config.ChannelLog.ExportDir != "irclogs" {
+		t.Error("ChannelLogConfig.ExportDir not set")
+	}
 }
 
 func TestDatabaseConfig(t *testing.T) {
 	config, _ := loadTestConfig()
 	db := config.Database
 
+	if db.Driver != "postgres" {
+		t.Error("DatabaseConfig.Driver not set")
+	}
+
+	if db.Path != "scumbag.db" {
+		t.Error("DatabaseConfig.Path not set")
+	}
+
+	if !db.AutoMigrate {
+		t.Error("DatabaseConfig.AutoMigrate not set")
+	}
+
+	if db.MaxOpenConns != 10 || db.MaxIdleConns != 2 || db.ConnMaxLifetime != "1h" {
+		t.Error("DatabaseConfig pool settings not set")
+	}
+
 	if db.Host != "db.example.com" {
 		t.Error("DatabaseConfig.Host not set")
 	}
@@ -88,3 +140,69 @@ func TestDatabaseConfig(t *testing.T) {
 		t.Error("DatabaseConfig.Password not set")
 	}
 }
+
+func TestKarmaConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+
+	if config.Karma.Cooldown != "5m" {
+		t.Error("KarmaConfig.Cooldown not set")
+	}
+}
+
+func TestLinkCheckConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+	linkCheck := config.LinkCheck
+
+	if linkCheck.Interval != "24h" {
+		t.Error("LinkCheckConfig.Interval not set")
+	}
+
+	if linkCheck.BatchSize != 100 {
+		t.Error("LinkCheckConfig.BatchSize not set")
+	}
+
+	if linkCheck.ArchiveDir != "archive" {
+		t.Error("LinkCheckConfig.ArchiveDir not set")
+	}
+
+	if linkCheck.ArchiveListen != "127.0.0.1:8080" {
+		t.Error("LinkCheckConfig.ArchiveListen not set")
+	}
+
+	if linkCheck.ArchiveURL != "http://scumbag.example.com" {
+		t.Error("LinkCheckConfig.ArchiveURL not set")
+	}
+}
+
+func TestMemoConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+
+	if config.Memos.Quota != 5 {
+		t.Error("MemoConfig.Quota not set")
+	}
+}
+
+func TestReminderConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+
+	if config.Reminders.Quota != 10 {
+		t.Error("ReminderConfig.Quota not set")
+	}
+}
+
+func TestTriviaConfig(t *testing.T) {
+	config, _ := loadTestConfig()
+	trivia := config.Trivia
+
+	if trivia.PacksDir != "trivia" {
+		t.Error("TriviaConfig.PacksDir not set")
+	}
+
+	if trivia.Rounds != 10 {
+		t.Error("TriviaConfig.Rounds not set")
+	}
+
+	if trivia.QuestionTime != "60s" {
+		t.Error("TriviaConfig.QuestionTime not set")
+	}
+}
@@ -0,0 +1,213 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+var convertHelp = []string{
+	cmdConvert + " <amount> <unit> to <unit> -- convert lengths, weights, volumes, areas, speeds, times, temperatures and data sizes, e.g. 5 mi to km, 72F to C, 2 GiB to MB",
+}
+
+// conversionRegexp matches "<amount> <unit> to|in <unit>"; the space after
+// the amount is optional, and units start with a letter.
+var conversionRegexp = regexp.MustCompile(`(?i)^\s*([-+]?(?:\d+\.?\d*|\.\d+)(?:e[-+]?\d+)?)\s*([\pL°].*?)\s+(?:to|in|into)\s+([\pL°].*?)\s*$`)
+
+// convertUnit converts to its dimension's base unit as amount*factor + offset;
+// only temperatures (based on kelvin) have an offset.
+type convertUnit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// convertUnits are keyed by lowercase name. Data sizes are decimal (1 KB is
+// 1000 bytes) unless binary (1 KiB is 1024). Volumes are US measures.
+var convertUnits = map[string]*convertUnit{}
+
+func init() {
+	for _, u := range []struct {
+		names     []string
+		dimension string
+		factor    float64
+		offset    float64
+	}{
+		{[]string{"m", "meter", "metre"}, "length", 1, 0},
+		{[]string{"km", "kilometer", "kilometre"}, "length", 1000, 0},
+		{[]string{"cm", "centimeter", "centimetre"}, "length", 0.01, 0},
+		{[]string{"mm", "millimeter", "millimetre"}, "length", 0.001, 0},
+		{[]string{"um", "µm", "micrometer", "micron"}, "length", 1e-6, 0},
+		{[]string{"nm", "nanometer"}, "length", 1e-9, 0},
+		{[]string{"mi", "mile"}, "length", 1609.344, 0},
+		{[]string{"yd", "yard"}, "length", 0.9144, 0},
+		{[]string{"ft", "foot", "feet"}, "length", 0.3048, 0},
+		{[]string{"in", "inch", "inches"}, "length", 0.0254, 0},
+		{[]string{"nmi", "nautical mile"}, "length", 1852, 0},
+		{[]string{"au"}, "length", 149597870700, 0},
+		{[]string{"ly", "light year", "lightyear"}, "length", 9460730472580800, 0},
+
+		{[]string{"kg", "kilogram", "kilo"}, "mass", 1, 0},
+		{[]string{"g", "gram"}, "mass", 0.001, 0},
+		{[]string{"mg", "milligram"}, "mass", 1e-6, 0},
+		{[]string{"t", "tonne", "metric ton"}, "mass", 1000, 0},
+		{[]string{"lb", "lbs", "pound"}, "mass", 0.45359237, 0},
+		{[]string{"oz", "ounce"}, "mass", 0.028349523125, 0},
+		{[]string{"st", "stone"}, "mass", 6.35029318, 0},
+
+		{[]string{"l", "liter", "litre"}, "volume", 1, 0},
+		{[]string{"ml", "milliliter", "millilitre"}, "volume", 0.001, 0},
+		{[]string{"cl", "centiliter", "centilitre"}, "volume", 0.01, 0},
+		{[]string{"m3", "m^3", "cubic meter", "cubic metre"}, "volume", 1000, 0},
+		{[]string{"gal", "gallon"}, "volume", 3.785411784, 0},
+		{[]string{"qt", "quart"}, "volume", 0.946352946, 0},
+		{[]string{"pt", "pint"}, "volume", 0.473176473, 0},
+		{[]string{"cup"}, "volume", 0.2365882365, 0},
+		{[]string{"floz", "fl oz", "fluid ounce"}, "volume", 0.0295735295625, 0},
+		{[]string{"tbsp", "tablespoon"}, "volume", 0.01478676478125, 0},
+		{[]string{"tsp", "teaspoon"}, "volume", 0.00492892159375, 0},
+
+		{[]string{"m2", "m^2", "sq m", "square meter", "square metre"}, "area", 1, 0},
+		{[]string{"km2", "km^2", "sq km", "square kilometer", "square kilometre"}, "area", 1e6, 0},
+		{[]string{"ft2", "ft^2", "sq ft", "square foot", "square feet"}, "area", 0.09290304, 0},
+		{[]string{"mi2", "mi^2", "sq mi", "square mile"}, "area", 2589988.110336, 0},
+		{[]string{"ha", "hectare"}, "area", 1e4, 0},
+		{[]string{"acre"}, "area", 4046.8564224, 0},
+
+		{[]string{"m/s", "mps"}, "speed", 1, 0},
+		{[]string{"km/h", "kmh", "kph"}, "speed", 1 / 3.6, 0},
+		{[]string{"mph", "mi/h"}, "speed", 0.44704, 0},
+		{[]string{"ft/s", "fps"}, "speed", 0.3048, 0},
+		{[]string{"kn", "kt", "knot"}, "speed", 1852.0 / 3600, 0},
+
+		{[]string{"s", "sec", "second"}, "time", 1, 0},
+		{[]string{"ms", "millisecond"}, "time", 0.001, 0},
+		{[]string{"min", "minute"}, "time", 60, 0},
+		{[]string{"h", "hr", "hour"}, "time", 3600, 0},
+		{[]string{"d", "day"}, "time", 86400, 0},
+		{[]string{"wk", "week"}, "time", 604800, 0},
+		{[]string{"mo", "month"}, "time", 31557600.0 / 12, 0},
+		{[]string{"yr", "year"}, "time", 31557600, 0},
+
+		{[]string{"b", "byte"}, "data", 1, 0},
+		{[]string{"bit"}, "data", 0.125, 0},
+		{[]string{"kb", "kilobyte"}, "data", 1e3, 0},
+		{[]string{"mb", "megabyte"}, "data", 1e6, 0},
+		{[]string{"gb", "gigabyte"}, "data", 1e9, 0},
+		{[]string{"tb", "terabyte"}, "data", 1e12, 0},
+		{[]string{"pb", "petabyte"}, "data", 1e15, 0},
+		{[]string{"kib", "kibibyte"}, "data", 1 << 10, 0},
+		{[]string{"mib", "mebibyte"}, "data", 1 << 20, 0},
+		{[]string{"gib", "gibibyte"}, "data", 1 << 30, 0},
+		{[]string{"tib", "tebibyte"}, "data", 1 << 40, 0},
+		{[]string{"pib", "pebibyte"}, "data", 1 << 50, 0},
+		{[]string{"kbit", "kilobit"}, "data", 125, 0},
+		{[]string{"mbit", "megabit"}, "data", 125e3, 0},
+		{[]string{"gbit", "gigabit"}, "data", 125e6, 0},
+
+		{[]string{"k", "kelvin"}, "temperature", 1, 0},
+		{[]string{"c", "°c", "celsius", "centigrade"}, "temperature", 1, 273.15},
+		{[]string{"f", "°f", "fahrenheit"}, "temperature", 5.0 / 9, 459.67 * 5 / 9},
+	} {
+		for _, name := range u.names {
+			convertUnits[name] = &convertUnit{dimension: u.dimension, factor: u.factor, offset: u.offset}
+		}
+	}
+}
+
+// findConvertUnit looks up a unit by name, also without a plural "s" and "degrees".
+func findConvertUnit(name string) *convertUnit {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "degrees "), "degree ")
+
+	if unit, ok := convertUnits[name]; ok {
+		return unit
+	}
+	if strings.HasSuffix(name, "s") {
+		return convertUnits[strings.TrimSuffix(name, "s")]
+	}
+	return nil
+}
+
+// convertQuery converts a "<amount> <unit> to <unit>" query, e.g. "5 mi to km"
+// is "5 mi = 8.04672 km". It returns false if `query` isn't a conversion.
+func convertQuery(query string) (string, bool, error) {
+	match := conversionRegexp.FindStringSubmatch(query)
+	if match == nil {
+		return "", false, nil
+	}
+
+	amount, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return "", true, fmt.Errorf("Bad amount: %s", match[1])
+	}
+
+	from, to := findConvertUnit(match[2]), findConvertUnit(match[3])
+	switch {
+	case from == nil:
+		return "", true, fmt.Errorf("Unknown unit %q.", match[2])
+	case to == nil:
+		return "", true, fmt.Errorf("Unknown unit %q.", match[3])
+	case from.dimension != to.dimension:
+		return "", true, fmt.Errorf("Can't convert %s (%s) to %s (%s).", match[2], from.dimension, match[3], to.dimension)
+	}
+
+	converted := (amount*from.factor + from.offset - to.offset) / to.factor
+	return fmt.Sprintf("%s %s = %s %s", match[1], match[2], strconv.FormatFloat(converted, 'g', 10, 64), match[3]), true, nil
+}
+
+// ConvertCommand converts between units.
+type ConvertCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewConvertCommand returns a new ConvertCommand instance.
+func NewConvertCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *ConvertCommand {
+	return &ConvertCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *ConvertCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ConvertCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	result, ok, err := convertQuery(args[0])
+	if !ok {
+		cmd.Help()
+		return
+	}
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, result)
+}
+
+// Help shows the command help.
+func (cmd *ConvertCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ConvertCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range convertHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
@@ -0,0 +1,58 @@
+package scumbag
+
+import "testing"
+
+func TestConvertQuery(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		expected string
+	}{
+		{"5 mi to km", "5 mi = 8.04672 km"},
+		{"10km in miles", "10 km = 6.213711922 miles"},
+		{"6 feet to cm", "6 feet = 182.88 cm"},
+		{"72F to C", "72 F = 22.22222222 C"},
+		{"100 celsius to fahrenheit", "100 celsius = 212 fahrenheit"},
+		{"0 K to C", "0 K = -273.15 C"},
+		{"-40 degrees C to degrees F", "-40 degrees C = -40 degrees F"},
+		{"2 GiB to MB", "2 GiB = 2147.483648 MB"},
+		{"1 GB to MiB", "1 GB = 953.6743164 MiB"},
+		{"8 bits to bytes", "8 bits = 1 bytes"},
+		{"1 gallon to l", "1 gallon = 3.785411784 l"},
+		{"2 fl oz to ml", "2 fl oz = 59.14705912 ml"},
+		{"1 acre to ha", "1 acre = 0.4046856422 ha"},
+		{"60 mph to km/h", "60 mph = 96.56064 km/h"},
+		{"1.5 hours to minutes", "1.5 hours = 90 minutes"},
+		{"1 lb to g", "1 lb = 453.59237 g"},
+	} {
+		result, ok, err := convertQuery(tc.query)
+		if !ok || err != nil {
+			t.Errorf("%q: %v %s", tc.query, ok, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.query, tc.expected, result)
+		}
+	}
+}
+
+func TestConvertQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		"5 mi to kg",
+		"5 furlongs to km",
+		"5 km to parsecs",
+	} {
+		if _, ok, err := convertQuery(query); !ok || err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+
+	for _, query := range []string{
+		"2 + 2",
+		"mi to km",
+		"255 in hex",
+	} {
+		if _, ok, _ := convertQuery(query); ok {
+			t.Errorf("%q: expected no conversion", query)
+		}
+	}
+}
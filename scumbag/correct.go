@@ -0,0 +1,149 @@
+package scumbag
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// How many of a nick's recent lines a correction is tried on, newest first.
+	correctLines = 5
+
+	// Go's regexps run in linear time, but a pattern is still given only so
+	// long to run over a line.
+	correctTimeout = 250 * time.Millisecond
+)
+
+// Matches "s/pattern/replacement/flags" or "nick: s/pattern/replacement/flags".
+// Slashes can be escaped with a backslash and the last one is optional.
+var correctionRegexp = regexp.MustCompile(`\A(?:([^\s:,]+)[:,]\s+)?s/((?:[^\\/]|\\.)+)/((?:[^\\/]|\\.)*)(?:/(\w*))?\z`)
+
+var (
+	correctionUnescaper = strings.NewReplacer(`\/`, "/", `\\`, `\`)
+
+	errCorrectionTimeout = errors.New("correction timed out")
+)
+
+// correction is a parsed "s/pattern/replacement/flags".
+type correction struct {
+	// Whose line to correct; empty for the nick's own.
+	nick        string
+	pattern     *regexp.Regexp
+	replacement string
+	// The "g" flag replaces every match instead of only the first.
+	global bool
+}
+
+// parseCorrection returns the correction in `text`, or nil if it isn't one.
+// Flags are "g" and "i" (case insensitive); anything else isn't a correction.
+func parseCorrection(text string) (*correction, error) {
+	match := correctionRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return nil, nil
+	}
+
+	c := &correction{nick: match[1], replacement: correctionUnescaper.Replace(match[3])}
+
+	pattern := strings.ReplaceAll(match[2], `\/`, "/")
+	for _, flag := range match[4] {
+		switch flag {
+		case 'g':
+			c.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, nil
+		}
+	}
+
+	var err error
+	if c.pattern, err = regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// apply returns `text` corrected, and false if the pattern didn't change it.
+func (c *correction) apply(text string) (string, bool) {
+	var corrected string
+	if c.global {
+		corrected = c.pattern.ReplaceAllLiteralString(text, c.replacement)
+	} else {
+		loc := c.pattern.FindStringIndex(text)
+		if loc == nil {
+			return text, false
+		}
+		corrected = text[:loc[0]] + c.replacement + text[loc[1]:]
+	}
+
+	return corrected, corrected != text
+}
+
+// applyCorrection is apply() giving up after `timeout`.
+func applyCorrection(c *correction, text string, timeout time.Duration) (string, bool, error) {
+	type result struct {
+		corrected string
+		changed   bool
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		corrected, changed := c.apply(text)
+		done <- result{corrected, changed}
+	}()
+
+	select {
+	case r := <-done:
+		return r.corrected, r.changed, nil
+	case <-time.After(timeout):
+		return "", false, errCorrectionTimeout
+	}
+}
+
+// CorrectLine is called from a goroutine to repeat a nick's recent line with
+// a "s/pattern/replacement/" correction applied.
+func (bot *Scumbag) CorrectLine(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) <= 1 || !line.Public() {
+		return
+	}
+
+	c, err := parseCorrection(line.Args[1])
+	if err != nil {
+		bot.Log.WithField("error", err).Debug("CorrectLine(): Invalid pattern.")
+		return
+	}
+	if c == nil {
+		return
+	}
+
+	server := conn.Config().Server
+	channel := line.Target()
+	nick := line.Nick
+	if c.nick != "" {
+		nick = c.nick
+	}
+
+	for _, recent := range bot.recentLines.nickLines(server, channel, nick, correctLines) {
+		corrected, changed, err := applyCorrection(c, recent.Text, correctTimeout)
+		if err != nil {
+			bot.Log.WithFields(log.Fields{"nick": line.Nick, "pattern": c.pattern}).Warn("CorrectLine(): Pattern timed out.")
+			return
+		}
+		if !changed {
+			continue
+		}
+
+		if strings.EqualFold(recent.Nick, line.Nick) {
+			bot.Msg(conn, channel, "%s meant: %s", recent.Nick, corrected)
+		} else {
+			bot.Msg(conn, channel, "%s thinks %s meant: %s", line.Nick, recent.Nick, corrected)
+		}
+		return
+	}
+}
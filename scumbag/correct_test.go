@@ -0,0 +1,76 @@
+package scumbag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCorrection(t *testing.T) {
+	for _, tc := range []struct {
+		text    string
+		nick    string
+		pattern string
+		global  bool
+		isNil   bool
+	}{
+		{text: "s/teh/the/", pattern: "teh"},
+		{text: "s/teh/the", pattern: "teh"},
+		{text: "s/teh/the/g", pattern: "teh", global: true},
+		{text: "s/teh/the/gi", pattern: "(?i)teh", global: true},
+		{text: "bob: s/teh/the/", nick: "bob", pattern: "teh"},
+		{text: "bob, s/teh/the/", nick: "bob", pattern: "teh"},
+		{text: `s/a\/b/c/`, pattern: "a/b"},
+		{text: "s/teh/the/x", isNil: true},
+		{text: "s//the/", isNil: true},
+		{text: "this s/teh/the/", isNil: true},
+		{text: "just talking", isNil: true},
+	} {
+		c, err := parseCorrection(tc.text)
+		if err != nil {
+			t.Errorf("%q: %s", tc.text, err)
+			continue
+		}
+
+		if tc.isNil {
+			if c != nil {
+				t.Errorf("%q: expected nil, got %+v", tc.text, c)
+			}
+			continue
+		}
+
+		if c == nil || c.nick != tc.nick || c.pattern.String() != tc.pattern || c.global != tc.global {
+			t.Errorf("%q: wrong correction %+v", tc.text, c)
+		}
+	}
+
+	if _, err := parseCorrection("s/(/x/"); err == nil {
+		t.Error("Expected an invalid pattern error")
+	}
+}
+
+func TestCorrectionApply(t *testing.T) {
+	for _, tc := range []struct {
+		correction string
+		text       string
+		expected   string
+		changed    bool
+	}{
+		{"s/teh/the/", "teh cat and teh dog", "the cat and teh dog", true},
+		{"s/teh/the/g", "teh cat and teh dog", "the cat and the dog", true},
+		{"s/TEH/the/", "teh cat", "teh cat", false},
+		{"s/TEH/the/i", "teh cat", "the cat", true},
+		{`s/cat/a\/b/`, "teh cat", "teh a/b", true},
+		{"s/c(a)t/$1/", "teh cat", "teh $1", true},
+		{"s/dog/cat/", "teh cat", "teh cat", false},
+	} {
+		c, err := parseCorrection(tc.correction)
+		if err != nil || c == nil {
+			t.Fatalf("%q: %+v, %v", tc.correction, c, err)
+		}
+
+		corrected, changed, err := applyCorrection(c, tc.text, time.Second)
+		if err != nil || corrected != tc.expected || changed != tc.changed {
+			t.Errorf("%q on %q: expected %q (%v), got %q (%v), %v", tc.correction, tc.text, tc.expected, tc.changed, corrected, changed, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package scumbag
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	cliDBCopy = "copy"
+)
+
+// copyTable is a table copied by "db copy".
+type copyTable struct {
+	name    string
+	columns []string
+}
+
+// copyTables are copied in order, so tables come after the tables they reference.
+var copyTables = []*copyTable{
+	{"links", []string{"id", "nick", "url", "canonical_url", "server", "channel", "reposts", "status_code", "checked_at", "dead_since", "archived_at", "created_at"}},
+	{"ignored_nicks", []string{"id", "server", "nick", "created_at"}},
+	{"link_purges", []string{"id", "server", "channel", "nick", "requested_by", "reason", "deleted", "created_at"}},
+	{"link_tags", []string{"id", "link_id", "tag", "nick", "created_at"}},
+	{"link_favorites", []string{"id", "link_id", "server", "nick", "created_at"}},
+	{"seen", []string{"id", "server", "channel", "nick", "action", "message", "target", "created_at"}},
+	{"memos", []string{"id", "server", "channel", "sender", "recipient", "message", "private", "created_at"}},
+	{"reminders", []string{"id", "server", "channel", "nick", "mention", "message", "timezone", "repeat", "remind_at", "created_at"}},
+	{"karma", []string{"id", "server", "channel", "thing", "nick", "delta", "reason", "created_at"}},
+	{"quotes", []string{"id", "server", "channel", "nick", "text", "added_by", "created_at"}},
+	{"factoids", []string{"id", "server", "channel", "name", "value", "locked", "nick", "created_at", "updated_at"}},
+	{"factoid_edits", []string{"id", "server", "channel", "name", "action", "value", "nick", "created_at"}},
+	{"channel_log", []string{"id", "server", "channel", "nick", "kind", "text", "created_at"}},
+	{"polls", []string{"id", "server", "channel", "question", "options", "nick", "status", "closes_at", "created_at"}},
+	{"poll_votes", []string{"id", "poll_id", "voter", "nick", "choice", "created_at"}},
+	{"trivia_answers", []string{"id", "server", "channel", "nick", "question", "points", "streak", "created_at"}},
+}
+
+func (bot *Scumbag) dbCLI(out io.Writer, args []string) error {
+	if len(args) <= 0 {
+		return fmt.Errorf("Usage: db <%s>", cliDBCopy)
+	}
+
+	switch args[0] {
+	case cliDBCopy:
+		return bot.copyDatabase(out, args[1:])
+	default:
+		return fmt.Errorf("Unknown db command: %s", args[0])
+	}
+}
+
+// copyDatabase migrates another (empty) database and copies everything from
+// the configured database into it, e.g.
+//
+//	db copy -driver sqlite -path scumbag.db
+func (bot *Scumbag) copyDatabase(out io.Writer, args []string) error {
+	target := &DatabaseConfig{}
+
+	flags := flag.NewFlagSet(cliDBCopy, flag.ContinueOnError)
+	flags.StringVar(&target.Driver, "driver", databaseDriverSQLite, "Target database driver (postgres, sqlite)")
+	flags.StringVar(&target.Path, "path", "", "Target SQLite database file")
+	flags.StringVar(&target.Host, "host", "", "Target Postgres host")
+	flags.StringVar(&target.SSL, "ssl", "disable", "Target Postgres sslmode")
+	flags.StringVar(&target.Name, "name", "", "Target Postgres database name")
+	flags.StringVar(&target.User, "user", "", "Target Postgres user")
+	flags.StringVar(&target.Password, "password", "", "Target Postgres password")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if target.Driver == databaseDriverSQLite && target.Path == "" {
+		return fmt.Errorf("Usage: db copy -driver sqlite -path <file>")
+	}
+
+	source, ok := bot.Links.(*SQLStore)
+	if !ok {
+		return fmt.Errorf("Configured database can't be copied")
+	}
+
+	db, destination, err := openDatabase(target)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := destination.migrateUp(0); err != nil {
+		return err
+	}
+
+	for _, table := range copyTables {
+		copied, err := source.copyTable(destination, table)
+		if err != nil {
+			return fmt.Errorf("%s: %s", table.name, err)
+		}
+		fmt.Fprintf(out, "Copied %d rows from %s\n", copied, table.name)
+	}
+
+	return nil
+}
+
+// copyTable copies every row of `table` to `destination`, keeping IDs.
+func (store *SQLStore) copyTable(destination *SQLStore, table *copyTable) (int, error) {
+	var existing int
+	if err := destination.queryRow(destination.db, "SELECT count(*) FROM "+table.name+";").Scan(&existing); err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, fmt.Errorf("destination table isn't empty")
+	}
+
+	columns := strings.Join(table.columns, ", ")
+	placeholders := make([]string, len(table.columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s);", table.name, columns, strings.Join(placeholders, ", "))
+
+	rows, err := store.query(store.db, "SELECT "+columns+" FROM "+table.name+" ORDER BY id;")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	tx, err := destination.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	copied := 0
+	values := make([]interface{}, len(table.columns))
+	pointers := make([]interface{}, len(table.columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, err
+		}
+
+		for i, value := range values {
+			switch v := value.(type) {
+			case time.Time:
+				values[i] = store.dialect.scanTime(v)
+			case []byte:
+				values[i] = string(v)
+			}
+		}
+
+		if _, err := destination.exec(tx, insert, values...); err != nil {
+			return 0, err
+		}
+		copied++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Rows were inserted with their IDs, so new rows need to start after them.
+	if destination.dialect.resetIDSQL != "" {
+		if _, err := destination.exec(tx, fmt.Sprintf(destination.dialect.resetIDSQL, table.name)); err != nil {
+			return 0, err
+		}
+	}
+
+	return copied, tx.Commit()
+}
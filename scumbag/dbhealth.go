@@ -0,0 +1,209 @@
+package scumbag
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dbPingTimeout  = 5 * time.Second
+	dbPingAttempts = 5
+	// Doubled after each failed startup ping.
+	dbPingBackoff = time.Second
+
+	// How often a down database is re-checked.
+	dbRetryInterval = 30 * time.Second
+
+	// Most links held while the database is down; older ones are dropped first.
+	dbQueueMax = 1000
+)
+
+// dbHealth tracks whether the database is reachable, and links seen while it isn't.
+type dbHealth struct {
+	sync.Mutex
+
+	down      bool
+	downSince time.Time
+	lastError error
+
+	queue   []*Link
+	dropped int
+}
+
+// pingDatabase checks the connection on startup, so bad credentials stop the
+// bot right away. It retries with backoff in case the database is still starting.
+func (bot *Scumbag) pingDatabase() error {
+	if bot.db == nil {
+		return nil
+	}
+
+	backoff := dbPingBackoff
+	var err error
+	for attempt := 1; attempt <= dbPingAttempts; attempt++ {
+		if err = bot.ping(); err == nil {
+			return nil
+		}
+
+		bot.Log.WithFields(log.Fields{"attempt": attempt, "err": err}).Warn("Database ping failed.")
+		if attempt < dbPingAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return fmt.Errorf("database unreachable after %d attempts: %s", dbPingAttempts, err)
+}
+
+func (bot *Scumbag) ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+	defer cancel()
+
+	return bot.db.PingContext(ctx)
+}
+
+// databaseDown returns true if the database is known to be down.
+func (bot *Scumbag) databaseDown() bool {
+	bot.dbHealth.Lock()
+	defer bot.dbHealth.Unlock()
+
+	return bot.dbHealth.down
+}
+
+// checkDatabase is called after a failed query. If the database doesn't answer
+// a ping either it's marked down and true is returned.
+func (bot *Scumbag) checkDatabase(queryErr error) bool {
+	if bot.db == nil {
+		return false
+	}
+
+	if err := bot.ping(); err == nil {
+		return false
+	}
+
+	bot.dbHealth.Lock()
+	defer bot.dbHealth.Unlock()
+
+	if !bot.dbHealth.down {
+		bot.dbHealth.down = true
+		bot.dbHealth.downSince = time.Now()
+		bot.LogError("checkDatabase(): Database down", queryErr)
+	}
+	bot.dbHealth.lastError = queryErr
+
+	return true
+}
+
+// queueLink holds a link until the database is back.
+func (bot *Scumbag) queueLink(link *Link) {
+	bot.dbHealth.Lock()
+	defer bot.dbHealth.Unlock()
+
+	if len(bot.dbHealth.queue) >= dbQueueMax {
+		bot.dbHealth.queue = bot.dbHealth.queue[1:]
+		bot.dbHealth.dropped++
+	}
+	bot.dbHealth.queue = append(bot.dbHealth.queue, link)
+
+	bot.Log.WithFields(log.Fields{"url": link.URL, "queued": len(bot.dbHealth.queue)}).Debug("queueLink()")
+}
+
+// startDatabaseMonitor re-checks a down database and saves queued links once it's back.
+func (bot *Scumbag) startDatabaseMonitor() {
+	if bot.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(dbRetryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+
+			if !bot.databaseDown() {
+				continue
+			}
+
+			if err := bot.ping(); err != nil {
+				bot.dbHealth.Lock()
+				bot.dbHealth.lastError = err
+				bot.dbHealth.Unlock()
+				continue
+			}
+
+			bot.databaseUp()
+		}
+	}()
+}
+
+// databaseUp marks the database up and saves the queued links.
+func (bot *Scumbag) databaseUp() {
+	bot.dbHealth.Lock()
+	queue := bot.dbHealth.queue
+	downtime := time.Since(bot.dbHealth.downSince)
+	bot.dbHealth.down = false
+	bot.dbHealth.lastError = nil
+	bot.dbHealth.queue = nil
+	bot.dbHealth.Unlock()
+
+	bot.Log.WithFields(log.Fields{"downtime": downtime, "queued": len(queue)}).Info("Database up.")
+
+	for i, link := range queue {
+		if err := bot.saveLink(link); err != nil {
+			// Down again; put back what's left.
+			if bot.checkDatabase(err) {
+				for _, unsaved := range queue[i:] {
+					bot.queueLink(unsaved)
+				}
+				return
+			}
+			bot.LogError("databaseUp()", err)
+		}
+	}
+}
+
+// saveLink saves a new link or counts a repost of an existing one.
+func (bot *Scumbag) saveLink(link *Link) error {
+	original, err := bot.Links.FindLink(link.Server, link.Channel, link.URL, link.CanonicalURL)
+	if err != nil {
+		return err
+	}
+
+	if original != nil {
+		return bot.Links.AddRepost(original.ID)
+	}
+	return bot.Links.SaveLink(link)
+}
+
+// databaseStatus describes the database health and connection pool, for admins.
+func (bot *Scumbag) databaseStatus() string {
+	if bot.db == nil {
+		return "Database: none"
+	}
+
+	stats := bot.db.Stats()
+	pool := fmt.Sprintf("%d open, %d in use, %d idle", stats.OpenConnections, stats.InUse, stats.Idle)
+
+	bot.dbHealth.Lock()
+	defer bot.dbHealth.Unlock()
+
+	queued := fmt.Sprintf("%d links queued", len(bot.dbHealth.queue))
+	if bot.dbHealth.dropped > 0 {
+		queued += fmt.Sprintf(", %d dropped", bot.dbHealth.dropped)
+	}
+
+	if !bot.dbHealth.down {
+		return fmt.Sprintf("Database: up (%s), %s", pool, queued)
+	}
+
+	return fmt.Sprintf("Database: DOWN since %s (%s), %s", humanize.Time(bot.dbHealth.downSince), bot.dbHealth.lastError, queued)
+}
@@ -0,0 +1,46 @@
+package scumbag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatabaseDownQueuesLinks(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatalf("Error creating bot: %s", err)
+	}
+
+	store := newTestSQLiteStore(t)
+	bot.db, bot.Links = store.db, store
+	bot.db.Close()
+
+	if !bot.checkDatabase(errors.New("connection refused")) || !bot.databaseDown() {
+		t.Fatal("Expected the database to be marked down")
+	}
+
+	link := &Link{Nick: "alice", URL: "https://example.com/", Server: "irc.example.com", Channel: "#scumbag", CreatedAt: time.Now()}
+	link.CanonicalURL = CanonicalURL(link.URL)
+	bot.queueLink(link)
+
+	if status := bot.databaseStatus(); !strings.Contains(status, "DOWN") || !strings.Contains(status, "1 links queued") {
+		t.Errorf("Unexpected status: %s", status)
+	}
+
+	// The database comes back.
+	store = newTestSQLiteStore(t)
+	bot.db, bot.Links = store.db, store
+	bot.databaseUp()
+
+	if bot.databaseDown() {
+		t.Error("Expected the database to be marked up")
+	}
+	if count, _ := store.CountLinks(&LinkFilter{}); count != 1 {
+		t.Errorf("Expected the queued link to be saved, got %d links", count)
+	}
+	if status := bot.databaseStatus(); !strings.Contains(status, "up") || !strings.Contains(status, "0 links queued") {
+		t.Errorf("Unexpected status: %s", status)
+	}
+}
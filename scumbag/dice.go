@@ -0,0 +1,270 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	diceMaxGroups = 10
+	// Most dice rolled in one expression, not counting explosions.
+	diceMaxDice  = 100
+	diceMaxSides = 1000
+	// Most extra dice one group's explosions can add.
+	diceMaxExplosions = 100
+)
+
+var rollHelp = []string{
+	cmdRoll + " <dice> -- roll dice, e.g. d20, 3d6+2, 2d20kl1 or 4d6kh3 (keep highest/lowest), 6d10! (exploding), 1d8+2d6-1",
+}
+
+// diceTermRegexp matches one "+NdS" group of dice, with optional "!" and
+// "kh3"/"kl3"/"k3" modifiers, or a "+N" constant.
+var diceTermRegexp = regexp.MustCompile(`\A([+-])?(?:((\d{0,3})d(\d{1,4}|%)((?:!|k[hl]?\d{0,3})*))|(\d{1,6}))`)
+
+var diceKeepRegexp = regexp.MustCompile(`k([hl])?(\d*)`)
+
+// diceGroup is a group of dice ("4d6kh3") or a constant ("2") in a roll.
+type diceGroup struct {
+	notation string
+	// -1 if the group is subtracted.
+	sign     int
+	constant int
+
+	count      int
+	sides      int
+	explode    bool
+	keep       int // 0 keeps them all.
+	keepLowest bool
+
+	rolls   []int
+	dropped []bool
+}
+
+// parseDice parses a dice expression like "4d6kh3+2".
+func parseDice(expr string) ([]*diceGroup, error) {
+	expr = strings.ToLower(strings.Join(strings.Fields(expr), ""))
+	if expr == "" {
+		return nil, fmt.Errorf("Roll what?")
+	}
+
+	var groups []*diceGroup
+	dice := 0
+	for rest := expr; rest != ""; {
+		match := diceTermRegexp.FindStringSubmatch(rest)
+		if match == nil || (len(groups) > 0 && match[1] == "") {
+			return nil, fmt.Errorf("Can't parse %q.", rest)
+		}
+		rest = rest[len(match[0]):]
+
+		group := &diceGroup{sign: 1}
+		if match[1] == "-" {
+			group.sign = -1
+		}
+		groups = append(groups, group)
+		if len(groups) > diceMaxGroups {
+			return nil, fmt.Errorf("Too many groups; %d at most.", diceMaxGroups)
+		}
+
+		if match[6] != "" {
+			group.notation = match[6]
+			group.constant, _ = strconv.Atoi(match[6])
+			continue
+		}
+
+		group.notation = match[2]
+		group.count = 1
+		if match[3] != "" {
+			group.count, _ = strconv.Atoi(match[3])
+		}
+		group.sides = 100
+		if match[4] != "%" {
+			group.sides, _ = strconv.Atoi(match[4])
+		}
+
+		modifiers := match[5]
+		group.explode = strings.Contains(modifiers, "!")
+		keeps := diceKeepRegexp.FindAllStringSubmatch(modifiers, -1)
+		if len(keeps) > 1 {
+			return nil, fmt.Errorf("Only one keep per group: %s", group.notation)
+		}
+		if len(keeps) == 1 {
+			group.keepLowest = keeps[0][1] == "l"
+			group.keep = 1
+			if keeps[0][2] != "" {
+				group.keep, _ = strconv.Atoi(keeps[0][2])
+			}
+		}
+
+		switch {
+		case group.count <= 0:
+			return nil, fmt.Errorf("Roll at least one die: %s", group.notation)
+		case group.sides <= 0 || group.sides > diceMaxSides:
+			return nil, fmt.Errorf("Dice have 1 to %d sides: %s", diceMaxSides, group.notation)
+		case group.explode && group.sides == 1:
+			return nil, fmt.Errorf("A d1 would explode forever: %s", group.notation)
+		case len(keeps) == 1 && group.keep <= 0:
+			return nil, fmt.Errorf("Keep at least one die: %s", group.notation)
+		}
+
+		if dice += group.count; dice > diceMaxDice {
+			return nil, fmt.Errorf("Too many dice; %d at most.", diceMaxDice)
+		}
+	}
+
+	return groups, nil
+}
+
+// roll rolls the group's dice, adding a die for each that explodes and
+// dropping the ones not kept.
+func (group *diceGroup) roll(r *lockedRand) {
+	if group.count <= 0 {
+		return
+	}
+
+	explosions := 0
+	for i := 0; i < group.count; i++ {
+		value := r.Intn(group.sides) + 1
+		group.rolls = append(group.rolls, value)
+
+		for group.explode && value == group.sides && explosions < diceMaxExplosions {
+			value = r.Intn(group.sides) + 1
+			group.rolls = append(group.rolls, value)
+			explosions++
+		}
+	}
+
+	group.dropped = make([]bool, len(group.rolls))
+	if group.keep <= 0 || group.keep >= len(group.rolls) {
+		return
+	}
+
+	order := make([]int, len(group.rolls))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if group.keepLowest {
+			return group.rolls[order[i]] < group.rolls[order[j]]
+		}
+		return group.rolls[order[i]] > group.rolls[order[j]]
+	})
+	for _, i := range order[group.keep:] {
+		group.dropped[i] = true
+	}
+}
+
+func (group *diceGroup) total() int {
+	if group.count <= 0 {
+		return group.sign * group.constant
+	}
+
+	total := 0
+	for i, value := range group.rolls {
+		if !group.dropped[i] {
+			total += value
+		}
+	}
+	return group.sign * total
+}
+
+// String shows the group's rolls, with "!" on dice that exploded and dropped dice in parentheses.
+func (group *diceGroup) String() string {
+	if group.count <= 0 {
+		return group.notation
+	}
+
+	var rolls []string
+	for i, value := range group.rolls {
+		rolled := strconv.Itoa(value)
+		if group.explode && value == group.sides {
+			rolled += "!"
+		}
+		if group.dropped[i] {
+			rolled = "(" + rolled + ")"
+		}
+		rolls = append(rolls, rolled)
+	}
+	return fmt.Sprintf("%s [%s]", group.notation, strings.Join(rolls, ", "))
+}
+
+// rollDice rolls `expr` and describes the result, e.g. "4d6kh3 [6, 5, 4, (2)] + 2 = 17".
+func rollDice(expr string, r *lockedRand) (string, error) {
+	groups, err := parseDice(expr)
+	if err != nil {
+		return "", err
+	}
+
+	var description strings.Builder
+	total := 0
+	for i, group := range groups {
+		group.roll(r)
+		total += group.total()
+
+		switch {
+		case i > 0 && group.sign < 0:
+			description.WriteString(" - ")
+		case i > 0:
+			description.WriteString(" + ")
+		case group.sign < 0:
+			description.WriteString("-")
+		}
+		description.WriteString(group.String())
+	}
+
+	return fmt.Sprintf("%s = %d", description.String(), total), nil
+}
+
+// RollCommand rolls dice.
+type RollCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewRollCommand returns a new RollCommand instance.
+func NewRollCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *RollCommand {
+	return &RollCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *RollCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RollCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	result, err := rollDice(args[0], rng)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s rolls %s", cmd.line.Nick, result)
+}
+
+// Help shows the command help.
+func (cmd *RollCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RollCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range rollHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
@@ -0,0 +1,185 @@
+package scumbag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDice(t *testing.T) {
+	for _, tc := range []struct {
+		expr       string
+		groups     int
+		count      int
+		sides      int
+		keep       int
+		keepLowest bool
+		explode    bool
+		err        bool
+	}{
+		{expr: "d20", groups: 1, count: 1, sides: 20},
+		{expr: "3d6+2", groups: 2, count: 3, sides: 6},
+		{expr: "4d6kh3+2", groups: 2, count: 4, sides: 6, keep: 3},
+		{expr: "4D6 K3", groups: 1, count: 4, sides: 6, keep: 3},
+		{expr: "2d20kl1", groups: 1, count: 2, sides: 20, keep: 1, keepLowest: true},
+		{expr: "2d20kl", groups: 1, count: 2, sides: 20, keep: 1, keepLowest: true},
+		{expr: "6d10!", groups: 1, count: 6, sides: 10, explode: true},
+		{expr: "6d10!kh2", groups: 1, count: 6, sides: 10, keep: 2, explode: true},
+		{expr: "6d10kh2!", groups: 1, count: 6, sides: 10, keep: 2, explode: true},
+		{expr: "d%", groups: 1, count: 1, sides: 100},
+		{expr: "-1d4+1d8-2", groups: 3, count: 1, sides: 4},
+		{expr: "", err: true},
+		{expr: "abc", err: true},
+		{expr: "0d6", err: true},
+		{expr: "1d0", err: true},
+		{expr: "1d1001", err: true},
+		{expr: "101d6", err: true},
+		{expr: "60d6+60d6", err: true},
+		{expr: "1d1!", err: true},
+		{expr: "4d6k0", err: true},
+		{expr: "4d6kh3kl1", err: true},
+		{expr: "1d6 2d6", err: true},
+		{expr: "1d6+", err: true},
+		{expr: "1+1+1+1+1+1+1+1+1+1+1", err: true},
+	} {
+		groups, err := parseDice(tc.expr)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tc.expr, err)
+			continue
+		}
+
+		if len(groups) != tc.groups {
+			t.Errorf("%q: expected %d groups, got %d", tc.expr, tc.groups, len(groups))
+			continue
+		}
+
+		group := groups[0]
+		if group.count != tc.count || group.sides != tc.sides || group.keep != tc.keep || group.keepLowest != tc.keepLowest || group.explode != tc.explode {
+			t.Errorf("%q: got %+v", tc.expr, group)
+		}
+	}
+}
+
+func TestParseDiceSigns(t *testing.T) {
+	groups, err := parseDice("-1d4+1d8-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, sign := range []int{-1, 1, -1} {
+		if groups[i].sign != sign {
+			t.Errorf("group %d: expected sign %d, got %d", i, sign, groups[i].sign)
+		}
+	}
+	if groups[2].constant != 2 {
+		t.Errorf("expected constant 2, got %d", groups[2].constant)
+	}
+}
+
+func TestDiceGroupRoll(t *testing.T) {
+	r := newLockedRand(1)
+
+	for i := 0; i < 100; i++ {
+		groups, err := parseDice("4d6kh3")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		group := groups[0]
+		group.roll(r)
+		if len(group.rolls) != 4 {
+			t.Fatalf("expected 4 rolls, got %v", group.rolls)
+		}
+
+		droppedValue, dropped := 0, 0
+		for i, value := range group.rolls {
+			if value < 1 || value > 6 {
+				t.Fatalf("roll out of range: %v", group.rolls)
+			}
+			if group.dropped[i] {
+				droppedValue = value
+				dropped++
+			}
+		}
+		if dropped != 1 {
+			t.Fatalf("expected one die dropped, got %v %v", group.rolls, group.dropped)
+		}
+
+		total := 0
+		for i, value := range group.rolls {
+			if !group.dropped[i] {
+				total += value
+				if value < droppedValue {
+					t.Fatalf("kept a lower die than the one dropped: %v %v", group.rolls, group.dropped)
+				}
+			}
+		}
+		if group.total() != total {
+			t.Fatalf("expected total %d, got %d", total, group.total())
+		}
+	}
+}
+
+func TestDiceGroupExplode(t *testing.T) {
+	r := newLockedRand(1)
+
+	exploded := false
+	for i := 0; i < 100; i++ {
+		groups, err := parseDice("1d2!")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		group := groups[0]
+		group.roll(r)
+
+		// Every die but the last rolled the maximum.
+		for i, value := range group.rolls {
+			if i < len(group.rolls)-1 && value != 2 {
+				t.Fatalf("exploded on a %d: %v", value, group.rolls)
+			}
+		}
+		if len(group.rolls) > 1 {
+			exploded = true
+			if !strings.Contains(group.String(), "2!") {
+				t.Errorf("expected an exploded die in %q", group.String())
+			}
+		}
+		if len(group.rolls) > diceMaxExplosions+1 {
+			t.Fatalf("too many explosions: %d", len(group.rolls))
+		}
+	}
+
+	if !exploded {
+		t.Error("expected a die to explode in 100 rolls")
+	}
+}
+
+func TestRollDice(t *testing.T) {
+	result, err := rollDice("2d1+3-1d1", newLockedRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "2d1 [1, 1] + 3 - 1d1 [1] = 4"; result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	result, err = rollDice("3d1kl2", newLockedRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "3d1kl2 [1, 1, (1)] = 2"; result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	if _, err := rollDice("nope", newLockedRand(1)); err == nil {
+		t.Error("expected an error")
+	}
+}
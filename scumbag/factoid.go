@@ -0,0 +1,440 @@
+package scumbag
+
+import (
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	// Value modifiers: say the rest as is, or as a /me action.
+	factoidReply  = "<reply>"
+	factoidAction = "<action>"
+
+	// Replaced with the nick asking.
+	factoidNickVar = "$nick"
+
+	factoidGlobalFlag = "-global"
+
+	// Edit history actions.
+	factoidLearned   = "learned"
+	factoidEdited    = "edited"
+	factoidForgotten = "forgot"
+	factoidLocked    = "locked"
+	factoidUnlocked  = "unlocked"
+
+	factoidInfo    = "info"
+	factoidHistory = "history"
+	factoidLock    = "lock"
+	factoidUnlock  = "unlock"
+
+	factoidHistoryLimit = 5
+)
+
+var learnHelp = []string{
+	cmdLearn + " [" + factoidGlobalFlag + "] <key> is <value> -- teach a factoid, then ask for it with " + cmdPrefix + "<key>",
+	"Values can start with " + factoidReply + " or " + factoidAction + ", and " + factoidNickVar + " is replaced with the nick asking. " + factoidGlobalFlag + " factoids work in every channel (admins only).",
+}
+
+var forgetHelp = cmdForget + " [" + factoidGlobalFlag + "] <key> -- forget a factoid"
+
+var factoidHelp = []string{
+	cmdFactoid + " " + factoidInfo + " [" + factoidGlobalFlag + "] <key> -- who last changed a factoid, and whether it's locked",
+	cmdFactoid + " " + factoidHistory + " [" + factoidGlobalFlag + "] <key> -- a factoid's recent changes",
+	cmdFactoid + " <" + factoidLock + "|" + factoidUnlock + "> [" + factoidGlobalFlag + "] <key> -- only admins can change locked factoids",
+}
+
+// Factoid is a learned response to "<cmdPrefix><name>".
+type Factoid struct {
+	ID      int64
+	Server  string
+	Channel string
+	Name    string
+	Value   string
+	Locked  bool
+	// Who last changed it.
+	Nick      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FactoidEdit is one change in a factoid's history.
+type FactoidEdit struct {
+	Action    string
+	Value     string
+	Nick      string
+	CreatedAt time.Time
+}
+
+// Scope describes where the factoid applies.
+func (factoid *Factoid) Scope() string {
+	if factoid.Channel == "" {
+		return "global"
+	}
+	return factoid.Channel
+}
+
+// lookupFactoid answers "<cmdPrefix><name>" with the channel's factoid, or the
+// global one. It returns false if there's neither.
+func (bot *Scumbag) lookupFactoid(conn *irc.Conn, line *irc.Line, name string) bool {
+	if name == "" || bot.databaseDown() {
+		return false
+	}
+
+	channel := factoidChannel(line)
+	server := conn.Config().Server
+
+	factoid, err := bot.findFactoid(server, channel, strings.ToLower(name))
+	if err != nil {
+		bot.LogError("lookupFactoid()", err)
+		return false
+	}
+	if factoid == nil {
+		return false
+	}
+
+	replyTo := line.Target()
+	value := strings.Replace(factoid.Value, factoidNickVar, line.Nick, -1)
+	switch {
+	case strings.HasPrefix(value, factoidReply):
+		bot.Msg(conn, replyTo, "%s", strings.TrimSpace(strings.TrimPrefix(value, factoidReply)))
+	case strings.HasPrefix(value, factoidAction):
+		bot.Action(conn, replyTo, "%s", strings.TrimSpace(strings.TrimPrefix(value, factoidAction)))
+	default:
+		bot.Msg(conn, replyTo, "%s is %s", factoid.Name, value)
+	}
+
+	return true
+}
+
+// findFactoid returns the factoid in `channel`, or the global one, or nil.
+func (bot *Scumbag) findFactoid(server, channel, name string) (*Factoid, error) {
+	if channel != "" {
+		factoid, err := bot.Factoids.GetFactoid(server, channel, name)
+		if err != nil || factoid != nil {
+			return factoid, err
+		}
+	}
+
+	return bot.Factoids.GetFactoid(server, "", name)
+}
+
+// factoidChannel returns the channel for channel factoids, or "" in private messages.
+func factoidChannel(line *irc.Line) string {
+	if !line.Public() {
+		return ""
+	}
+	return line.Target()
+}
+
+// factoidArgs splits "[-global] <key> ..." into the factoid's channel, name and the remaining fields.
+// Global factoids and factoids in private messages have an empty channel.
+func factoidArgs(line *irc.Line, args []string) (channel, name string, rest []string) {
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+
+	channel = factoidChannel(line)
+	if len(fields) > 0 && fields[0] == factoidGlobalFlag {
+		channel = ""
+		fields = fields[1:]
+	}
+
+	if len(fields) <= 0 {
+		return channel, "", nil
+	}
+	return channel, strings.ToLower(strings.TrimPrefix(fields[0], cmdPrefix)), fields[1:]
+}
+
+// builtinCommand returns true if `name` is a command, so a factoid by that name would never be seen.
+func builtinCommand(name string) bool {
+	command := cmdPrefix + name
+	if command == cmdAdmin || command == cmdVersion {
+		return true
+	}
+
+	for _, helpCommand := range helpCommands {
+		if command == helpCommand {
+			return true
+		}
+	}
+	return false
+}
+
+// canChangeFactoid returns false and tells the nick why if they can't change the factoid.
+func (bot *Scumbag) canChangeFactoid(conn *irc.Conn, line *irc.Line, channel string, factoid *Factoid) bool {
+	if bot.Admin(line.Nick) {
+		return true
+	}
+
+	switch {
+	case channel == "":
+		bot.Msg(conn, line.Target(), "Only admins can change global factoids.")
+		return false
+	case factoid != nil && factoid.Locked:
+		bot.Msg(conn, line.Target(), "%s is locked.", factoid.Name)
+		return false
+	}
+	return true
+}
+
+// LearnCommand teaches the bot a factoid.
+type LearnCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewLearnCommand returns a new LearnCommand instance.
+func NewLearnCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *LearnCommand {
+	return &LearnCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *LearnCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("LearnCommand.Run()", err)
+		return
+	}
+
+	scope, name, rest := factoidArgs(cmd.line, args)
+	if name == "" || len(rest) < 2 || strings.ToLower(rest[0]) != "is" {
+		cmd.Help()
+		return
+	}
+
+	if builtinCommand(name) {
+		cmd.bot.Msg(cmd.conn, channel, "%s%s is already a command.", cmdPrefix, name)
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	existing, err := cmd.bot.Factoids.GetFactoid(server, scope, name)
+	if err != nil {
+		cmd.bot.LogError("LearnCommand.Run()", err)
+		return
+	}
+
+	if !cmd.bot.canChangeFactoid(cmd.conn, cmd.line, scope, existing) {
+		return
+	}
+
+	now := time.Now()
+	factoid := &Factoid{Server: server, Channel: scope, Name: name, Value: strings.Join(rest[1:], " "), Nick: cmd.line.Nick, CreatedAt: now, UpdatedAt: now}
+	action := factoidLearned
+	if existing != nil {
+		factoid.Locked = existing.Locked
+		action = factoidEdited
+	}
+
+	if err := cmd.bot.Factoids.SaveFactoid(factoid, action); err != nil {
+		cmd.bot.LogError("LearnCommand.Run()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "OK, %s%s (%s).", cmdPrefix, name, factoid.Scope())
+}
+
+// Help shows the command help.
+func (cmd *LearnCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("LearnCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range learnHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+// ForgetCommand deletes a factoid.
+type ForgetCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewForgetCommand returns a new ForgetCommand instance.
+func NewForgetCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *ForgetCommand {
+	return &ForgetCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *ForgetCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ForgetCommand.Run()", err)
+		return
+	}
+
+	scope, name, rest := factoidArgs(cmd.line, args)
+	if name == "" || len(rest) > 0 {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	existing, err := cmd.bot.Factoids.GetFactoid(server, scope, name)
+	if err != nil {
+		cmd.bot.LogError("ForgetCommand.Run()", err)
+		return
+	}
+
+	if existing == nil {
+		cmd.bot.Msg(cmd.conn, channel, "I don't know %s%s.", cmdPrefix, name)
+		return
+	}
+
+	if !cmd.bot.canChangeFactoid(cmd.conn, cmd.line, scope, existing) {
+		return
+	}
+
+	if _, err := cmd.bot.Factoids.DeleteFactoid(server, scope, name, cmd.line.Nick, time.Now()); err != nil {
+		cmd.bot.LogError("ForgetCommand.Run()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "Forgot %s%s (%s).", cmdPrefix, name, existing.Scope())
+}
+
+// Help shows the command help.
+func (cmd *ForgetCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("ForgetCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, forgetHelp)
+}
+
+// FactoidCommand shows and locks factoids.
+type FactoidCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewFactoidCommand returns a new FactoidCommand instance.
+func NewFactoidCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *FactoidCommand {
+	return &FactoidCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *FactoidCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("FactoidCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	fields := strings.Fields(args[0])
+	if len(fields) < 2 {
+		cmd.Help()
+		return
+	}
+
+	scope, name, rest := factoidArgs(cmd.line, []string{strings.Join(fields[1:], " ")})
+	if name == "" || len(rest) > 0 {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	factoid, err := cmd.bot.Factoids.GetFactoid(server, scope, name)
+	if err != nil {
+		cmd.bot.LogError("FactoidCommand.Run()", err)
+		return
+	}
+
+	switch fields[0] {
+	case factoidInfo:
+		if factoid == nil {
+			cmd.bot.Msg(cmd.conn, channel, "I don't know %s%s.", cmdPrefix, name)
+			return
+		}
+
+		locked := ""
+		if factoid.Locked {
+			locked = ", locked"
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s%s (%s%s) was last changed by %s %s", cmdPrefix, name, factoid.Scope(), locked, factoid.Nick, humanize.Time(factoid.UpdatedAt))
+
+	case factoidHistory:
+		edits, err := cmd.bot.Factoids.FactoidEdits(server, scope, name, factoidHistoryLimit)
+		if err != nil {
+			cmd.bot.LogError("FactoidCommand.Run()", err)
+			return
+		}
+
+		if len(edits) <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "%s%s has no history.", cmdPrefix, name)
+			return
+		}
+
+		for _, edit := range edits {
+			if edit.Value == "" || edit.Action == factoidLocked || edit.Action == factoidUnlocked {
+				cmd.bot.Msg(cmd.conn, channel, "%s %s it %s", edit.Nick, edit.Action, humanize.Time(edit.CreatedAt))
+			} else {
+				cmd.bot.Msg(cmd.conn, channel, "%s %s it %s: %s", edit.Nick, edit.Action, humanize.Time(edit.CreatedAt), edit.Value)
+			}
+		}
+
+	case factoidLock, factoidUnlock:
+		if !cmd.bot.Admin(cmd.line.Nick) {
+			cmd.bot.Msg(cmd.conn, channel, "Only admins can lock factoids.")
+			return
+		}
+
+		if factoid == nil {
+			cmd.bot.Msg(cmd.conn, channel, "I don't know %s%s.", cmdPrefix, name)
+			return
+		}
+
+		action := factoidLocked
+		factoid.Locked = fields[0] == factoidLock
+		if !factoid.Locked {
+			action = factoidUnlocked
+		}
+		factoid.Nick = cmd.line.Nick
+		factoid.UpdatedAt = time.Now()
+
+		if err := cmd.bot.Factoids.SaveFactoid(factoid, action); err != nil {
+			cmd.bot.LogError("FactoidCommand.Run()", err)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s%s (%s) is now %s.", cmdPrefix, name, factoid.Scope(), action)
+
+	default:
+		cmd.Help()
+	}
+}
+
+// Help shows the command help.
+func (cmd *FactoidCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("FactoidCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range factoidHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
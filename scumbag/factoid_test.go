@@ -0,0 +1,44 @@
+package scumbag
+
+import (
+	"reflect"
+	"testing"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+func TestFactoidArgs(t *testing.T) {
+	public := &irc.Line{Nick: "alice", Cmd: "PRIVMSG", Args: []string{"#scumbag", "?learn"}}
+	private := &irc.Line{Nick: "alice", Cmd: "PRIVMSG", Args: []string{"scumbag", "?learn"}}
+
+	for _, tc := range []struct {
+		line    *irc.Line
+		args    string
+		channel string
+		name    string
+		rest    []string
+	}{
+		{public, "FAQ is read it", "#scumbag", "faq", []string{"is", "read", "it"}},
+		{public, "-global ?faq is read it", "", "faq", []string{"is", "read", "it"}},
+		{public, "faq", "#scumbag", "faq", []string{}},
+		{public, "-global", "", "", nil},
+		{private, "faq is read it", "", "faq", []string{"is", "read", "it"}},
+	} {
+		channel, name, rest := factoidArgs(tc.line, []string{tc.args})
+		if channel != tc.channel || name != tc.name || !reflect.DeepEqual(rest, tc.rest) {
+			t.Errorf("%q: expected %q %q %q, got %q %q %q", tc.args, tc.channel, tc.name, tc.rest, channel, name, rest)
+		}
+	}
+}
+
+func TestBuiltinCommand(t *testing.T) {
+	for _, name := range []string{"url", "admin", "version", "learn"} {
+		if !builtinCommand(name) {
+			t.Errorf("%s should be a command", name)
+		}
+	}
+
+	if builtinCommand("faq") {
+		t.Error("faq shouldn't be a command")
+	}
+}
@@ -3,15 +3,27 @@ package scumbag
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 
 	irc "github.com/fluffle/goirc/client"
 )
 
 const (
-	githubUserEventsURL = "https://api.github.com/users/%s/events"
-	githubHelp          = cmdPrefix + "gh <username>"
+	githubUserEventsURL  = "https://api.github.com/users/%s/events"
+	githubRepoURL        = "https://api.github.com/repos/%s/%s"
+	githubPullRequestURL = githubRepoURL + "/pulls/%s"
+	githubIssueURL       = githubRepoURL + "/issues/%s"
+	githubHelp           = cmdPrefix + "gh <username>"
 )
 
+func init() {
+	RegisterUnfurler(&Unfurler{
+		Name:   "github",
+		Match:  regexp.MustCompile(`\Ahttps?://(?:www\.)?github\.com/([\w.-]+)/([\w.-]+)(?:/(pull|issues)/(\d+))?/?(?:[?#].*)?\z`),
+		Format: unfurlGithub,
+	})
+}
+
 // GithubEvent stores a Github API response.
 type GithubEvent struct {
 	Payload GithubPayload `json:"payload"`
@@ -64,6 +76,30 @@ type GithubCommitStats struct {
 	Deletions int `json:"deletions"`
 }
 
+// GithubRepository stores information on a single repository.
+type GithubRepository struct {
+	FullName        string `json:"full_name"`
+	Description     string `json:"description"`
+	Language        string `json:"language"`
+	StargazersCount int    `json:"stargazers_count"`
+	ForksCount      int    `json:"forks_count"`
+}
+
+// GithubIssue stores information on a single issue or pull request.
+type GithubIssue struct {
+	Number   int        `json:"number"`
+	Title    string     `json:"title"`
+	State    string     `json:"state"`
+	Merged   bool       `json:"merged"`
+	Comments int        `json:"comments"`
+	User     GithubUser `json:"user"`
+}
+
+// GithubUser stores information on a user.
+type GithubUser struct {
+	Login string `json:"login"`
+}
+
 // GithubCommand interacts with the Github API.
 type GithubCommand struct {
 	BaseCommand
@@ -186,3 +222,80 @@ func (cmd *GithubCommand) pullRequestEvent(event GithubEvent) {
 	cmd.bot.Msg(cmd.conn, channel, eventMsg)
 	cmd.bot.Msg(cmd.conn, channel, event.Payload.PullRequest.HTMLURL)
 }
+
+func (cmd *GithubCommand) getRepository(owner, repo string) (*GithubRepository, error) {
+	content, err := getContent(fmt.Sprintf(githubRepoURL, owner, repo))
+	if err != nil {
+		return nil, err
+	}
+
+	var repository GithubRepository
+	if err := json.Unmarshal(content, &repository); err != nil {
+		return nil, err
+	}
+
+	return &repository, nil
+}
+
+// getIssue fetches an issue, or a pull request if `pull` is true.
+func (cmd *GithubCommand) getIssue(owner, repo, number string, pull bool) (*GithubIssue, error) {
+	requestURL := fmt.Sprintf(githubIssueURL, owner, repo, number)
+	if pull {
+		requestURL = fmt.Sprintf(githubPullRequestURL, owner, repo, number)
+	}
+
+	content, err := getContent(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var issue GithubIssue
+	if err := json.Unmarshal(content, &issue); err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+func unfurlGithub(bot *Scumbag, conn *irc.Conn, line *irc.Line, match []string) (string, error) {
+	cmd := NewGithubCommand(bot, conn, line)
+	owner, repo, kind, number := match[1], match[2], match[3], match[4]
+
+	if number == "" {
+		repository, err := cmd.getRepository(owner, repo)
+		if err != nil {
+			return "", err
+		}
+		if repository.FullName == "" {
+			// Not a repository (e.g. github.com/settings/profile).
+			return "", nil
+		}
+
+		summary := fmt.Sprintf("%s: %s [%d stars, %d forks]", repository.FullName, repository.Description, repository.StargazersCount, repository.ForksCount)
+		if repository.Language != "" {
+			summary += " " + repository.Language
+		}
+		return summary, nil
+	}
+
+	pull := kind == "pull"
+	issue, err := cmd.getIssue(owner, repo, number, pull)
+	if err != nil {
+		return "", err
+	}
+	if issue.Number == 0 {
+		return "", nil
+	}
+
+	state := issue.State
+	if issue.Merged {
+		state = "merged"
+	}
+
+	label := "Issue"
+	if pull {
+		label = "PR"
+	}
+
+	return fmt.Sprintf("%s/%s %s #%d [%s] %s (by %s, %d comments)", owner, repo, label, issue.Number, state, issue.Title, issue.User.Login, issue.Comments), nil
+}
@@ -3,6 +3,8 @@ package scumbag
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 
 	irc "github.com/fluffle/goirc/client"
 )
@@ -15,6 +17,14 @@ const (
 	hackerNewsItem        = hackerNewsAPIURL + "/item/%d.json"
 )
 
+func init() {
+	RegisterUnfurler(&Unfurler{
+		Name:   "hackernews",
+		Match:  regexp.MustCompile(`\Ahttps?://news\.ycombinator\.com/item\?id=(\d+)`),
+		Format: unfurlHackerNews,
+	})
+}
+
 var hackerNewsHelp = []string{
 	"Get top story:    " + cmdPrefix + "hn",
 	"Get newest story: " + cmdPrefix + "hn -new",
@@ -28,14 +38,15 @@ type HackerNewsItem struct {
 	Score int    `json:"score"`
 	URL   string `json:"url"`
 
-	// Type        string  `json:"type"`
-	// By          string  `json:"by"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Descendants int    `json:"descendants"`
+
 	// Time        int64   `json:"time"`
 	// Text        string  `json:"text"`
 	// Deleted     bool    `json:"deleted"`
 	// Parent      int64   `json:"parent"`
 	// Kids        []int64 `json:"kids"`
-	// Descendants int     `json:"descendants"`
 }
 
 // HackerNewsCommand interacts with the Hacker News API.
@@ -185,3 +196,22 @@ func (cmd *HackerNewsCommand) msgItem(item HackerNewsItem) {
 	cmd.bot.Msg(cmd.conn, channel, message)
 	cmd.bot.Msg(cmd.conn, channel, item.URL)
 }
+
+func unfurlHackerNews(bot *Scumbag, conn *irc.Conn, line *irc.Line, match []string) (string, error) {
+	storyID, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	item, err := NewHackerNewsCommand(bot, conn, line).getItem(storyID)
+	if err != nil {
+		return "", err
+	}
+
+	if item.Type != "story" {
+		// Comments and jobs have no score worth showing.
+		return "", nil
+	}
+
+	return fmt.Sprintf("[%d points, %d comments] %s (by %s)", item.Score, item.Descendants, item.Title, item.By), nil
+}
@@ -16,20 +16,42 @@ type HelpCommand struct {
 }
 
 var helpCommands = []string{
+	cmdCalc,
+	cmdChoose,
+	cmdConvert,
+	cmdEightBall,
+	cmdFactoid,
 	cmdFiglet,
+	cmdFlip,
+	cmdForget,
 	cmdCorona,
 	cmdGame,
 	cmdGithub,
+	cmdGrab,
+	cmdGrep,
 	cmdHackerNews,
 	cmdHelp,
+	cmdKarma,
+	cmdLearn,
+	cmdMemos,
 	cmdMovie,
 	cmdNews,
+	cmdPoll,
+	cmdQuote,
 	cmdReddit,
+	cmdRemind,
+	cmdReminders,
+	cmdRoll,
+	cmdSeen,
 	cmdSpell,
+	cmdTell,
+	cmdTrivia,
 	cmdTwitter,
 	cmdURL,
+	cmdURLStats,
 	cmdUptime,
 	cmdUrbanDict,
+	cmdVote,
 	cmdWeather,
 	cmdWiki,
 	cmdWolfram,
@@ -49,30 +71,74 @@ func (cmd *HelpCommand) Run(args ...string) {
 
 	helpPhrase := args[0]
 	switch helpPhrase {
+	case strings.TrimLeft(cmdCalc, cmdPrefix):
+		NewCalcCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdChoose, cmdPrefix):
+		NewChooseCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdConvert, cmdPrefix):
+		NewConvertCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdCorona, cmdPrefix):
 		NewCoronaCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdEightBall, cmdPrefix):
+		NewEightBallCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdFactoid, cmdPrefix):
+		NewFactoidCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdFiglet, cmdPrefix):
 		NewFigletCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdFlip, cmdPrefix):
+		NewFlipCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdForget, cmdPrefix):
+		NewForgetCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdGame, cmdPrefix):
 		NewGameCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdGithub, cmdPrefix):
 		NewGithubCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdGrab, cmdPrefix):
+		NewGrabCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdGrep, cmdPrefix):
+		NewGrepCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdHackerNews, cmdPrefix):
 		NewHackerNewsCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdKarma, cmdPrefix):
+		NewKarmaCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdLearn, cmdPrefix):
+		NewLearnCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdMemos, cmdPrefix):
+		NewMemosCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdNews, cmdPrefix):
 		NewNewsCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdPoll, cmdPrefix):
+		NewPollCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdQuote, cmdPrefix):
+		NewQuoteCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdReddit, cmdPrefix):
 		NewRedditCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdRemind, cmdPrefix):
+		NewRemindCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdReminders, cmdPrefix):
+		NewRemindersCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdRoll, cmdPrefix):
+		NewRollCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdSeen, cmdPrefix):
+		NewSeenCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdSpell, cmdPrefix):
 		NewSpellcheckCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdTell, cmdPrefix):
+		NewTellCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdTrivia, cmdPrefix):
+		NewTriviaCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdTwitter, cmdPrefix):
 		NewTwitterCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdUrbanDict, cmdPrefix):
 		NewUrbanDictionaryCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdURL, cmdPrefix):
 		NewLinkCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdURLStats, cmdPrefix):
+		NewLinkStatsCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdUptime, cmdPrefix):
 		NewUptimeCommand(cmd.bot, cmd.conn, cmd.line).Help()
+	case strings.TrimLeft(cmdVote, cmdPrefix):
+		NewVoteCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdWeather, cmdPrefix):
 		NewWeatherCommand(cmd.bot, cmd.conn, cmd.line).Help()
 	case strings.TrimLeft(cmdWiki, cmdPrefix):
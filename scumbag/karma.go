@@ -0,0 +1,247 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	karmaDefaultCooldown = 5 * time.Minute
+
+	// Longest thing karma can be given to.
+	karmaMaxThing = 64
+
+	karmaTopLimit = 5
+	karmaReasons  = 3
+
+	karmaTopFlag    = "-top"
+	karmaBottomFlag = "-bottom"
+
+	// Separates the votes from their reason; "#golang" is just a word.
+	karmaReasonMarker = "#"
+)
+
+var karmaHelp = []string{
+	"<thing>++ or <thing>-- [# reason] -- give or take karma",
+	cmdKarma + " <thing> -- show a thing's karma and recent reasons",
+	cmdKarma + " " + karmaTopFlag + " or " + karmaBottomFlag + " -- the channel's highest or lowest karma",
+}
+
+// A thing needs at least two letters or digits, so "--", "C++" and "i--" aren't votes.
+var karmaThingRegexp = regexp.MustCompile(`\w.*\w`)
+
+// Things with brackets, operators or quotes are code, like "a[i]++" or "(*n)--".
+var karmaCodeRegexp = regexp.MustCompile("[][(){};=<>\"'`*&|!]")
+
+// Winks aren't code; stripped before looking for semicolons.
+var karmaSmileys = strings.NewReplacer(";-)", "", ";)", "")
+
+// KarmaVote is one "thing++" or "thing--".
+type KarmaVote struct {
+	Server  string
+	Channel string
+	// Lowercase.
+	Thing string
+	// Who voted.
+	Nick string
+	// +1 or -1.
+	Delta     int
+	Reason    string
+	CreatedAt time.Time
+}
+
+// Karma is a thing's score in a channel.
+type Karma struct {
+	Thing   string
+	Score   int
+	Up      int
+	Down    int
+	Reasons []string
+}
+
+// karmaVotes returns the votes in `text`, with only Thing, Delta and Reason set.
+// A reason is everything after a "# " following the last vote, e.g. "bob++ # fixed the build".
+func karmaVotes(text string) []*KarmaVote {
+	// Braces or semicolons mean pasted code, e.g. "for (...; i++) { count++ }".
+	if strings.ContainsAny(karmaSmileys.Replace(text), "{};") {
+		return nil
+	}
+
+	var votes []*KarmaVote
+
+	fields := strings.Fields(text)
+	for i, field := range fields {
+		var delta int
+		switch {
+		case strings.HasSuffix(field, "++"):
+			delta = 1
+		case strings.HasSuffix(field, "--"):
+			delta = -1
+		default:
+			continue
+		}
+
+		thing := strings.ToLower(strings.TrimRight(strings.TrimLeft(field[:len(field)-2], "@"), ":,"))
+		if !karmaThingRegexp.MatchString(thing) || karmaCodeRegexp.MatchString(thing) || len(thing) > karmaMaxThing {
+			continue
+		}
+
+		vote := &KarmaVote{Thing: thing, Delta: delta}
+		votes = append(votes, vote)
+
+		if i+1 < len(fields) && fields[i+1] == karmaReasonMarker {
+			vote.Reason = strings.Join(fields[i+2:], " ")
+			break
+		}
+	}
+
+	return votes
+}
+
+// karmaCooldown returns how long a nick waits to vote on the same thing again.
+func (bot *Scumbag) karmaCooldown() time.Duration {
+	if bot.Config.Karma == nil || bot.Config.Karma.Cooldown == "" {
+		return karmaDefaultCooldown
+	}
+
+	cooldown, err := time.ParseDuration(bot.Config.Karma.Cooldown)
+	if err != nil {
+		bot.LogError("karmaCooldown()", err)
+		return karmaDefaultCooldown
+	}
+	return cooldown
+}
+
+// KarmaLine is called from a goroutine to count "thing++" and "thing--" in channel messages.
+func (bot *Scumbag) KarmaLine(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) <= 1 || !line.Public() || strings.HasPrefix(line.Args[1], cmdPrefix) {
+		return
+	}
+
+	votes := karmaVotes(line.Args[1])
+	if len(votes) <= 0 || bot.databaseDown() {
+		return
+	}
+
+	server := conn.Config().Server
+	channel := line.Target()
+	nick := line.Nick
+	cooldown := bot.karmaCooldown()
+
+	for _, vote := range votes {
+		if vote.Thing == strings.ToLower(nick) {
+			bot.Msg(conn, channel, "No self-karma, %s.", nick)
+			continue
+		}
+
+		last, err := bot.Karma.LastKarmaVote(server, channel, nick, vote.Thing)
+		if err != nil {
+			bot.LogError("KarmaLine()", err)
+			return
+		}
+		if time.Since(last) < cooldown {
+			bot.Log.WithFields(log.Fields{"nick": nick, "thing": vote.Thing}).Debug("KarmaLine(): Cooling down.")
+			continue
+		}
+
+		vote.Server = server
+		vote.Channel = channel
+		vote.Nick = nick
+		vote.CreatedAt = line.Time
+		if err := bot.Karma.AddKarma(vote); err != nil {
+			bot.LogError("KarmaLine()", err)
+			return
+		}
+	}
+}
+
+// KarmaCommand reports karma scores.
+type KarmaCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewKarmaCommand returns a new KarmaCommand instance.
+func NewKarmaCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *KarmaCommand {
+	return &KarmaCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *KarmaCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("KarmaCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	thing := strings.ToLower(strings.TrimSpace(args[0]))
+
+	if thing == karmaTopFlag || thing == karmaBottomFlag {
+		scores, err := cmd.bot.Karma.TopKarma(server, channel, karmaTopLimit, thing == karmaBottomFlag)
+		if err != nil {
+			cmd.bot.LogError("KarmaCommand.Run()", err)
+			return
+		}
+
+		if len(scores) <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "No karma in %s yet.", channel)
+			return
+		}
+
+		var formatted []string
+		for _, karma := range scores {
+			formatted = append(formatted, fmt.Sprintf("%s (%d)", karma.Thing, karma.Score))
+		}
+
+		title := "Top karma"
+		if thing == karmaBottomFlag {
+			title = "Bottom karma"
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s", title, strings.Join(formatted, ", "))
+		return
+	}
+
+	karma, err := cmd.bot.Karma.Karma(server, channel, thing, karmaReasons)
+	if err != nil {
+		cmd.bot.LogError("KarmaCommand.Run()", err)
+		return
+	}
+
+	if karma.Up+karma.Down <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "%s has no karma.", thing)
+		return
+	}
+
+	response := fmt.Sprintf("%s has %d karma (+%d/-%d)", thing, karma.Score, karma.Up, karma.Down)
+	if len(karma.Reasons) > 0 {
+		response += ": " + strings.Join(karma.Reasons, "; ")
+	}
+	cmd.bot.Msg(cmd.conn, channel, "%s", response)
+}
+
+// Help shows the command help.
+func (cmd *KarmaCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("KarmaCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range karmaHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
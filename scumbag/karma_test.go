@@ -0,0 +1,36 @@
+package scumbag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKarmaVotes(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		expected []*KarmaVote
+	}{
+		{"bob++", []*KarmaVote{{Thing: "bob", Delta: 1}}},
+		{"thanks Bob++ and golang--", []*KarmaVote{{Thing: "bob", Delta: 1}, {Thing: "golang", Delta: -1}}},
+		{"@alice: ++", nil},
+		{"@alice++ # fixed the build", []*KarmaVote{{Thing: "alice", Delta: 1, Reason: "fixed the build"}}},
+		{"alice++ # fixed #build bob++", []*KarmaVote{{Thing: "alice", Delta: 1, Reason: "fixed #build bob++"}}},
+		{"bob++ #golang rocks", []*KarmaVote{{Thing: "bob", Delta: 1}}},
+		{"alice++ #fixed bob++", []*KarmaVote{{Thing: "alice", Delta: 1}, {Thing: "bob", Delta: 1}}},
+		{"go++ #", []*KarmaVote{{Thing: "go", Delta: 1}}},
+		{"alice:++", []*KarmaVote{{Thing: "alice", Delta: 1}}},
+		{"-- +++ ----", nil},
+		{"C++ is great", nil},
+		{"i--", nil},
+		{"x++ in the loop", nil},
+		{"for (i = 0; i < n; i++) { a[i]++; count++ }", nil},
+		{"total++;", nil},
+		{"bob++ ;)", []*KarmaVote{{Thing: "bob", Delta: 1}}},
+		{"(*n)-- and \"s\"++", nil},
+		{"no votes here", nil},
+	} {
+		if actual := karmaVotes(tc.text); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%q: expected %+v, got %+v", tc.text, tc.expected, actual)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package scumbag
 
 import (
-	"database/sql"
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	irc "github.com/fluffle/goirc/client"
 	log "github.com/sirupsen/logrus"
 )
@@ -13,21 +14,35 @@ import (
 const (
 	searchLimit = 5
 	urlSep      = " | "
-
-	urlHelp = cmdPrefix + "url <username> or /<search>/"
 )
 
+var urlHelp = []string{
+	cmdURL + " <nick> or /<search>/",
+	cmdURL + " nick:<nick> domain:<domain> tag:<tag> fav:<nick> since:<2w/2020-01-31> until:<date> /<search>/ limit:<n> -random",
+	cmdURL + " -all-channels <filters> -- admin only",
+	cmdURL + " -top -- most reposted links",
+	cmdURL + " -archived <id> -- archived copy of a dead link",
+	cmdURL + " -forgetme -- delete all of your saved links",
+	cmdURL + " -tag/-untag <id> <tag>... -- tag a link",
+	cmdURL + " -fav/-unfav <id> -- favorite a link",
+}
+
 var (
 	urlRegexp = regexp.MustCompile(`((ftp|git|http|https):\/\/(\w+:{0,1}\w*@)?(\S+)(:[0-9]+)?(?:\/|\/([\w#!:.?+=&%@!\-\/]))?)`)
 )
 
 // Link represents a saved URL link.
 type Link struct {
-	Nick      string
-	URL       string
-	Server    string
-	Channel   string
-	CreatedAt time.Time
+	ID           int64
+	Nick         string
+	URL          string
+	CanonicalURL string
+	Server       string
+	Channel      string
+	Reposts      int
+	Dead         bool
+	Archived     bool
+	CreatedAt    time.Time
 }
 
 // LinkCommand interacts with the databased-saved URL links.
@@ -44,7 +59,7 @@ func NewLinkCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *LinkCommand {
 	return &LinkCommand{bot: bot, conn: conn, line: line}
 }
 
-// Run is the command handler for "<cmdPrefix>url <nick_or_regex>"
+// Run is the command handler for "<cmdPrefix>url <query>"; see LinkQuery.
 func (cmd *LinkCommand) Run(args ...string) {
 	channel, err := cmd.Channel(cmd.line)
 	if err != nil {
@@ -58,7 +73,49 @@ func (cmd *LinkCommand) Run(args ...string) {
 		return
 	}
 
-	links, err := cmd.SearchLinks(query)
+	fields := strings.Fields(query)
+	switch fields[0] {
+	case "-top":
+		cmd.topLinks(channel)
+		return
+	case "-forgetme":
+		cmd.forgetMe(channel, len(fields) == 2 && fields[1] == "confirm")
+		return
+	case "-archived":
+		if len(fields) != 2 {
+			cmd.Help()
+			return
+		}
+		cmd.archived(channel, fields[1])
+		return
+	case "-tag", "-untag":
+		if len(fields) < 3 {
+			cmd.Help()
+			return
+		}
+		cmd.tag(channel, fields[1], fields[2:], fields[0] == "-tag")
+		return
+	case "-fav", "-unfav":
+		if len(fields) != 2 {
+			cmd.Help()
+			return
+		}
+		cmd.favorite(channel, fields[1], fields[0] == "-fav")
+		return
+	}
+
+	linkQuery, err := ParseLinkQuery(query, time.Now())
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return
+	}
+
+	if linkQuery.AllChannels && !cmd.bot.Admin(cmd.line.Nick) {
+		cmd.bot.Msg(cmd.conn, channel, "-all-channels is admin only.")
+		return
+	}
+
+	links, err := cmd.SearchLinks(linkQuery)
 	if err != nil {
 		cmd.bot.LogError("LinkCommand.Run()", err)
 		return
@@ -66,7 +123,15 @@ func (cmd *LinkCommand) Run(args ...string) {
 
 	response := make([]string, len(links))
 	for i, link := range links {
-		response[i] = link.URL
+		response[i] = formatLinkID(link.ID) + " " + link.URL
+		if linkQuery.AllChannels {
+			response[i] += " (" + link.Channel + ")"
+		}
+		if link.Dead && link.Archived {
+			response[i] += " [dead, archived]"
+		} else if link.Dead {
+			response[i] += " [dead]"
+		}
 	}
 
 	cmd.bot.Msg(cmd.conn, channel, strings.Join(response, urlSep))
@@ -80,7 +145,9 @@ func (cmd *LinkCommand) Help() {
 		return
 	}
 
-	cmd.bot.Msg(cmd.conn, channel, urlHelp)
+	for _, helpText := range urlHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
 }
 
 // SaveURLs is called from a goroutine to save links from `conn.Config().Server` and `line`.
@@ -96,7 +163,7 @@ func (bot *Scumbag) SaveURLs(conn *irc.Conn, line *irc.Line) {
 	server := conn.Config().Server
 	msg := line.Args[1]
 
-	if urls := urlRegexp.FindAllString(msg, -1); urls != nil {
+	if urls := findURLs(msg); urls != nil {
 		if ignoredChannel(bot, server, channel) {
 			bot.Log.WithFields(log.Fields{"server": server, "channel": channel}).Debug("SaveURLs(): Ignored channel.")
 			return
@@ -107,30 +174,59 @@ func (bot *Scumbag) SaveURLs(conn *irc.Conn, line *irc.Line) {
 			return
 		}
 
+		// While the database is down links are queued, not dropped.
+		failed := func(link *Link, err error) {
+			if bot.checkDatabase(err) {
+				bot.queueLink(link)
+			} else {
+				bot.LogError("SaveURLs()", err)
+			}
+		}
+
 		for _, url := range urls {
-			var urlMatch string
+			link := &Link{Nick: nick, URL: url, CanonicalURL: CanonicalURL(url), Server: server, Channel: channel, CreatedAt: line.Time}
 
-			err := bot.DB.QueryRow("SELECT url FROM links WHERE url=$1 AND server=$2 AND channel=$3;", url, server, channel).Scan(&urlMatch)
+			if bot.databaseDown() {
+				bot.queueLink(link)
+				continue
+			}
+
+			original, err := bot.Links.FindLink(server, channel, url, link.CanonicalURL)
 			switch {
-			case err == sql.ErrNoRows:
+			case err != nil:
+				failed(link, err)
+
+			case original == nil:
 				// Link doesn't exist, so create one.
-				if _, insertErr := bot.DB.Exec("INSERT INTO links(nick, url, server, channel, created_at) VALUES($1, $2, $3, $4, $5) RETURNING id;", nick, url, server, channel, line.Time); insertErr != nil {
-					bot.LogError("SaveURLs()", insertErr)
+				if saveErr := bot.Links.SaveLink(link); saveErr != nil {
+					failed(link, saveErr)
 				}
 				bot.Log.WithFields(log.Fields{"URL": url, "server": server, "channel": channel}).Debug("SaveURLs(): New Link")
 
-			case err != nil:
-				bot.LogError("SaveURLs()", err)
-
 			default:
 				bot.Log.WithFields(log.Fields{"url": url}).Debug("SaveURLs(): Existing Link")
+				bot.repost(conn, line, original)
 			}
 		}
 	}
 }
 
+// repost bumps the repost count of `original` and optionally calls out the reposter.
+func (bot *Scumbag) repost(conn *irc.Conn, line *irc.Line, original *Link) {
+	if err := bot.Links.AddRepost(original.ID); err != nil {
+		bot.LogError("repost()", err)
+	}
+
+	channel := line.Args[0]
+	if original.Nick == line.Nick || !repostNoticeChannel(bot, conn.Config().Server, channel) {
+		return
+	}
+
+	bot.Msg(conn, channel, "old! first posted by %s %s", original.Nick, humanize.Time(original.CreatedAt))
+}
+
 // SearchLinks searches the links database for query.
-func (cmd *LinkCommand) SearchLinks(query string) ([]*Link, error) {
+func (cmd *LinkCommand) SearchLinks(query *LinkQuery) ([]*Link, error) {
 	var results []*Link
 
 	channel, err := cmd.Channel(cmd.line)
@@ -139,63 +235,106 @@ func (cmd *LinkCommand) SearchLinks(query string) ([]*Link, error) {
 		return results, err
 	}
 
-	// Regex search:  <cmdPrefix>url /imgur/
-	if strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
-		urlQuery := strings.Replace(query, "/", "", 2)
+	cmd.bot.Log.WithField("query", query).Debug("LinkCommand.SearchLinks()")
 
-		rows, err := cmd.bot.DB.Query(`SELECT nick, url, server, channel FROM links WHERE url ILIKE '%' || $1 || '%' AND server=$2 AND channel=$3 ORDER BY created_at DESC LIMIT $4;`, urlQuery, cmd.conn.Config().Server, channel, searchLimit)
-		if err != nil {
-			cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-			return nil, err
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			link := Link{}
-			err := rows.Scan(&link.Nick, &link.URL, &link.Server, &link.Channel)
-			if err != nil {
-				cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-				return nil, err
-			}
+	results, err = cmd.bot.Links.SearchLinks(query, cmd.conn.Config().Server, channel)
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.SearchLinks()", err)
+		return nil, err
+	}
 
-			results = append(results, &link)
-		}
+	return results, nil
+}
 
-		err = rows.Err()
-		if err != nil {
-			cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-			return nil, err
-		}
-	} else {
-		// Nick search:  <cmdPrefix>url oshuma
-		cmd.bot.Log.WithField("nick", query).Debug("LinkCommand.SearchLinks(): Nick Search")
+func (cmd *LinkCommand) topLinks(channel string) {
+	links, err := cmd.bot.Links.TopLinks(cmd.conn.Config().Server, channel, searchLimit)
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.topLinks()", err)
+		return
+	}
 
-		rows, err := cmd.bot.DB.Query(`SELECT nick, url, server, channel FROM links WHERE nick=$1 AND server=$2 AND channel=$3 ORDER BY created_at DESC LIMIT $4;`, query, cmd.conn.Config().Server, channel, searchLimit)
-		if err != nil {
-			cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-			return nil, err
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			link := Link{}
-			err := rows.Scan(&link.Nick, &link.URL, &link.Server, &link.Channel)
-			if err != nil {
-				cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-				return nil, err
-			}
+	var response []string
+	for _, link := range links {
+		response = append(response, fmt.Sprintf("%s (%dx)", link.URL, link.Reposts+1))
+	}
 
-			results = append(results, &link)
-		}
+	if len(response) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "No reposts yet.")
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, strings.Join(response, urlSep))
+}
 
-		err = rows.Err()
+// findURLs returns the URLs in `msg`, minus any trailing punctuation.
+func findURLs(msg string) []string {
+	urls := urlRegexp.FindAllString(msg, -1)
+	for i, url := range urls {
+		urls[i] = TrimURL(url)
+	}
+	return urls
+}
+
+func (cmd *LinkCommand) archived(channel, rawID string) {
+	id, err := parseLinkID(rawID)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return
+	}
+
+	if cmd.bot.Config.LinkCheck == nil {
+		cmd.bot.Msg(cmd.conn, channel, "Archiving is disabled.")
+		return
+	}
+
+	link, err := cmd.bot.Links.GetLink(cmd.conn.Config().Server, channel, id)
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.archived()", err)
+		return
+	}
+
+	if link == nil || !link.Archived {
+		cmd.bot.Msg(cmd.conn, channel, "No archive for %s.", formatLinkID(id))
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, archiveURL(cmd.bot, id))
+}
+
+// forgetMe deletes all of the user's links on this server, after asking them to confirm.
+func (cmd *LinkCommand) forgetMe(channel string, confirmed bool) {
+	server := cmd.conn.Config().Server
+	nick := cmd.line.Nick
+	key := server + " " + nick
+
+	if !confirmed {
+		count, err := cmd.bot.Links.CountLinks(&LinkFilter{Server: server, Nick: nick})
 		if err != nil {
-			cmd.bot.LogError("LinkCommand.SearchLinks()", err)
-			return nil, err
+			cmd.bot.LogError("LinkCommand.forgetMe()", err)
+			return
 		}
+
+		cmd.bot.forgetMe.request(key, forgetMeTimeout)
+		cmd.bot.Msg(cmd.conn, channel, "%s: this deletes all %d of your links on this server. Say \"%s -forgetme confirm\" within %s to go ahead.", nick, count, cmdURL, forgetMeTimeout)
+		return
 	}
 
-	return results, nil
+	if !cmd.bot.forgetMe.confirm(key) {
+		cmd.bot.Msg(cmd.conn, channel, "%s: nothing to confirm; run %s -forgetme first.", nick, cmdURL)
+		return
+	}
+
+	deleted, err := cmd.bot.PurgeLinks(&LinkPurge{
+		LinkFilter:  LinkFilter{Server: server, Nick: nick},
+		RequestedBy: nick,
+		Reason:      purgeReasonForgetMe,
+	})
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.forgetMe()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: deleted %d links.", nick, deleted)
 }
 
 func ignoredChannel(bot *Scumbag, server, channel string) bool {
@@ -210,10 +349,20 @@ func ignoredChannel(bot *Scumbag, server, channel string) bool {
 	return !channelConfig.SaveURLs
 }
 
+func repostNoticeChannel(bot *Scumbag, server, channel string) bool {
+	serverConfig, err := bot.Config.Server(server)
+	if err != nil {
+		bot.LogError("repostNoticeChannel()", err)
+		return false
+	}
+
+	channelConfig, ok := serverConfig.Channels[channel]
+	return ok && channelConfig.RepostNotice
+}
+
 func ignoredNick(bot *Scumbag, server, nick string) bool {
-	var result bool
-	err := bot.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM ignored_nicks WHERE server=$1 AND nick=$2);", server, nick).Scan(&result)
-	if err != nil && err != sql.ErrNoRows {
+	result, err := bot.Ignores.IgnoredNick(server, nick)
+	if err != nil {
 		bot.LogError("ignoredNick()", err)
 		return false
 	}
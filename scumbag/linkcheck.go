@@ -0,0 +1,279 @@
+package scumbag
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	linkCheckDefaultInterval  = 24 * time.Hour
+	linkCheckDefaultBatchSize = 100
+	linkCheckTimeout          = 15 * time.Second
+
+	// Largest page saved as an archive snapshot.
+	linkArchiveMaxBytes = 5 * 1024 * 1024
+
+	linkArchivePath = "/archive/"
+)
+
+// Like page titles, links aren't checked or archived on private addresses.
+var linkCheckClient = &http.Client{Timeout: linkCheckTimeout, Transport: publicTransport}
+
+// startLinkChecker starts the dead link checker and archive server, if configured.
+func (bot *Scumbag) startLinkChecker() {
+	config := bot.Config.LinkCheck
+	if config == nil {
+		return
+	}
+
+	interval := linkCheckDefaultInterval
+	if config.Interval != "" {
+		parsed, err := time.ParseDuration(config.Interval)
+		if err != nil {
+			bot.LogError("startLinkChecker()", err)
+			return
+		}
+		interval = parsed
+	}
+
+	if config.ArchiveListen != "" {
+		bot.startArchiveServer(config.ArchiveListen)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			bot.checkLinks(interval)
+
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+		}
+	}()
+}
+
+// checkLinks re-checks links not checked in the last `interval`, a batch at a
+// time, until none are due.
+func (bot *Scumbag) checkLinks(interval time.Duration) {
+	batchSize := bot.Config.LinkCheck.BatchSize
+	if batchSize <= 0 {
+		batchSize = linkCheckDefaultBatchSize
+	}
+
+	// Checked links are stamped with a later time, so each batch is new ones.
+	checkedBefore := time.Now().Add(-interval)
+
+	for {
+		links, err := bot.Links.LinksToCheck(checkedBefore, batchSize)
+		if err != nil {
+			bot.LogError("checkLinks()", err)
+			return
+		}
+
+		for _, link := range links {
+			select {
+			case <-bot.quit:
+				return
+			default:
+			}
+
+			// Stop rather than fetch the same unsaved batch forever.
+			if err := bot.checkLink(link); err != nil {
+				bot.LogError("checkLinks()", err)
+				return
+			}
+		}
+
+		if len(links) < batchSize {
+			return
+		}
+	}
+}
+
+func (bot *Scumbag) checkLink(link *Link) error {
+	statusCode, checked := linkStatus(link.URL)
+	dead := checked && (statusCode == 0 || statusCode >= http.StatusBadRequest)
+	now := time.Now()
+
+	bot.Log.WithFields(log.Fields{"url": link.URL, "status": statusCode}).Debug("checkLink()")
+
+	if err := bot.Links.SetLinkStatus(link.ID, statusCode, dead, now); err != nil {
+		return err
+	}
+
+	if checked && !dead && !link.Archived && archiveChannel(bot, link.Server, link.Channel) {
+		if err := bot.archiveLink(link); err != nil {
+			bot.LogError("checkLink()", err)
+		}
+	}
+	return nil
+}
+
+// linkStatus returns the HTTP status of `url`, or 0 if it could not be fetched.
+// Servers which don't support HEAD are retried with GET. Links which aren't
+// http(s) or resolve to a private address aren't checked, and return false.
+func linkStatus(url string) (int, bool) {
+	if !IsHTTPURL(url) {
+		return 0, false
+	}
+
+	statusCode, err := linkRequest("HEAD", url)
+	if errors.Is(err, errPrivateAddress) {
+		return 0, false
+	}
+	if err == nil && statusCode < http.StatusBadRequest {
+		return statusCode, true
+	}
+
+	getStatusCode, getErr := linkRequest("GET", url)
+	if getErr != nil {
+		return statusCode, true
+	}
+
+	return getStatusCode, true
+}
+
+func linkRequest(method, url string) (int, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", VersionString())
+
+	resp, err := linkCheckClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// archiveLink saves a gzipped snapshot of an HTML link to the archive directory.
+// Like checking, it refuses links which resolve to private addresses.
+func (bot *Scumbag) archiveLink(link *Link) error {
+	if !IsHTTPURL(link.URL) {
+		return nil
+	}
+
+	req, err := http.NewRequest("GET", link.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", VersionString())
+
+	resp, err := linkCheckClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return nil
+	}
+
+	if err := os.MkdirAll(bot.Config.LinkCheck.ArchiveDir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(archiveFile(bot, link.ID))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if _, err := io.Copy(writer, io.LimitReader(resp.Body, linkArchiveMaxBytes)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return bot.Links.SetLinkArchived(link.ID, time.Now())
+}
+
+// startArchiveServer serves archive snapshots at /archive/<id>.
+func (bot *Scumbag) startArchiveServer(listen string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(linkArchivePath, bot.archiveHandler)
+
+	bot.archiveServer = &http.Server{Addr: listen, Handler: mux}
+
+	go func() {
+		bot.Log.WithField("listen", listen).Info("Starting archive server.")
+		if err := bot.archiveServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			bot.LogError("startArchiveServer()", err)
+		}
+	}()
+}
+
+func (bot *Scumbag) archiveHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, linkArchivePath), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(archiveFile(bot, id))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		bot.LogError("archiveHandler()", err)
+		http.Error(w, "Bad archive", http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	// Snapshots are arbitrary pages; don't let them run scripts on this origin.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Type", "text/html")
+	io.Copy(w, reader)
+}
+
+// archiveURL returns the public URL of a link's snapshot.
+func archiveURL(bot *Scumbag, id int64) string {
+	baseURL := bot.Config.LinkCheck.ArchiveURL
+	if baseURL == "" {
+		baseURL = "http://" + bot.Config.LinkCheck.ArchiveListen
+	}
+
+	return fmt.Sprintf("%s%s%d", strings.TrimRight(baseURL, "/"), linkArchivePath, id)
+}
+
+func archiveFile(bot *Scumbag, id int64) string {
+	return filepath.Join(bot.Config.LinkCheck.ArchiveDir, fmt.Sprintf("%d.html.gz", id))
+}
+
+func archiveChannel(bot *Scumbag, server, channel string) bool {
+	if bot.Config.LinkCheck.ArchiveDir == "" {
+		return false
+	}
+
+	serverConfig, err := bot.Config.Server(server)
+	if err != nil {
+		return false
+	}
+
+	channelConfig, ok := serverConfig.Channels[channel]
+	return ok && channelConfig.ArchiveURLs
+}
@@ -0,0 +1,88 @@
+package scumbag
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckLinks(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Links = NewMemoryStore()
+	bot.Config.LinkCheck = &LinkCheckConfig{BatchSize: 2}
+
+	now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		link := &Link{Nick: "alice", URL: fmt.Sprintf("ftp://example.com/%d", i), Server: "irc.example.com", Channel: "#scumbag", CreatedAt: now}
+		if err := bot.Links.SaveLink(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bot.checkLinks(time.Hour)
+
+	links, err := bot.Links.LinksToCheck(time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 0 {
+		t.Errorf("Expected every batch to be checked, %d links left", len(links))
+	}
+
+	// ftp:// links aren't checked, so aren't dead either.
+	links, err = bot.Links.Links(&LinkFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		if link.Dead {
+			t.Errorf("%s: expected not to be dead", link.URL)
+		}
+	}
+}
+
+func TestLinkStatusSchemes(t *testing.T) {
+	for _, url := range []string{
+		"ftp://example.com/file",
+		"mailto:alice@example.com",
+		"javascript:alert(1)",
+		"file:///etc/passwd",
+	} {
+		if _, checked := linkStatus(url); checked {
+			t.Errorf("%s: expected not to be checked", url)
+		}
+	}
+}
+
+func TestLinkCheckPrivateAddress(t *testing.T) {
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer server.Close()
+
+	if _, checked := linkStatus(server.URL); checked {
+		t.Error("Expected a loopback link not to be checked")
+	}
+
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Config.LinkCheck = &LinkCheckConfig{ArchiveDir: t.TempDir()}
+
+	if err := bot.archiveLink(&Link{ID: 1, URL: server.URL}); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("Expected errPrivateAddress archiving a loopback link, got %v", err)
+	}
+
+	if fetched {
+		t.Error("Expected the loopback server never to be fetched")
+	}
+}
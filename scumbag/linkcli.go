@@ -0,0 +1,236 @@
+package scumbag
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	cliLinksCanonicalize = "canonicalize"
+	cliLinksExport       = "export"
+	cliLinksImport       = "import"
+)
+
+func (bot *Scumbag) linksCLI(out io.Writer, args []string) error {
+	if len(args) <= 0 {
+		return fmt.Errorf("Usage: links <%s|%s|%s>", cliLinksCanonicalize, cliLinksExport, cliLinksImport)
+	}
+
+	switch args[0] {
+	case cliLinksCanonicalize:
+		return bot.canonicalizeLinks(out)
+	case cliLinksExport:
+		return bot.exportLinks(out, args[1:])
+	case cliLinksImport:
+		return bot.importLinks(out, args[1:])
+	default:
+		return fmt.Errorf("Unknown links command: %s", args[0])
+	}
+}
+
+// exportLinks writes saved links to stdout or -out, e.g.
+//
+//	links export -format html -server irc.example.com:6667 -channel '#scumbag' -since 1y -out links.html
+func (bot *Scumbag) exportLinks(out io.Writer, args []string) error {
+	flags := flag.NewFlagSet(cliLinksExport, flag.ContinueOnError)
+	format := flags.String("format", linkFormatCSV, "Export format (csv, jsonl, html)")
+	server := flags.String("server", "", "Only links from this server")
+	channel := flags.String("channel", "", "Only links from this channel")
+	nick := flags.String("nick", "", "Only links from this nick")
+	since := flags.String("since", "", "Only links since this date (2020-01-31) or duration (2w)")
+	until := flags.String("until", "", "Only links before this date or duration")
+	outFile := flags.String("out", "", "Output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := &LinkFilter{Server: *server, Channel: *channel, Nick: *nick}
+	for _, dateFilter := range []struct {
+		value string
+		date  *time.Time
+	}{{*since, &filter.Since}, {*until, &filter.Until}} {
+		if dateFilter.value == "" {
+			continue
+		}
+
+		date, err := parseLinkQueryTime(dateFilter.value, time.Now())
+		if err != nil {
+			return err
+		}
+		*dateFilter.date = date
+	}
+
+	if *outFile != "" {
+		file, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer, err := newLinkWriter(out, *format)
+	if err != nil {
+		return err
+	}
+
+	links, err := bot.Links.Links(filter)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		if err := writer.Write(link); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// importLinks saves links from exported files or IRC client logs, all in one
+// transaction, e.g.
+//
+//	links import -format irssi -server irc.example.com:6667 -channel '#scumbag' -dry-run ~/irclogs/*.log
+func (bot *Scumbag) importLinks(out io.Writer, args []string) error {
+	flags := flag.NewFlagSet(cliLinksImport, flag.ContinueOnError)
+	format := flags.String("format", linkFormatCSV, "Import format (csv, jsonl, html, irssi, weechat, znc)")
+	server := flags.String("server", "", "Server for links without one (required for logs)")
+	channel := flags.String("channel", "", "Channel for links without one (required for logs)")
+	nick := flags.String("nick", "", "Nick for links without one (e.g. browser bookmarks)")
+	date := flags.String("date", "", "Date of log lines before the first irssi day header, or of a ZNC log (2020-01-31)")
+	dryRun := flags.Bool("dry-run", false, "Show what would be imported without saving")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() <= 0 {
+		return fmt.Errorf("Usage: links import [flags] <file>...")
+	}
+
+	var day time.Time
+	if *date != "" {
+		parsed, err := time.ParseInLocation(linkQueryDateFormat, *date, time.Local)
+		if err != nil {
+			return fmt.Errorf("Invalid date: %s", *date)
+		}
+		day = parsed
+	}
+
+	var imported []*Link
+	skipped := 0
+	// Canonical URLs seen in this import, per server and channel.
+	seen := make(map[string]bool)
+
+	for _, filename := range flags.Args() {
+		defaults := &Link{Nick: *nick, Server: *server, Channel: *channel, CreatedAt: day}
+
+		if *format == linkFormatZNC && day.IsZero() {
+			day, err := zncLogDay(filepath.Base(filename))
+			if err != nil {
+				return err
+			}
+			defaults.CreatedAt = day
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+
+		links, err := readLinks(file, *format, defaults)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+
+		for _, link := range links {
+			if link.Server == "" || link.Channel == "" {
+				return fmt.Errorf("%s: %s has no server or channel; use -server and -channel", filename, link.URL)
+			}
+
+			canonicalURL := CanonicalURL(link.URL)
+			key := strings.Join([]string{link.Server, link.Channel, canonicalURL}, " ")
+
+			exists := seen[key]
+			if !exists {
+				original, err := bot.Links.FindLink(link.Server, link.Channel, link.URL, canonicalURL)
+				if err != nil {
+					return err
+				}
+				exists = original != nil
+			}
+			seen[key] = true
+
+			if exists || ignoredNick(bot, link.Server, link.Nick) {
+				skipped++
+				continue
+			}
+
+			fmt.Fprintf(out, "[%s] %s <%s> %s\n", link.CreatedAt.Format(linkRecordTimeFormat), link.Channel, link.Nick, link.URL)
+
+			link.CanonicalURL = canonicalURL
+			imported = append(imported, link)
+		}
+	}
+
+	if *dryRun {
+		fmt.Fprintf(out, "Would import: %d, Skipped: %d\n", len(imported), skipped)
+		return nil
+	}
+
+	if err := bot.Links.SaveLinks(imported); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Imported: %d, Skipped: %d\n", len(imported), skipped)
+	return nil
+}
+
+// canonicalizeLinks backfills links.canonical_url and merges links which
+// canonicalize to the same URL in the same channel into the oldest one. It
+// all happens in one transaction, so a failed run changes nothing and can
+// simply be run again.
+func (bot *Scumbag) canonicalizeLinks(out io.Writer) error {
+	links, err := bot.Links.Links(&LinkFilter{})
+	if err != nil {
+		return err
+	}
+
+	// Oldest link for each server/channel/canonical_url.
+	originals := make(map[string]*Link)
+	canonicalURLs := make(map[int64]string)
+	var merges []*LinkMerge
+
+	for _, link := range links {
+		canonicalURL := CanonicalURL(link.URL)
+		key := fmt.Sprintf("%s %s %s", link.Server, link.Channel, canonicalURL)
+
+		original, exists := originals[key]
+		if !exists {
+			originals[key] = link
+
+			if link.CanonicalURL != canonicalURL {
+				canonicalURLs[link.ID] = canonicalURL
+			}
+			continue
+		}
+
+		// The duplicate itself counts as one repost of the original.
+		original.Reposts += link.Reposts + 1
+		merges = append(merges, &LinkMerge{DuplicateID: link.ID, OriginalID: original.ID, Reposts: original.Reposts})
+		fmt.Fprintf(out, "Merging %s into %s\n", link.URL, original.URL)
+	}
+
+	if err := bot.Links.CanonicalizeLinks(canonicalURLs, merges); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Canonicalized: %d, Merged: %d\n", len(canonicalURLs), len(merges))
+	return nil
+}
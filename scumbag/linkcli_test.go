@@ -0,0 +1,54 @@
+package scumbag
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportLinks(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Links = NewMemoryStore()
+
+	dir, err := ioutil.TempDir("", "scumbag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A log started with /log open, so no "--- Log opened" line.
+	filename := filepath.Join(dir, "scumbag.log")
+	log := "12:01 <@alice> look https://example.com/a\n12:02 < bob> https://example.com/a again\n"
+	if err := ioutil.WriteFile(filename, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"import", "-format", "irssi", "-server", "irc.example.com", "-channel", "#scumbag", filename}
+	var out bytes.Buffer
+	if err := bot.linksCLI(&out, args); err == nil || !strings.Contains(err.Error(), "-date") {
+		t.Errorf("Expected an error asking for -date, got %v", err)
+	}
+
+	args = append([]string{"import", "-date", "2020-03-15"}, args[1:]...)
+	if err := bot.linksCLI(&out, args); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "Imported: 1, Skipped: 1\n") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+
+	links, err := bot.Links.Links(&LinkFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, 3, 15, 12, 1, 0, 0, time.Local); len(links) != 1 || !links[0].CreatedAt.Equal(expected) {
+		t.Errorf("Expected one link at %s, got %+v", expected, links)
+	}
+}
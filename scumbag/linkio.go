@@ -0,0 +1,367 @@
+package scumbag
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Link export and import formats.
+const (
+	linkFormatCSV     = "csv"
+	linkFormatJSONL   = "jsonl"
+	linkFormatHTML    = "html"
+	linkFormatIrssi   = "irssi"
+	linkFormatWeechat = "weechat"
+	linkFormatZNC     = "znc"
+
+	linkRecordTimeFormat = time.RFC3339
+)
+
+var (
+	linkCSVHeader = []string{"nick", "url", "server", "channel", "created_at"}
+
+	bookmarkRegexp     = regexp.MustCompile(`(?i)<A\s+([^>]*)>`)
+	bookmarkAttrRegexp = regexp.MustCompile(`(?i)([A-Z_]+)="([^"]*)"`)
+
+	// --- Log opened Sun Mar 15 12:00:00 2020
+	// --- Day changed Sun Mar 15 2020
+	irssiDayRegexp  = regexp.MustCompile(`\A--- (?:Log opened|Day changed) \w{3} (\w{3} \d{1,2}) (?:[\d:]+ )?(\d{4})`)
+	irssiLineRegexp = regexp.MustCompile(`\A(\d{2}):(\d{2})(?::(\d{2}))? <[ @%+&~]?([^>]+)> (.*)\z`)
+
+	// 2020-03-15 12:00:00	@nick	message
+	weechatLineRegexp = regexp.MustCompile(`\A(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\t[@%+&~]?([^\t]+)\t(.*)\z`)
+
+	// [12:00:00] <nick> message
+	zncLineRegexp     = regexp.MustCompile(`\A\[(\d{2}):(\d{2}):(\d{2})\] <([^>]+)> (.*)\z`)
+	zncFilenameRegexp = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})`)
+)
+
+// linkRecord is a Link as written to CSV and JSON Lines.
+type linkRecord struct {
+	Nick      string `json:"nick"`
+	URL       string `json:"url"`
+	Server    string `json:"server"`
+	Channel   string `json:"channel"`
+	CreatedAt string `json:"created_at"`
+}
+
+// linkWriter streams links in one of the export formats.
+type linkWriter interface {
+	Write(link *Link) error
+	Close() error
+}
+
+func newLinkWriter(w io.Writer, format string) (linkWriter, error) {
+	switch format {
+	case linkFormatCSV:
+		writer := csv.NewWriter(w)
+		return &csvLinkWriter{writer: writer}, writer.Write(linkCSVHeader)
+	case linkFormatJSONL:
+		return &jsonLinkWriter{encoder: json.NewEncoder(w)}, nil
+	case linkFormatHTML:
+		writer := &htmlLinkWriter{writer: w}
+		return writer, writer.header()
+	default:
+		return nil, fmt.Errorf("Unknown export format: %s", format)
+	}
+}
+
+func newLinkRecord(link *Link) *linkRecord {
+	return &linkRecord{
+		Nick:      link.Nick,
+		URL:       link.URL,
+		Server:    link.Server,
+		Channel:   link.Channel,
+		CreatedAt: link.CreatedAt.Format(linkRecordTimeFormat),
+	}
+}
+
+func (record *linkRecord) link() (*Link, error) {
+	createdAt, err := time.Parse(linkRecordTimeFormat, record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Link{
+		Nick:      record.Nick,
+		URL:       record.URL,
+		Server:    record.Server,
+		Channel:   record.Channel,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+type csvLinkWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvLinkWriter) Write(link *Link) error {
+	record := newLinkRecord(link)
+	return w.writer.Write([]string{record.Nick, record.URL, record.Server, record.Channel, record.CreatedAt})
+}
+
+func (w *csvLinkWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonLinkWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonLinkWriter) Write(link *Link) error {
+	return w.encoder.Encode(newLinkRecord(link))
+}
+
+func (w *jsonLinkWriter) Close() error {
+	return nil
+}
+
+// htmlLinkWriter writes a Netscape bookmarks file, which browsers can import.
+// NICK, SERVER and CHANNEL are extra attributes browsers ignore, so a round trip keeps them.
+type htmlLinkWriter struct {
+	writer io.Writer
+}
+
+func (w *htmlLinkWriter) header() error {
+	_, err := io.WriteString(w.writer, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+	return err
+}
+
+func (w *htmlLinkWriter) Write(link *Link) error {
+	escapedURL := html.EscapeString(link.URL)
+	_, err := fmt.Fprintf(w.writer, "    <DT><A HREF=\"%s\" ADD_DATE=\"%d\" TAGS=\"%s\" NICK=\"%s\" SERVER=\"%s\" CHANNEL=\"%s\">%s</A>\n",
+		escapedURL,
+		link.CreatedAt.Unix(),
+		html.EscapeString(strings.TrimLeft(link.Channel, "#&")),
+		html.EscapeString(link.Nick),
+		html.EscapeString(link.Server),
+		html.EscapeString(link.Channel),
+		escapedURL,
+	)
+	return err
+}
+
+func (w *htmlLinkWriter) Close() error {
+	_, err := io.WriteString(w.writer, "</DL><p>\n")
+	return err
+}
+
+// readLinks reads links from `r`. Fields missing from the input (e.g. the
+// server and channel of a log file) are taken from `defaults`, log lines
+// without a date use the date of defaults.CreatedAt and anything else
+// without a date is dated now.
+func readLinks(r io.Reader, format string, defaults *Link) ([]*Link, error) {
+	var links []*Link
+	var err error
+
+	switch format {
+	case linkFormatCSV:
+		links, err = readCSVLinks(r)
+	case linkFormatJSONL:
+		links, err = readJSONLinks(r)
+	case linkFormatHTML:
+		links, err = readHTMLLinks(r)
+	case linkFormatIrssi, linkFormatWeechat, linkFormatZNC:
+		links, err = readLogLinks(r, format, defaults.CreatedAt)
+	default:
+		return nil, fmt.Errorf("Unknown import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link.Server == "" {
+			link.Server = defaults.Server
+		}
+		if link.Channel == "" {
+			link.Channel = defaults.Channel
+		}
+		if link.Nick == "" {
+			link.Nick = defaults.Nick
+		}
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = time.Now()
+		}
+	}
+
+	return links, nil
+}
+
+func readCSVLinks(r io.Reader) ([]*Link, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*Link
+	for i, row := range rows {
+		if i == 0 && row[0] == linkCSVHeader[0] {
+			continue
+		}
+
+		if len(row) != len(linkCSVHeader) {
+			return nil, fmt.Errorf("CSV line %d: expected %d fields, got %d", i+1, len(linkCSVHeader), len(row))
+		}
+
+		record := &linkRecord{Nick: row[0], URL: row[1], Server: row[2], Channel: row[3], CreatedAt: row[4]}
+		link, err := record.link()
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %s", i+1, err)
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+func readJSONLinks(r io.Reader) ([]*Link, error) {
+	var links []*Link
+
+	decoder := json.NewDecoder(r)
+	for {
+		var record linkRecord
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		link, err := record.link()
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+func readHTMLLinks(r io.Reader) ([]*Link, error) {
+	var links []*Link
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		for _, anchor := range bookmarkRegexp.FindAllStringSubmatch(scanner.Text(), -1) {
+			attrs := make(map[string]string)
+			for _, attr := range bookmarkAttrRegexp.FindAllStringSubmatch(anchor[1], -1) {
+				attrs[strings.ToUpper(attr[1])] = html.UnescapeString(attr[2])
+			}
+
+			if attrs["HREF"] == "" {
+				continue
+			}
+
+			link := &Link{
+				Nick:    attrs["NICK"],
+				URL:     attrs["HREF"],
+				Server:  attrs["SERVER"],
+				Channel: attrs["CHANNEL"],
+			}
+
+			if addDate, err := strconv.ParseInt(attrs["ADD_DATE"], 10, 64); err == nil {
+				link.CreatedAt = time.Unix(addDate, 0)
+			}
+
+			links = append(links, link)
+		}
+	}
+
+	return links, scanner.Err()
+}
+
+// readLogLinks extracts links from an IRC client log. irssi logs carry their own
+// dates, starting from `day` if they don't open with one; ZNC logs are one file
+// per day, so `day` comes from the file name.
+func readLogLinks(r io.Reader, format string, day time.Time) ([]*Link, error) {
+	var links []*Link
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		text := scanner.Text()
+
+		var nick, msg string
+		var createdAt time.Time
+
+		switch format {
+		case linkFormatIrssi:
+			if match := irssiDayRegexp.FindStringSubmatch(text); match != nil {
+				if parsed, err := time.ParseInLocation("Jan 2 2006", match[1]+" "+match[2], time.Local); err == nil {
+					day = parsed
+				}
+				continue
+			}
+
+			match := irssiLineRegexp.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			if day.IsZero() {
+				return nil, fmt.Errorf("Line %d: no date; the log needs a \"--- Log opened\" line first, or use -date", lineNum)
+			}
+			createdAt = logTime(day, match[1], match[2], match[3])
+			nick, msg = match[4], match[5]
+
+		case linkFormatWeechat:
+			match := weechatLineRegexp.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+
+			parsed, err := time.ParseInLocation("2006-01-02 15:04:05", match[1], time.Local)
+			if err != nil {
+				continue
+			}
+			createdAt = parsed
+			nick, msg = match[2], match[3]
+
+		case linkFormatZNC:
+			match := zncLineRegexp.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			createdAt = logTime(day, match[1], match[2], match[3])
+			nick, msg = match[4], match[5]
+		}
+
+		for _, url := range findURLs(msg) {
+			links = append(links, &Link{Nick: nick, URL: url, CreatedAt: createdAt})
+		}
+	}
+
+	return links, scanner.Err()
+}
+
+// zncLogDay returns the date in a ZNC log file name, e.g. "#channel_20200315.log".
+func zncLogDay(filename string) (time.Time, error) {
+	match := zncFilenameRegexp.FindStringSubmatch(filename)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("No date in file name: %s", filename)
+	}
+
+	return time.ParseInLocation("20060102", match[1]+match[2]+match[3], time.Local)
+}
+
+func logTime(day time.Time, hour, minute, second string) time.Time {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	s, _ := strconv.Atoi(second)
+
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, s, 0, time.Local)
+}
@@ -0,0 +1,123 @@
+package scumbag
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkExportRoundTrip(t *testing.T) {
+	createdAt := time.Date(2020, 3, 15, 12, 30, 0, 0, time.Local)
+	links := []*Link{
+		{Nick: "alice", URL: "https://example.com/a?b=1&c=2", Server: "irc.example.com:6667", Channel: "#scumbag", CreatedAt: createdAt},
+		{Nick: "bob", URL: "https://example.com/\"quoted\"", Server: "irc.example.com:6667", Channel: "#scumbag", CreatedAt: createdAt.Add(time.Hour)},
+	}
+
+	for _, format := range []string{linkFormatCSV, linkFormatJSONL, linkFormatHTML} {
+		var buf bytes.Buffer
+
+		writer, err := newLinkWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		for _, link := range links {
+			if err := writer.Write(link); err != nil {
+				t.Fatalf("%s: %s", format, err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		imported, err := readLinks(&buf, format, &Link{})
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if len(imported) != len(links) {
+			t.Fatalf("%s: expected %d links, got %d", format, len(links), len(imported))
+		}
+
+		for i := range links {
+			if !imported[i].CreatedAt.Equal(links[i].CreatedAt) {
+				t.Errorf("%s: expected %s, got %s", format, links[i].CreatedAt, imported[i].CreatedAt)
+			}
+			imported[i].CreatedAt = links[i].CreatedAt
+
+			if !reflect.DeepEqual(imported[i], links[i]) {
+				t.Errorf("%s: expected %+v, got %+v", format, links[i], imported[i])
+			}
+		}
+	}
+}
+
+func TestReadLogLinks(t *testing.T) {
+	defaults := &Link{Server: "irc.example.com:6667", Channel: "#scumbag"}
+
+	logs := map[string]string{
+		linkFormatIrssi: strings.Join([]string{
+			"--- Log opened Sun Mar 15 12:00:00 2020",
+			"12:01 <@alice> look (https://example.com/a).",
+			"12:02 -!- bob [bob@example.com] has joined #scumbag",
+			"--- Day changed Mon Mar 16 2020",
+			"09:30 < bob> https://example.com/b and http://example.com/c",
+		}, "\n"),
+		linkFormatWeechat: strings.Join([]string{
+			"2020-03-15 12:01:00\t@alice\tlook (https://example.com/a).",
+			"2020-03-15 12:02:00\t-->\tbob (bob@example.com) has joined #scumbag",
+			"2020-03-16 09:30:00\tbob\thttps://example.com/b and http://example.com/c",
+		}, "\n"),
+	}
+
+	expected := []*Link{
+		{Nick: "alice", URL: "https://example.com/a", CreatedAt: time.Date(2020, 3, 15, 12, 1, 0, 0, time.Local)},
+		{Nick: "bob", URL: "https://example.com/b", CreatedAt: time.Date(2020, 3, 16, 9, 30, 0, 0, time.Local)},
+		{Nick: "bob", URL: "http://example.com/c", CreatedAt: time.Date(2020, 3, 16, 9, 30, 0, 0, time.Local)},
+	}
+	for _, link := range expected {
+		link.Server, link.Channel = defaults.Server, defaults.Channel
+	}
+
+	for format, log := range logs {
+		links, err := readLinks(strings.NewReader(log), format, defaults)
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(links, expected) {
+			t.Errorf("%s: expected %+v, got %+v", format, expected, links)
+		}
+	}
+
+	// irssi lines before any day header need a date from -date.
+	headless := "12:01 <@alice> look (https://example.com/a)."
+	if _, err := readLinks(strings.NewReader(headless), linkFormatIrssi, defaults); err == nil {
+		t.Error("irssi: expected an error for a log without a day header")
+	}
+
+	day, err := zncLogDay("#scumbag_20200315.log")
+	if err != nil {
+		t.Fatalf("zncLogDay(): %s", err)
+	}
+
+	defaults.CreatedAt = day
+	links, err := readLinks(strings.NewReader("[12:01:00] <alice> look (https://example.com/a).\n[12:02:00] *** Joins: bob"), linkFormatZNC, defaults)
+	if err != nil {
+		t.Fatalf("znc: %s", err)
+	}
+
+	if len(links) != 1 || !reflect.DeepEqual(links[0], expected[0]) {
+		t.Errorf("znc: expected %+v, got %+v", expected[0], links)
+	}
+
+	links, err = readLinks(strings.NewReader(headless), linkFormatIrssi, defaults)
+	if err != nil {
+		t.Fatalf("irssi: %s", err)
+	}
+
+	if len(links) != 1 || !reflect.DeepEqual(links[0], expected[0]) {
+		t.Errorf("irssi: expected %+v, got %+v", expected[0], links)
+	}
+}
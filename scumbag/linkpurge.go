@@ -0,0 +1,124 @@
+package scumbag
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	linkRetentionInterval = time.Hour
+
+	// How long a "<cmdPrefix>url -forgetme" waits for "confirm".
+	forgetMeTimeout = 2 * time.Minute
+
+	purgeReasonAdmin     = "admin"
+	purgeReasonForgetMe  = "forgetme"
+	purgeReasonRetention = "retention"
+)
+
+// LinkPurge describes which links to delete and why.
+type LinkPurge struct {
+	LinkFilter
+
+	RequestedBy string
+	Reason      string
+}
+
+// confirmations tracks requests waiting for the user to confirm them.
+type confirmations struct {
+	sync.Mutex
+	pending map[string]time.Time
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{pending: make(map[string]time.Time)}
+}
+
+// request starts waiting for `key` to be confirmed within `timeout`.
+func (c *confirmations) request(key string, timeout time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.pending[key] = time.Now().Add(timeout)
+}
+
+// confirm returns true (once) if `key` was requested and hasn't expired.
+func (c *confirmations) confirm(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	expires, ok := c.pending[key]
+	delete(c.pending, key)
+
+	return ok && time.Now().Before(expires)
+}
+
+// PurgeLinks deletes the links matching `purge`, logs it to link_purges if
+// any were deleted and returns the number of links deleted.
+func (bot *Scumbag) PurgeLinks(purge *LinkPurge) (int, error) {
+	if purge.Nick == "" && purge.Until.IsZero() {
+		return 0, errors.New("refusing to purge every link; need a nick or date")
+	}
+
+	ids, err := bot.Links.PurgeLinks(purge)
+	if err != nil {
+		return 0, err
+	}
+
+	bot.Log.WithFields(log.Fields{"purge": purge, "deleted": len(ids)}).Info("Purged links.")
+
+	// Snapshots of deleted links go too.
+	if bot.Config.LinkCheck != nil && bot.Config.LinkCheck.ArchiveDir != "" {
+		for _, id := range ids {
+			if err := os.Remove(archiveFile(bot, id)); err != nil && !os.IsNotExist(err) {
+				bot.LogError("PurgeLinks()", err)
+			}
+		}
+	}
+
+	return len(ids), nil
+}
+
+// startLinkRetention periodically deletes links older than each channel's RetentionDays.
+func (bot *Scumbag) startLinkRetention() {
+	go func() {
+		ticker := time.NewTicker(linkRetentionInterval)
+		defer ticker.Stop()
+
+		for {
+			bot.enforceLinkRetention()
+
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (bot *Scumbag) enforceLinkRetention() {
+	for _, serverConfig := range bot.Config.Servers {
+		for channel, channelConfig := range serverConfig.Channels {
+			if channelConfig == nil || channelConfig.RetentionDays <= 0 {
+				continue
+			}
+
+			_, err := bot.PurgeLinks(&LinkPurge{
+				LinkFilter: LinkFilter{
+					Server:  serverConfig.Server,
+					Channel: channel,
+					Until:   time.Now().AddDate(0, 0, -channelConfig.RetentionDays),
+				},
+				Reason: purgeReasonRetention,
+			})
+			if err != nil {
+				bot.LogError("enforceLinkRetention()", err)
+			}
+		}
+	}
+}
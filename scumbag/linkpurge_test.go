@@ -0,0 +1,38 @@
+package scumbag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmations(t *testing.T) {
+	c := newConfirmations()
+
+	if c.confirm("alice") {
+		t.Error("Expected an unrequested key not to confirm")
+	}
+
+	c.request("alice", time.Minute)
+	if !c.confirm("alice") {
+		t.Error("Expected a requested key to confirm")
+	}
+	if c.confirm("alice") {
+		t.Error("Expected a key to confirm only once")
+	}
+
+	c.request("bob", -time.Second)
+	if c.confirm("bob") {
+		t.Error("Expected an expired key not to confirm")
+	}
+}
+
+func TestPurgeLinksNeedsNickOrDate(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bot.PurgeLinks(&LinkPurge{LinkFilter: LinkFilter{Server: "irc.example.com", Channel: "#scumbag"}}); err == nil {
+		t.Error("Expected an error purging every link in a channel")
+	}
+}
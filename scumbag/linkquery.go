@@ -0,0 +1,209 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	searchMaxLimit = 20
+
+	linkQueryDateFormat = "2006-01-02"
+
+	// Host part of a link, for domain: filters.
+	linkHostSQL = `lower(substring(COALESCE(canonical_url, url) from '://([^/:?#]+)'))`
+
+	// Columns selected for a Link, in PostgresStore.queryLinks() scan order.
+	linkColumns = "id, nick, url, COALESCE(canonical_url, ''), COALESCE(server, ''), COALESCE(channel, ''), reposts, created_at, dead_since IS NOT NULL, archived_at IS NOT NULL"
+)
+
+var (
+	linkQueryPatternRegexp  = regexp.MustCompile(`/([^/]*)/`)
+	linkQueryDurationRegexp = regexp.MustCompile(`\A(\d+)([hdwmy])\z`)
+
+	// Patterns are plain text, so LIKE wildcards in them are escaped.
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// LinkQuery is a parsed "<cmdPrefix>url" search, e.g.
+//
+//	nick:alice domain:youtube.com tag:golang fav:bob since:2w until:2020-01-31 /talk/ limit:10 -random
+//
+// A bare word is a nick, for compatibility with "<cmdPrefix>url <nick>".
+type LinkQuery struct {
+	Nick        string
+	Domain      string
+	Pattern     string
+	Tags        []string
+	Favorite    string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+	AllChannels bool
+	Random      bool
+}
+
+// ParseLinkQuery parses `query`; relative dates are relative to `now`.
+func ParseLinkQuery(query string, now time.Time) (*LinkQuery, error) {
+	q := &LinkQuery{Limit: searchLimit}
+
+	// The pattern may contain spaces, so pull it out before splitting fields.
+	if match := linkQueryPatternRegexp.FindStringSubmatchIndex(query); match != nil {
+		q.Pattern = query[match[2]:match[3]]
+		query = query[:match[0]] + " " + query[match[1]:]
+	}
+
+	for _, field := range strings.Fields(query) {
+		switch field {
+		case "-all-channels":
+			q.AllChannels = true
+			continue
+		case "-random":
+			q.Random = true
+			continue
+		}
+
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) == 1 {
+			if strings.HasPrefix(field, "-") {
+				return nil, fmt.Errorf("Unknown flag: %s", field)
+			}
+			if q.Nick != "" {
+				return nil, fmt.Errorf("Unexpected: %s", field)
+			}
+			q.Nick = field
+			continue
+		}
+
+		key, value := parts[0], parts[1]
+		if value == "" {
+			return nil, fmt.Errorf("Missing value for %s:", key)
+		}
+
+		switch key {
+		case "nick":
+			q.Nick = value
+		case "domain":
+			q.Domain = strings.TrimPrefix(strings.ToLower(value), "www.")
+		case "tag":
+			q.Tags = append(q.Tags, normalizeTag(value))
+		case "fav":
+			q.Favorite = value
+		case "since":
+			since, err := parseLinkQueryTime(value, now)
+			if err != nil {
+				return nil, err
+			}
+			q.Since = since
+		case "until":
+			until, err := parseLinkQueryTime(value, now)
+			if err != nil {
+				return nil, err
+			}
+			q.Until = until
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("Invalid limit: %s", value)
+			}
+			if limit > searchMaxLimit {
+				limit = searchMaxLimit
+			}
+			q.Limit = limit
+		default:
+			return nil, fmt.Errorf("Unknown filter: %s", key)
+		}
+	}
+
+	return q, nil
+}
+
+// SQL compiles the query into a parameterized SELECT on the links table.
+func (q *LinkQuery) SQL(server, channel string) (string, []interface{}) {
+	where, args := q.Where(server, channel)
+
+	order := "created_at DESC"
+	if q.Random {
+		order = "random()"
+	}
+
+	args = append(args, q.Limit)
+	sql := fmt.Sprintf("SELECT %s FROM links WHERE %s ORDER BY %s LIMIT $%d;", linkColumns, where, order, len(args))
+	return sql, args
+}
+
+// Where compiles the query filters into a parameterized WHERE clause (without the "WHERE").
+func (q *LinkQuery) Where(server, channel string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions = append(conditions, "server="+arg(server))
+	if !q.AllChannels {
+		conditions = append(conditions, "channel="+arg(channel))
+	}
+
+	if q.Nick != "" {
+		conditions = append(conditions, "nick="+arg(q.Nick))
+	}
+
+	if q.Pattern != "" {
+		conditions = append(conditions, "url ILIKE '%' || "+arg(likeEscaper.Replace(q.Pattern))+` || '%' ESCAPE '\'`)
+	}
+
+	if q.Domain != "" {
+		domain := arg(q.Domain)
+		conditions = append(conditions, fmt.Sprintf("(%s = %s OR %s LIKE '%%.' || %s)", linkHostSQL, domain, linkHostSQL, domain))
+	}
+
+	for _, tag := range q.Tags {
+		conditions = append(conditions, "id IN (SELECT link_id FROM link_tags WHERE tag="+arg(tag)+")")
+	}
+
+	if q.Favorite != "" {
+		conditions = append(conditions, "id IN (SELECT link_id FROM link_favorites WHERE nick="+arg(q.Favorite)+")")
+	}
+
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(q.Since))
+	}
+
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(q.Until))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// parseLinkQueryTime parses either a date (2020-01-31) or a duration before `now` (12h, 3d, 2w, 6m, 1y).
+func parseLinkQueryTime(value string, now time.Time) (time.Time, error) {
+	if date, err := time.ParseInLocation(linkQueryDateFormat, value, now.Location()); err == nil {
+		return date, nil
+	}
+
+	match := linkQueryDurationRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("Invalid date: %s", value)
+	}
+
+	n, _ := strconv.Atoi(match[1])
+	switch match[2] {
+	case "h":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "d":
+		return now.AddDate(0, 0, -n), nil
+	case "w":
+		return now.AddDate(0, 0, -7*n), nil
+	case "m":
+		return now.AddDate(0, -n, 0), nil
+	default:
+		return now.AddDate(-n, 0, 0), nil
+	}
+}
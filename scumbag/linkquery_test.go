@@ -0,0 +1,92 @@
+package scumbag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLinkQuery(t *testing.T) {
+	now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	q, err := ParseLinkQuery("nick:alice domain:www.YouTube.com tag:#Golang tag:talks fav:bob since:2w /some talk/ limit:10 -random", now)
+	if err != nil {
+		t.Fatalf("Error parsing query: %s", err)
+	}
+
+	expected := &LinkQuery{
+		Nick:     "alice",
+		Domain:   "youtube.com",
+		Pattern:  "some talk",
+		Tags:     []string{"golang", "talks"},
+		Favorite: "bob",
+		Since:    time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC),
+		Limit:    10,
+		Random:   true,
+	}
+	if !reflect.DeepEqual(q, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, q)
+	}
+
+	q, err = ParseLinkQuery("oshuma", now)
+	if err != nil || q.Nick != "oshuma" || q.Limit != searchLimit {
+		t.Errorf("Bare nick not parsed: %+v (%v)", q, err)
+	}
+
+	q, err = ParseLinkQuery("/imgur/", now)
+	if err != nil || q.Pattern != "imgur" || q.Nick != "" {
+		t.Errorf("Bare pattern not parsed: %+v (%v)", q, err)
+	}
+
+	q, _ = ParseLinkQuery("limit:1000 until:2020-01-31", now)
+	if q.Limit != searchMaxLimit {
+		t.Errorf("Limit not capped: %d", q.Limit)
+	}
+	if !q.Until.Equal(time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Until not parsed: %s", q.Until)
+	}
+
+	for _, bad := range []string{"since:tomorrow", "limit:x", "color:red", "nick:", "alice bob", "-dead", "alice -randum"} {
+		if _, err := ParseLinkQuery(bad, now); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestLinkQuerySQL(t *testing.T) {
+	q := &LinkQuery{Nick: "alice", Pattern: "talk", Limit: 5}
+	sql, args := q.SQL("irc.example.com", "#scumbag")
+
+	expectedSQL := "SELECT " + linkColumns + " FROM links WHERE server=$1 AND channel=$2 AND nick=$3 AND url ILIKE '%' || $4 || '%' ESCAPE '\\' ORDER BY created_at DESC LIMIT $5;"
+	if sql != expectedSQL {
+		t.Errorf("Expected SQL:\n%s\ngot:\n%s", expectedSQL, sql)
+	}
+
+	expectedArgs := []interface{}{"irc.example.com", "#scumbag", "alice", "talk", 5}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected args %v, got %v", expectedArgs, args)
+	}
+
+	q = &LinkQuery{Pattern: `50%_off\`, Limit: 5}
+	_, args = q.SQL("irc.example.com", "#scumbag")
+	if expected := `50\%\_off\\`; args[2] != expected {
+		t.Errorf("Expected pattern %s, got %v", expected, args[2])
+	}
+
+	q = &LinkQuery{Tags: []string{"golang"}, Favorite: "bob", Limit: 5}
+	sql, args = q.SQL("irc.example.com", "#scumbag")
+
+	expectedWhere := "WHERE server=$1 AND channel=$2 AND id IN (SELECT link_id FROM link_tags WHERE tag=$3) AND id IN (SELECT link_id FROM link_favorites WHERE nick=$4) ORDER BY"
+	if !strings.Contains(sql, expectedWhere) || len(args) != 5 {
+		t.Errorf("Expected SQL containing:\n%s\ngot:\n%s %v", expectedWhere, sql, args)
+	}
+
+	q = &LinkQuery{AllChannels: true, Random: true, Limit: 1}
+	sql, args = q.SQL("irc.example.com", "#scumbag")
+
+	expectedSQL = "SELECT " + linkColumns + " FROM links WHERE server=$1 ORDER BY random() LIMIT $2;"
+	if sql != expectedSQL || len(args) != 2 {
+		t.Errorf("Expected SQL:\n%s\ngot:\n%s %v", expectedSQL, sql, args)
+	}
+}
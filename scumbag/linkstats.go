@@ -0,0 +1,195 @@
+package scumbag
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	// Days and weeks of links counted by "<cmdPrefix>urlstats".
+	linkStatsDays  = 7
+	linkStatsWeeks = 4
+
+	linkStatsDateFormat = "Jan 2"
+)
+
+var urlStatsHelp = []string{
+	cmdURLStats + " [since:<2w/2020-01-31>] [until:<date>] -- channel link stats",
+	cmdURLStats + " -all-channels [filters] -- stats for the whole server",
+	cmdURLStats + " nick:<nick> [filters] -- stats and most reposted link for one nick",
+}
+
+// LinkStatsCommand reports aggregate statistics on saved links.
+type LinkStatsCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewLinkStatsCommand returns a new LinkStatsCommand instance.
+func NewLinkStatsCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *LinkStatsCommand {
+	return &LinkStatsCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run is the command handler for "<cmdPrefix>urlstats <filters>"; filters are the same as LinkQuery.
+func (cmd *LinkStatsCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("LinkStatsCommand.Run()", err)
+		return
+	}
+
+	now := time.Now()
+	query, err := ParseLinkQuery(args[0], now)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return
+	}
+
+	if query.AllChannels && !cmd.bot.Admin(cmd.line.Nick) {
+		cmd.bot.Msg(cmd.conn, channel, "-all-channels is admin only.")
+		return
+	}
+
+	stats, err := cmd.bot.Links.LinkStats(query, cmd.conn.Config().Server, channel)
+	if err != nil {
+		cmd.bot.LogError("LinkStatsCommand.Run()", err)
+		return
+	}
+
+	total := stats.Total
+	if total <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "No links.")
+		return
+	}
+
+	// Averages are over the requested window, or since the first link.
+	start := query.Since
+	if start.IsZero() {
+		start = stats.First
+	}
+	end := query.Until
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+	days := math.Max(end.Sub(start).Hours()/24, 1)
+
+	cmd.bot.Msg(cmd.conn, channel, "Links: %d total, %.1f/day, %.1f/week", total, float64(total)/days, float64(total)/days*7)
+	cmd.bot.Msg(cmd.conn, channel, "Per day: %s", formatLinkActivity(stats.PerDay))
+	cmd.bot.Msg(cmd.conn, channel, "Per week: %s", formatLinkActivity(stats.PerWeek))
+
+	if query.Nick == "" {
+		cmd.bot.Msg(cmd.conn, channel, "Top posters: %s", formatLinkCounts(stats.TopPosters))
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "Top domains: %s", formatLinkCounts(stats.TopDomains))
+
+	if link := stats.MostReposted; link != nil {
+		cmd.bot.Msg(cmd.conn, channel, "Most reposted: %s (%dx, by %s)", link.URL, link.Reposts+1, link.Nick)
+	}
+}
+
+// Help shows the command help.
+func (cmd *LinkStatsCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("LinkStatsCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range urlStatsHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+func formatLinkCounts(counts []*LinkCount) string {
+	if len(counts) <= 0 {
+		return "none"
+	}
+
+	formatted := make([]string, len(counts))
+	for i, count := range counts {
+		formatted[i] = fmt.Sprintf("%s (%d)", count.Name, count.Count)
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// formatLinkActivity formats per-day or per-week counts, oldest first.
+func formatLinkActivity(counts []*LinkCount) string {
+	formatted := make([]string, len(counts))
+	for i, count := range counts {
+		formatted[i] = fmt.Sprintf("%s: %d", count.Name, count.Count)
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// linkActivityEnd returns when the query's per-day and per-week counts end:
+// its until: date, or `now`.
+func linkActivityEnd(query *LinkQuery, now time.Time) time.Time {
+	if query.Until.IsZero() || query.Until.After(now) {
+		return now
+	}
+	return query.Until
+}
+
+// linkActivityStart returns the start of the oldest day or week counted up to `end`.
+func linkActivityStart(end time.Time) time.Time {
+	return startOfWeek(end).AddDate(0, 0, -7*(linkStatsWeeks-1))
+}
+
+// linkActivity counts links created at `times` on each of the last
+// linkStatsDays days and in each of the last linkStatsWeeks weeks (from
+// Monday) up to `end`, oldest first.
+func linkActivity(times []time.Time, end time.Time) (perDay, perWeek []*LinkCount) {
+	dayStarts := make([]time.Time, linkStatsDays)
+	for i := range dayStarts {
+		dayStarts[i] = startOfDay(end).AddDate(0, 0, i-linkStatsDays+1)
+		perDay = append(perDay, &LinkCount{Name: dayStarts[i].Format(linkStatsDateFormat)})
+	}
+
+	weekStarts := make([]time.Time, linkStatsWeeks)
+	for i := range weekStarts {
+		weekStarts[i] = startOfWeek(end).AddDate(0, 0, 7*(i-linkStatsWeeks+1))
+		perWeek = append(perWeek, &LinkCount{Name: weekStarts[i].Format(linkStatsDateFormat)})
+	}
+
+	for _, t := range times {
+		t = t.In(end.Location())
+		if t.After(end) {
+			continue
+		}
+		countLinkActivity(perDay, dayStarts, t)
+		countLinkActivity(perWeek, weekStarts, t)
+	}
+
+	return perDay, perWeek
+}
+
+// countLinkActivity adds `t` to the newest of `counts` starting before it.
+func countLinkActivity(counts []*LinkCount, starts []time.Time, t time.Time) {
+	for i := len(starts) - 1; i >= 0; i-- {
+		if !t.Before(starts[i]) {
+			counts[i].Count++
+			return
+		}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the start of the Monday on or before `t`.
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,83 @@
+package scumbag
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkActivity(t *testing.T) {
+	// A Wednesday.
+	end := time.Date(2020, 3, 18, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 11, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 12, 9, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 17, 23, 59, 0, 0, time.UTC),
+		time.Date(2020, 3, 18, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 18, 13, 0, 0, 0, time.UTC),
+	}
+
+	perDay, perWeek := linkActivity(times, end)
+	if formatted := formatLinkActivity(perDay); formatted != "Mar 12: 1, Mar 13: 0, Mar 14: 0, Mar 15: 0, Mar 16: 0, Mar 17: 1, Mar 18: 1" {
+		t.Errorf("Unexpected per day counts: %s", formatted)
+	}
+	if formatted := formatLinkActivity(perWeek); formatted != "Feb 24: 1, Mar 2: 0, Mar 9: 2, Mar 16: 2" {
+		t.Errorf("Unexpected per week counts: %s", formatted)
+	}
+}
+
+func TestLinkStatsCommand(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Links = NewMemoryStore()
+	conn := newTestConn(t, bot)
+
+	now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.Local)
+	for i, link := range []*Link{
+		{Nick: "alice", URL: "https://www.youtube.com/watch?v=1", Channel: "#scumbag"},
+		{Nick: "bob", URL: "https://golang.org/doc/", Channel: "#scumbag"},
+		{Nick: "alice", URL: "https://example.com/", Channel: "#secret"},
+	} {
+		link.Server = conn.Config().Server
+		link.CreatedAt = now.Add(time.Duration(i) * time.Hour)
+		if err := bot.Links.SaveLink(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(nick, args string) []string {
+		NewLinkStatsCommand(bot, conn.Conn, testLine(nick, "#scumbag", cmdURLStats+" "+args)).Run(args)
+		return conn.said(t)
+	}
+
+	said := run("alice", "until:2020-03-16")
+	expected := []string{
+		"#scumbag Links: 2 total",
+		"#scumbag Per day: Mar 10: 0, Mar 11: 0, Mar 12: 0, Mar 13: 0, Mar 14: 0, Mar 15: 2, Mar 16: 0",
+		"#scumbag Per week: Feb 24: 0, Mar 2: 0, Mar 9: 2, Mar 16: 0",
+		"#scumbag Top posters: alice (1), bob (1)",
+	}
+	if len(said) < len(expected) {
+		t.Fatalf("Expected stats, got %q", said)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(said[i], prefix) {
+			t.Errorf("Expected %q, got %q", prefix, said[i])
+		}
+	}
+
+	if said := run("alice", "-all-channels"); len(said) != 1 || said[0] != "#scumbag -all-channels is admin only." {
+		t.Errorf("Expected -all-channels to be refused, got %q", said)
+	}
+
+	if said := run("admin_nick", "-all-channels until:2020-03-16"); len(said) < 1 || !strings.HasPrefix(said[0], "#scumbag Links: 3 total") {
+		t.Errorf("Expected stats for all channels, got %q", said)
+	}
+
+	if said := run("alice", "since:bogus"); len(said) != 1 || said[0] != "#scumbag Invalid date: bogus" {
+		t.Errorf("Expected an invalid date, got %q", said)
+	}
+}
@@ -0,0 +1,124 @@
+package scumbag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxTagLength = 32
+
+// formatLinkID returns the short ID shown next to a link, e.g. "#123".
+func formatLinkID(id int64) string {
+	return "#" + strconv.FormatInt(id, 10)
+}
+
+// parseLinkID parses a link ID with or without the leading "#".
+func parseLinkID(rawID string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(rawID, "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("Invalid link ID: %s", rawID)
+	}
+	return id, nil
+}
+
+// normalizeTag lowercases a tag and strips a leading "#".
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// channelLink returns the ID of link `rawID` if it was saved in this channel.
+func (cmd *LinkCommand) channelLink(channel, rawID string) (int64, bool) {
+	id, err := parseLinkID(rawID)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return 0, false
+	}
+
+	link, err := cmd.bot.Links.GetLink(cmd.conn.Config().Server, channel, id)
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.channelLink()", err)
+		return 0, false
+	}
+
+	if link == nil {
+		cmd.bot.Msg(cmd.conn, channel, "No link %s in %s.", formatLinkID(id), channel)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// tag adds (or removes) `tags` on a link.
+func (cmd *LinkCommand) tag(channel, rawID string, tags []string, add bool) {
+	id, ok := cmd.channelLink(channel, rawID)
+	if !ok {
+		return
+	}
+
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || len(tag) > maxTagLength {
+			cmd.bot.Msg(cmd.conn, channel, "Invalid tag: %s", tag)
+			return
+		}
+
+		var err error
+		if add {
+			err = cmd.bot.Links.TagLink(id, tag, cmd.line.Nick, time.Now())
+		} else {
+			err = cmd.bot.Links.UntagLink(id, tag)
+		}
+		if err != nil {
+			cmd.bot.LogError("LinkCommand.tag()", err)
+			return
+		}
+	}
+
+	current, err := cmd.bot.Links.LinkTags(id)
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.tag()", err)
+		return
+	}
+
+	if len(current) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "%s has no tags.", formatLinkID(id))
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s tags: %s", formatLinkID(id), strings.Join(current, ", "))
+}
+
+// favorite adds (or removes) a link to the user's favorites.
+func (cmd *LinkCommand) favorite(channel, rawID string, add bool) {
+	id, ok := cmd.channelLink(channel, rawID)
+	if !ok {
+		return
+	}
+
+	nick := cmd.line.Nick
+
+	var changed bool
+	var err error
+	if add {
+		changed, err = cmd.bot.Links.FavoriteLink(id, cmd.conn.Config().Server, nick, time.Now())
+	} else {
+		changed, err = cmd.bot.Links.UnfavoriteLink(id, nick)
+	}
+	if err != nil {
+		cmd.bot.LogError("LinkCommand.favorite()", err)
+		return
+	}
+
+	switch {
+	case add && changed:
+		cmd.bot.Msg(cmd.conn, channel, "%s: favorited %s.", nick, formatLinkID(id))
+	case add:
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s is already a favorite.", nick, formatLinkID(id))
+	case changed:
+		cmd.bot.Msg(cmd.conn, channel, "%s: unfavorited %s.", nick, formatLinkID(id))
+	default:
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s isn't a favorite.", nick, formatLinkID(id))
+	}
+}
@@ -0,0 +1,272 @@
+package scumbag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	memoDefaultQuota = 5
+
+	tellPrivateFlag = "-private"
+	memosCancel     = "cancel"
+)
+
+var tellHelp = []string{
+	cmdTell + " <nick> <message> -- deliver a message the next time the nick speaks or joins",
+	cmdTell + " " + tellPrivateFlag + " <nick> <message> -- deliver it in a private message",
+}
+
+var memosHelp = []string{
+	cmdMemos + " -- list the memos you left that haven't been delivered yet",
+	cmdMemos + " " + memosCancel + " <id> -- cancel one of them",
+}
+
+// Memo is a message left for a nick with "<cmdPrefix>tell".
+type Memo struct {
+	ID        int64
+	Server    string
+	Channel   string
+	Sender    string
+	Recipient string
+	Message   string
+	// Private memos are delivered in a private message instead of the channel.
+	Private   bool
+	CreatedAt time.Time
+}
+
+// String formats the memo for its recipient.
+func (memo *Memo) String() string {
+	return fmt.Sprintf("%s left you a message %s: %s", memo.Sender, humanize.Time(memo.CreatedAt), memo.Message)
+}
+
+// memoHandler handles JOIN events.
+func (bot *Scumbag) memoHandler(conn *irc.Conn, line *irc.Line) {
+	go bot.DeliverMemos(conn, line)
+}
+
+// DeliverMemos is called from a goroutine to deliver memos waiting for the nick in `line`.
+func (bot *Scumbag) DeliverMemos(conn *irc.Conn, line *irc.Line) {
+	if line.Nick == "" || line.Nick == conn.Me().Nick || bot.databaseDown() {
+		return
+	}
+
+	memos, err := bot.Memos.TakeMemos(conn.Config().Server, line.Nick)
+	if err != nil {
+		if !bot.checkDatabase(err) {
+			bot.LogError("DeliverMemos()", err)
+		}
+		return
+	}
+
+	// Public memos go to the channel the nick spoke in or joined, if it's the
+	// one they were left in; anywhere else they're private.
+	channel := ""
+	switch {
+	case line.Cmd == "JOIN" && len(line.Args) > 0:
+		channel = line.Args[0]
+	case line.Cmd == "PRIVMSG" && line.Public():
+		channel = line.Target()
+	}
+
+	for _, memo := range memos {
+		if memo.Private || !strings.EqualFold(channel, memo.Channel) {
+			bot.Msg(conn, line.Nick, "%s", memo)
+		} else {
+			bot.Msg(conn, channel, "%s: %s", line.Nick, memo)
+		}
+	}
+}
+
+// memoQuota returns the most undelivered memos one nick can leave.
+func (bot *Scumbag) memoQuota() int {
+	if bot.Config.Memos == nil || bot.Config.Memos.Quota <= 0 {
+		return memoDefaultQuota
+	}
+	return bot.Config.Memos.Quota
+}
+
+// TellCommand leaves a memo for another nick.
+type TellCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewTellCommand returns a new TellCommand instance.
+func NewTellCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *TellCommand {
+	return &TellCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *TellCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("TellCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 {
+		cmd.bot.Log.WithField("args", args).Debug("TellCommand.Run(): No args")
+		return
+	}
+
+	fields := strings.Fields(args[0])
+	private := len(fields) > 0 && fields[0] == tellPrivateFlag
+	if private {
+		fields = fields[1:]
+	}
+
+	if len(fields) < 2 {
+		cmd.Help()
+		return
+	}
+
+	sender := cmd.line.Nick
+	recipient := fields[0]
+	switch {
+	case strings.EqualFold(recipient, sender):
+		cmd.bot.Msg(cmd.conn, channel, "Tell yourself.")
+		return
+	case strings.EqualFold(recipient, cmd.conn.Me().Nick):
+		cmd.bot.Msg(cmd.conn, channel, "I'm right here.")
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	sent, err := cmd.bot.Memos.SentMemos(server, sender)
+	if err != nil {
+		cmd.bot.LogError("TellCommand.Run()", err)
+		return
+	}
+
+	if quota := cmd.bot.memoQuota(); len(sent) >= quota {
+		cmd.bot.Msg(cmd.conn, channel, "You already have %d memos waiting; cancel one with %s %s <id>", len(sent), cmdMemos, memosCancel)
+		return
+	}
+
+	memo := &Memo{
+		Server:    server,
+		Sender:    sender,
+		Recipient: recipient,
+		Message:   strings.Join(fields[1:], " "),
+		Private:   private,
+		CreatedAt: time.Now(),
+	}
+	if cmd.line.Public() {
+		memo.Channel = channel
+	}
+
+	if err := cmd.bot.Memos.SaveMemo(memo); err != nil {
+		cmd.bot.LogError("TellCommand.Run()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "I'll tell %s when they're around.", recipient)
+}
+
+// Help shows the command help.
+func (cmd *TellCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("TellCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range tellHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+// MemosCommand lists or cancels the memos a nick left.
+type MemosCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewMemosCommand returns a new MemosCommand instance.
+func NewMemosCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *MemosCommand {
+	return &MemosCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *MemosCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("MemosCommand.Run()", err)
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+
+	server := cmd.conn.Config().Server
+	nick := cmd.line.Nick
+
+	if len(fields) > 0 {
+		if fields[0] != memosCancel || len(fields) != 2 {
+			cmd.Help()
+			return
+		}
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			cmd.Help()
+			return
+		}
+
+		cancelled, err := cmd.bot.Memos.CancelMemo(server, nick, id)
+		if err != nil {
+			cmd.bot.LogError("MemosCommand.Run()", err)
+			return
+		}
+
+		if !cancelled {
+			cmd.bot.Msg(cmd.conn, channel, "You have no memo #%d waiting.", id)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Cancelled memo #%d.", id)
+		return
+	}
+
+	memos, err := cmd.bot.Memos.SentMemos(server, nick)
+	if err != nil {
+		cmd.bot.LogError("MemosCommand.Run()", err)
+		return
+	}
+
+	if len(memos) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "You have no memos waiting.")
+		return
+	}
+
+	// Private memos shouldn't be listed in the channel.
+	for _, memo := range memos {
+		cmd.bot.Msg(cmd.conn, nick, "#%d to %s, %s: %s", memo.ID, memo.Recipient, humanize.Time(memo.CreatedAt), memo.Message)
+	}
+}
+
+// Help shows the command help.
+func (cmd *MemosCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("MemosCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range memosHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
@@ -0,0 +1,50 @@
+package scumbag
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+func TestDeliverMemos(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Memos = NewMemoryStore()
+	conn := newTestConn(t, bot)
+
+	tell := func(channel, message string, private bool) {
+		memo := &Memo{Server: conn.Config().Server, Channel: channel, Sender: "bob", Recipient: "alice", Message: message, Private: private, CreatedAt: time.Now()}
+		if err := bot.Memos.SaveMemo(memo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	join := &irc.Line{Nick: "alice", Ident: "alice", Host: "example.com", Src: "alice!alice@example.com", Cmd: "JOIN", Args: []string{"#Scumbag"}, Time: time.Now()}
+
+	for _, tc := range []struct {
+		name     string
+		line     *irc.Line
+		channel  string
+		private  bool
+		expected string
+	}{
+		{"join same channel", join, "#scumbag", false, "#Scumbag alice: bob left you a message"},
+		{"join other channel", join, "#secret", false, "alice bob left you a message"},
+		{"private memo", join, "#scumbag", true, "alice bob left you a message"},
+		{"speak in same channel", testLine("alice", "#secret", "hi"), "#secret", false, "#secret alice: bob left you a message"},
+		{"speak in other channel", testLine("alice", "#scumbag", "hi"), "#secret", false, "alice bob left you a message"},
+		{"private message", testLine("alice", conn.Me().Nick, "hi"), "#scumbag", false, "alice bob left you a message"},
+	} {
+		tell(tc.channel, tc.name, tc.private)
+		bot.DeliverMemos(conn.Conn, tc.line)
+
+		said := conn.said(t)
+		if len(said) != 1 || !strings.HasPrefix(said[0], tc.expected) || !strings.HasSuffix(said[0], tc.name) {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, said)
+		}
+	}
+}
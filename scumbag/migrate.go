@@ -0,0 +1,274 @@
+package scumbag
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	cliMigrateUp     = "up"
+	cliMigrateDown   = "down"
+	cliMigrateStatus = "status"
+)
+
+// migrationFiles holds the migrations for each driver, named "<version>-<name>.<up|down>.sql".
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFilenameRegexp = regexp.MustCompile(`\A(\d+)-(.+)\.(up|down)\.sql\z`)
+
+// migration is one versioned schema change.
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+
+	// When it was applied, or zero if it's pending.
+	AppliedAt time.Time
+}
+
+func (m *migration) String() string {
+	return fmt.Sprintf("%03d-%s", m.Version, m.Name)
+}
+
+// loadMigrations returns the embedded migrations in `dir`, oldest first.
+func loadMigrations(dir string) ([]*migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		match := migrationFilenameRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Bad migration file name: %s", entry.Name())
+		}
+
+		contents, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("Migration %s needs both up and down files", m)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// migrations returns every migration for the store's driver, with AppliedAt set
+// on those recorded in schema_migrations.
+func (store *SQLStore) migrations() ([]*migration, error) {
+	migrations, err := loadMigrations(store.dialect.migrationDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := store.exec(store.db, "CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name varchar, applied_at timestamp);"); err != nil {
+		return nil, err
+	}
+
+	rows, err := store.query(store.db, "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = store.dialect.scanTime(appliedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		m.AppliedAt = applied[m.Version]
+	}
+
+	return migrations, nil
+}
+
+// migrateUp applies pending migrations up to and including version `to`
+// (or all of them if `to` is 0), each in its own transaction.
+func (store *SQLStore) migrateUp(to int) ([]*migration, error) {
+	migrations, err := store.migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*migration
+	for _, m := range migrations {
+		if !m.AppliedAt.IsZero() {
+			continue
+		}
+		if to > 0 && m.Version > to {
+			break
+		}
+
+		if err := store.runMigration(m, true); err != nil {
+			return applied, fmt.Errorf("%s: %s", m, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// migrateDown reverts the last `steps` applied migrations, newest first.
+func (store *SQLStore) migrateDown(steps int) ([]*migration, error) {
+	migrations, err := store.migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []*migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if m.AppliedAt.IsZero() {
+			continue
+		}
+
+		if err := store.runMigration(m, false); err != nil {
+			return reverted, fmt.Errorf("%s: %s", m, err)
+		}
+		reverted = append(reverted, m)
+	}
+
+	return reverted, nil
+}
+
+func (store *SQLStore) runMigration(m *migration, up bool) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := store.exec(tx, m.Up); err != nil {
+			return err
+		}
+		m.AppliedAt = time.Now()
+		if _, err := store.exec(tx, "INSERT INTO schema_migrations(version, name, applied_at) VALUES($1, $2, $3);", m.Version, m.Name, m.AppliedAt); err != nil {
+			return err
+		}
+	} else {
+		if _, err := store.exec(tx, m.Down); err != nil {
+			return err
+		}
+		m.AppliedAt = time.Time{}
+		if _, err := store.exec(tx, "DELETE FROM schema_migrations WHERE version=$1;", m.Version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// autoMigrate applies pending migrations on startup, if Database.AutoMigrate is set.
+func (bot *Scumbag) autoMigrate() error {
+	store, ok := bot.Links.(*SQLStore)
+	if !ok || !bot.Config.Database.AutoMigrate {
+		return nil
+	}
+
+	applied, err := store.migrateUp(0)
+	for _, m := range applied {
+		bot.Log.WithField("migration", m.String()).Info("Applied migration.")
+	}
+	return err
+}
+
+// migrateCLI runs "migrate up [-to <version>]", "migrate down [-steps <n>]" or "migrate status".
+func (bot *Scumbag) migrateCLI(out io.Writer, args []string) error {
+	usage := fmt.Errorf("Usage: migrate <%s|%s|%s>", cliMigrateUp, cliMigrateDown, cliMigrateStatus)
+	if len(args) <= 0 {
+		return usage
+	}
+
+	store, ok := bot.Links.(*SQLStore)
+	if !ok {
+		return fmt.Errorf("Configured database can't be migrated")
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	to := flags.Int("to", 0, "Migrate up to this version (default latest)")
+	steps := flags.Int("steps", 1, "Number of migrations to revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case cliMigrateUp:
+		applied, err := store.migrateUp(*to)
+		for _, m := range applied {
+			fmt.Fprintf(out, "Applied %s\n", m)
+		}
+		if err == nil && len(applied) <= 0 {
+			fmt.Fprintln(out, "Nothing to migrate.")
+		}
+		return err
+
+	case cliMigrateDown:
+		reverted, err := store.migrateDown(*steps)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "Reverted %s\n", m)
+		}
+		return err
+
+	case cliMigrateStatus:
+		migrations, err := store.migrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			status := "pending"
+			if !m.AppliedAt.IsZero() {
+				status = "applied " + m.AppliedAt.Format(linkRecordTimeFormat)
+			}
+			fmt.Fprintf(out, "%s  %s\n", m, status)
+		}
+		return nil
+
+	default:
+		return usage
+	}
+}
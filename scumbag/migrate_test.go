@@ -0,0 +1,59 @@
+package scumbag
+
+import (
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	postgres, err := loadMigrations(postgresDialect.migrationDir)
+	if err != nil {
+		t.Fatalf("Error loading Postgres migrations: %s", err)
+	}
+
+	sqlite, err := loadMigrations(sqliteDialect.migrationDir)
+	if err != nil {
+		t.Fatalf("Error loading SQLite migrations: %s", err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("Expected the same migrations for each driver, got %d and %d", len(postgres), len(sqlite))
+	}
+	for i, m := range postgres {
+		if m.Version != i+1 || m.String() != sqlite[i].String() {
+			t.Errorf("Expected migration %d to match, got %s and %s", i+1, m, sqlite[i])
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	store := newTestSQLiteStore(t)
+
+	migrations, err := store.migrations()
+	if err != nil {
+		t.Fatalf("Error getting migrations: %s", err)
+	}
+	for _, m := range migrations {
+		if m.AppliedAt.IsZero() {
+			t.Errorf("Expected %s to be applied", m)
+		}
+	}
+
+	if applied, err := store.migrateUp(0); err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing to migrate, got %v (%v)", applied, err)
+	}
+
+	reverted, err := store.migrateDown(len(migrations))
+	if err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("Expected %d migrations reverted, got %v (%v)", len(migrations), reverted, err)
+	}
+	if reverted[0].Version != len(migrations) {
+		t.Errorf("Expected newest migration reverted first, got %s", reverted[0])
+	}
+
+	if applied, err := store.migrateUp(3); err != nil || len(applied) != 3 {
+		t.Errorf("Expected 3 migrations applied, got %v (%v)", applied, err)
+	}
+	if applied, err := store.migrateUp(0); err != nil || len(applied) != len(migrations)-3 {
+		t.Errorf("Expected the rest applied, got %v (%v)", applied, err)
+	}
+}
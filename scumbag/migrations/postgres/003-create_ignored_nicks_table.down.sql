@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ignored_nicks;
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS canonical_url varchar;
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS reposts integer NOT NULL DEFAULT 0;
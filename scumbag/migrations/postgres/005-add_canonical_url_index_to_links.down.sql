@@ -0,0 +1 @@
+DROP INDEX IF EXISTS links_server_channel_canonical_url_idx;
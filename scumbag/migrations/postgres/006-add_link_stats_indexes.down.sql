@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS links_server_host_idx;
+DROP INDEX IF EXISTS links_server_reposts_idx;
+DROP INDEX IF EXISTS links_server_nick_idx;
+DROP INDEX IF EXISTS links_server_channel_created_at_idx;
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS links_server_channel_created_at_idx ON links (server, channel, created_at);
+CREATE INDEX IF NOT EXISTS links_server_nick_idx ON links (server, nick);
+CREATE INDEX IF NOT EXISTS links_server_reposts_idx ON links (server, reposts) WHERE reposts > 0;
+
+-- Matches linkHostSQL in scumbag/linkquery.go, for grouping and filtering by domain.
+CREATE INDEX IF NOT EXISTS links_server_host_idx ON links (server, (lower(substring(COALESCE(canonical_url, url) from '://([^/:?#]+)'))));
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS links_checked_at_idx;
+
+ALTER TABLE IF EXISTS links DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE IF EXISTS links DROP COLUMN IF EXISTS dead_since;
+ALTER TABLE IF EXISTS links DROP COLUMN IF EXISTS checked_at;
+ALTER TABLE IF EXISTS links DROP COLUMN IF EXISTS status_code;
@@ -0,0 +1,6 @@
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS status_code integer;
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS checked_at timestamp without time zone;
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS dead_since timestamp without time zone;
+ALTER TABLE IF EXISTS links ADD COLUMN IF NOT EXISTS archived_at timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS links_checked_at_idx ON links (checked_at NULLS FIRST);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS link_purges (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  requested_by varchar,
+  reason varchar,
+  deleted integer,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS link_tags (
+  id serial,
+  link_id integer REFERENCES links (id) ON DELETE CASCADE,
+  tag varchar,
+  nick varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id),
+  UNIQUE (link_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS link_tags_tag_idx ON link_tags (tag);
+
+CREATE TABLE IF NOT EXISTS link_favorites (
+  id serial,
+  link_id integer REFERENCES links (id) ON DELETE CASCADE,
+  server varchar,
+  nick varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id),
+  UNIQUE (link_id, nick)
+);
+
+CREATE INDEX IF NOT EXISTS link_favorites_server_nick_idx ON link_favorites (server, nick);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS seen (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  action varchar,
+  message varchar,
+  target varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS seen_server_nick_idx ON seen (server, lower(nick));
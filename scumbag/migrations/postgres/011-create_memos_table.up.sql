@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS memos (
+  id serial,
+  server varchar,
+  channel varchar,
+  sender varchar,
+  recipient varchar,
+  message varchar,
+  private boolean DEFAULT false,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS memos_server_recipient_idx ON memos (server, lower(recipient));
+CREATE INDEX IF NOT EXISTS memos_server_sender_idx ON memos (server, lower(sender));
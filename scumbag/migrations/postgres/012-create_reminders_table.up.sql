@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reminders (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  mention boolean DEFAULT false,
+  message varchar,
+  timezone varchar,
+  repeat varchar,
+  remind_at timestamp without time zone,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS reminders_remind_at_idx ON reminders (remind_at);
+CREATE INDEX IF NOT EXISTS reminders_server_nick_idx ON reminders (server, lower(nick));
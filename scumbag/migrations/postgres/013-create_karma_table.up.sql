@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS karma (
+  id serial,
+  server varchar,
+  channel varchar,
+  thing varchar,
+  nick varchar,
+  delta integer,
+  reason varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS karma_server_channel_thing_idx ON karma (server, channel, thing);
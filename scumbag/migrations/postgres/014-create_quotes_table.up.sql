@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS quotes (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  text varchar,
+  added_by varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS quotes_server_channel_idx ON quotes (server, channel);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS factoid_edits;
+DROP TABLE IF EXISTS factoids;
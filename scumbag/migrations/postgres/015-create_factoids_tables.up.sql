@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS factoids (
+  id serial,
+  server varchar,
+  channel varchar,
+  name varchar,
+  value varchar,
+  locked boolean DEFAULT false,
+  nick varchar,
+  created_at timestamp without time zone,
+  updated_at timestamp without time zone,
+
+  PRIMARY KEY (id),
+  UNIQUE (server, channel, name)
+);
+
+CREATE TABLE IF NOT EXISTS factoid_edits (
+  id serial,
+  server varchar,
+  channel varchar,
+  name varchar,
+  action varchar,
+  value varchar,
+  nick varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS factoid_edits_server_channel_name_idx ON factoid_edits (server, channel, name);
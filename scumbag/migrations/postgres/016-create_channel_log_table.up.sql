@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS channel_log (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  kind varchar,
+  text varchar,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS channel_log_server_channel_created_at_idx ON channel_log (server, channel, created_at);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS polls (
+  id serial,
+  server varchar,
+  channel varchar,
+  question varchar,
+  options varchar,
+  nick varchar,
+  status varchar,
+  closes_at timestamp without time zone,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS polls_server_channel_status_idx ON polls (server, channel, status);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+  id serial,
+  poll_id integer REFERENCES polls (id) ON DELETE CASCADE,
+  voter varchar,
+  nick varchar,
+  choice integer,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id),
+  UNIQUE (poll_id, voter)
+);
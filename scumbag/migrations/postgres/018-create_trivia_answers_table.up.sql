@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS trivia_answers (
+  id serial,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  question varchar,
+  points integer,
+  streak integer,
+  created_at timestamp without time zone,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS trivia_answers_server_channel_idx ON trivia_answers (server, channel);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS polls_server_channel_open_idx;
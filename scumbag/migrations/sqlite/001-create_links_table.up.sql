@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS links (
+  id integer PRIMARY KEY,
+  url varchar,
+  nick varchar,
+  created_at timestamp
+);
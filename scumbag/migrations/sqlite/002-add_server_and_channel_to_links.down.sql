@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN channel;
+ALTER TABLE links DROP COLUMN server;
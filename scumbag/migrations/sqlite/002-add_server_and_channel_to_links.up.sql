@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN server varchar;
+ALTER TABLE links ADD COLUMN channel varchar;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS ignored_nicks (
+  id integer PRIMARY KEY,
+  server varchar,
+  nick varchar,
+  created_at timestamp
+);
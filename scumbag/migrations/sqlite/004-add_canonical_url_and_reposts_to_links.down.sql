@@ -0,0 +1,2 @@
+ALTER TABLE links DROP COLUMN reposts;
+ALTER TABLE links DROP COLUMN canonical_url;
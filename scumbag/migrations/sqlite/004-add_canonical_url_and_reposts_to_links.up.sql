@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN canonical_url varchar;
+ALTER TABLE links ADD COLUMN reposts integer NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS links_server_channel_canonical_url_idx ON links (server, channel, canonical_url);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS links_server_reposts_idx;
+DROP INDEX IF EXISTS links_server_nick_idx;
+DROP INDEX IF EXISTS links_server_channel_created_at_idx;
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS links_server_channel_created_at_idx ON links (server, channel, created_at);
+CREATE INDEX IF NOT EXISTS links_server_nick_idx ON links (server, nick);
+CREATE INDEX IF NOT EXISTS links_server_reposts_idx ON links (server, reposts) WHERE reposts > 0;
+
+-- No domain index: link_host() only exists inside scumbago, and an index on it
+-- would make the database unwritable from the sqlite3 shell.
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS links_checked_at_idx;
+
+ALTER TABLE links DROP COLUMN archived_at;
+ALTER TABLE links DROP COLUMN dead_since;
+ALTER TABLE links DROP COLUMN checked_at;
+ALTER TABLE links DROP COLUMN status_code;
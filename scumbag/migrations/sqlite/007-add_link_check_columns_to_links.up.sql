@@ -0,0 +1,6 @@
+ALTER TABLE links ADD COLUMN status_code integer;
+ALTER TABLE links ADD COLUMN checked_at timestamp;
+ALTER TABLE links ADD COLUMN dead_since timestamp;
+ALTER TABLE links ADD COLUMN archived_at timestamp;
+
+CREATE INDEX IF NOT EXISTS links_checked_at_idx ON links (checked_at);
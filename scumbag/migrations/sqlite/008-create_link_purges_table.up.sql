@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS link_purges (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  requested_by varchar,
+  reason varchar,
+  deleted integer,
+  created_at timestamp
+);
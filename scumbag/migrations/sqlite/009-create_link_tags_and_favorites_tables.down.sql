@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS link_favorites;
+DROP TABLE IF EXISTS link_tags;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS link_tags (
+  id integer PRIMARY KEY,
+  link_id integer REFERENCES links (id) ON DELETE CASCADE,
+  tag varchar,
+  nick varchar,
+  created_at timestamp,
+
+  UNIQUE (link_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS link_tags_tag_idx ON link_tags (tag);
+
+CREATE TABLE IF NOT EXISTS link_favorites (
+  id integer PRIMARY KEY,
+  link_id integer REFERENCES links (id) ON DELETE CASCADE,
+  server varchar,
+  nick varchar,
+  created_at timestamp,
+
+  UNIQUE (link_id, nick)
+);
+
+CREATE INDEX IF NOT EXISTS link_favorites_server_nick_idx ON link_favorites (server, nick);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS seen (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  action varchar,
+  message varchar,
+  target varchar,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS seen_server_nick_idx ON seen (server, lower(nick));
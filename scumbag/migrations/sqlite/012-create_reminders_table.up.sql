@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reminders (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  mention boolean DEFAULT false,
+  message varchar,
+  timezone varchar,
+  repeat varchar,
+  remind_at timestamp,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS reminders_remind_at_idx ON reminders (remind_at);
+CREATE INDEX IF NOT EXISTS reminders_server_nick_idx ON reminders (server, lower(nick));
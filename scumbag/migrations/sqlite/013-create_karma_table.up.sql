@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS karma (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  thing varchar,
+  nick varchar,
+  delta integer,
+  reason varchar,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS karma_server_channel_thing_idx ON karma (server, channel, thing);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS quotes (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  text varchar,
+  added_by varchar,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS quotes_server_channel_idx ON quotes (server, channel);
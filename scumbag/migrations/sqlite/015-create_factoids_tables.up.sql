@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS factoids (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  name varchar,
+  value varchar,
+  locked boolean DEFAULT false,
+  nick varchar,
+  created_at timestamp,
+  updated_at timestamp,
+
+  UNIQUE (server, channel, name)
+);
+
+CREATE TABLE IF NOT EXISTS factoid_edits (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  name varchar,
+  action varchar,
+  value varchar,
+  nick varchar,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS factoid_edits_server_channel_name_idx ON factoid_edits (server, channel, name);
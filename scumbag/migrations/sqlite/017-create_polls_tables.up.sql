@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS polls (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  question varchar,
+  options varchar,
+  nick varchar,
+  status varchar,
+  closes_at timestamp,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS polls_server_channel_status_idx ON polls (server, channel, status);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+  id integer PRIMARY KEY,
+  poll_id integer REFERENCES polls (id) ON DELETE CASCADE,
+  voter varchar,
+  nick varchar,
+  choice integer,
+  created_at timestamp,
+
+  UNIQUE (poll_id, voter)
+);
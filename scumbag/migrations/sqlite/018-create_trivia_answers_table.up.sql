@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS trivia_answers (
+  id integer PRIMARY KEY,
+  server varchar,
+  channel varchar,
+  nick varchar,
+  question varchar,
+  points integer,
+  streak integer,
+  created_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS trivia_answers_server_channel_idx ON trivia_answers (server, channel);
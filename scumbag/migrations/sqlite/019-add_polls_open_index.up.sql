@@ -0,0 +1,4 @@
+-- One open poll per channel; cancel all but the oldest if there are more.
+UPDATE polls SET status='cancelled' WHERE status='open' AND id NOT IN (SELECT min(id) FROM polls WHERE status='open' GROUP BY server, channel);
+
+CREATE UNIQUE INDEX IF NOT EXISTS polls_server_channel_open_idx ON polls (server, channel) WHERE status='open';
@@ -0,0 +1,422 @@
+package scumbag
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// Poll statuses.
+const (
+	pollOpen      = "open"
+	pollClosed    = "closed"
+	pollCancelled = "cancelled"
+)
+
+const (
+	// How often polls are checked for closing.
+	pollInterval = 15 * time.Second
+
+	pollDefaultDuration = 10 * time.Minute
+	pollMaxDuration     = 7 * 24 * time.Hour
+	pollMaxOptions      = 10
+
+	pollClose  = "close"
+	pollCancel = "cancel"
+
+	// The IRCv3 capability which adds an "account" tag to lines.
+	accountTagCap = "account-tag"
+)
+
+// errPollOpen is returned saving a poll in a channel which already has one open.
+var errPollOpen = errors.New("A poll is already open.")
+
+var pollHelp = []string{
+	cmdPoll + ` "<question>" <option> | <option> [| ...] [10m] -- open a poll in the channel, for 10m unless a duration is given`,
+	cmdPoll + " -- show the channel's open poll",
+	cmdPoll + " " + pollClose + " or " + cmdPoll + " " + pollCancel + " -- end the poll early, with or without results (admins only)",
+}
+
+var voteHelp = cmdVote + " <number> -- vote in the channel's open poll; voting again changes your vote"
+
+// Poll is a question the channel votes on until ClosesAt.
+type Poll struct {
+	ID       int64
+	Server   string
+	Channel  string
+	Question string
+	Options  []string
+	// Who opened it.
+	Nick      string
+	Status    string
+	ClosesAt  time.Time
+	CreatedAt time.Time
+}
+
+// PollVote is a vote for one of a poll's options.
+type PollVote struct {
+	PollID int64
+	// The voter's account or hostmask; see pollVoter().
+	Voter string
+	Nick  string
+	// Options are numbered from 1.
+	Choice    int
+	CreatedAt time.Time
+}
+
+func (poll *Poll) String() string {
+	var options []string
+	for i, option := range poll.Options {
+		options = append(options, fmt.Sprintf("%d) %s", i+1, option))
+	}
+	return fmt.Sprintf("Poll #%d: %q %s", poll.ID, poll.Question, strings.Join(options, " "))
+}
+
+// results describes the votes for each option and the winner.
+func (poll *Poll) results(votes map[int]int) string {
+	var counts []string
+	var winners []string
+	total, most := 0, 0
+	for i, option := range poll.Options {
+		count := votes[i+1]
+		counts = append(counts, fmt.Sprintf("%s: %d", option, count))
+		total += count
+
+		switch {
+		case count > most:
+			most = count
+			winners = []string{option}
+		case count == most && count > 0:
+			winners = append(winners, option)
+		}
+	}
+
+	result := fmt.Sprintf("Poll #%d closed: %q %s.", poll.ID, poll.Question, strings.Join(counts, ", "))
+	switch {
+	case total <= 0:
+		return result + " No votes."
+	case len(winners) > 1:
+		return result + " Tie between " + strings.Join(winners, " and ") + "."
+	default:
+		return result + " " + winners[0] + " wins!"
+	}
+}
+
+// parsePoll parses `"<question>" <option> | <option> [| ...] [duration]`. The
+// duration is the last option's last word, if it's one parseReminderDuration() accepts.
+func parsePoll(args string) (question string, options []string, duration time.Duration, err error) {
+	args = strings.TrimSpace(args)
+	if !strings.HasPrefix(args, `"`) {
+		return "", nil, 0, errors.New(`The question goes in "quotes".`)
+	}
+
+	end := strings.Index(args[1:], `"`)
+	if end < 0 {
+		return "", nil, 0, errors.New("The question needs a closing quote.")
+	}
+	question = strings.TrimSpace(args[1 : end+1])
+	if question == "" {
+		return "", nil, 0, errors.New("The question is empty.")
+	}
+
+	options = strings.Split(args[end+2:], "|")
+	duration = pollDefaultDuration
+
+	last := strings.Fields(options[len(options)-1])
+	if len(last) > 0 {
+		if parsed, err := parseReminderDuration(last[len(last)-1]); err == nil {
+			duration = parsed
+			options[len(options)-1] = strings.Join(last[:len(last)-1], " ")
+		}
+	}
+
+	for i, option := range options {
+		options[i] = strings.TrimSpace(option)
+		if options[i] == "" {
+			return "", nil, 0, errors.New("Options can't be empty.")
+		}
+	}
+
+	switch {
+	case len(options) < 2:
+		return "", nil, 0, errors.New("A poll needs at least two options, separated by |.")
+	case len(options) > pollMaxOptions:
+		return "", nil, 0, fmt.Errorf("A poll can have at most %d options.", pollMaxOptions)
+	case duration > pollMaxDuration:
+		return "", nil, 0, errors.New("Polls can run for a week at most.")
+	}
+
+	return question, options, duration, nil
+}
+
+// pollVoter identifies the voter by their services account, if the server
+// acknowledged the account-tag capability requested on connect, or else their
+// user@host, so changing nick doesn't get another vote.
+func pollVoter(line *irc.Line) string {
+	if account := line.Tags["account"]; account != "" && account != "*" {
+		return "account:" + strings.ToLower(account)
+	}
+	return "host:" + strings.ToLower(line.Ident+"@"+line.Host)
+}
+
+// startPolls closes polls as they're due until the bot quits.
+func (bot *Scumbag) startPolls() {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			bot.closePolls(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+		}
+	}()
+}
+
+// closePolls closes the polls due at `now` and announces their results.
+// Polls due while the bot was down are closed late.
+func (bot *Scumbag) closePolls(now time.Time) {
+	if bot.databaseDown() {
+		return
+	}
+
+	polls, err := bot.Polls.DuePolls(now)
+	if err != nil {
+		if !bot.checkDatabase(err) {
+			bot.LogError("closePolls()", err)
+		}
+		return
+	}
+
+	for _, poll := range polls {
+		conn, ok := bot.ircClients[poll.Server]
+		if !ok || !conn.Connected() {
+			// Closed once the server is back.
+			continue
+		}
+
+		bot.closePoll(conn, poll)
+	}
+}
+
+// closePoll closes an open poll and announces its results in its channel.
+func (bot *Scumbag) closePoll(conn *irc.Conn, poll *Poll) {
+	closed, err := bot.Polls.ClosePoll(poll.ID, pollClosed)
+	if err != nil {
+		bot.LogError("closePoll()", err)
+		return
+	}
+	if !closed {
+		return
+	}
+
+	votes, err := bot.Polls.PollVotes(poll.ID)
+	if err != nil {
+		bot.LogError("closePoll()", err)
+		return
+	}
+
+	bot.Log.WithFields(log.Fields{"id": poll.ID, "server": poll.Server, "channel": poll.Channel}).Debug("closePoll()")
+	bot.Msg(conn, poll.Channel, "%s", poll.results(votes))
+}
+
+// PollCommand opens, shows and ends polls.
+type PollCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewPollCommand returns a new PollCommand instance.
+func NewPollCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *PollCommand {
+	return &PollCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *PollCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("PollCommand.Run()", err)
+		return
+	}
+
+	if !cmd.line.Public() {
+		cmd.bot.Msg(cmd.conn, cmd.line.Nick, "Polls only work in channels.")
+		return
+	}
+
+	var arg string
+	if len(args) > 0 {
+		arg = strings.TrimSpace(args[0])
+	}
+
+	server := cmd.conn.Config().Server
+	poll, err := cmd.bot.Polls.OpenPoll(server, channel)
+	if err != nil {
+		cmd.bot.LogError("PollCommand.Run()", err)
+		return
+	}
+
+	switch arg {
+	case "":
+		if poll == nil {
+			cmd.bot.Msg(cmd.conn, channel, "No poll open in %s.", channel)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s -- closes %s.", poll, humanize.Time(poll.ClosesAt))
+
+	case pollClose, pollCancel:
+		if !cmd.bot.Admin(cmd.line.Nick) {
+			cmd.bot.Msg(cmd.conn, channel, "Only admins can end polls early.")
+			return
+		}
+		if poll == nil {
+			cmd.bot.Msg(cmd.conn, channel, "No poll open in %s.", channel)
+			return
+		}
+
+		if arg == pollClose {
+			cmd.bot.closePoll(cmd.conn, poll)
+			return
+		}
+
+		if _, err := cmd.bot.Polls.ClosePoll(poll.ID, pollCancelled); err != nil {
+			cmd.bot.LogError("PollCommand.Run()", err)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Poll #%d cancelled.", poll.ID)
+
+	default:
+		if poll != nil {
+			cmd.bot.Msg(cmd.conn, channel, "Poll #%d is still open; one at a time.", poll.ID)
+			return
+		}
+
+		question, options, duration, err := parsePoll(arg)
+		if err != nil {
+			cmd.bot.Msg(cmd.conn, channel, "%s", err)
+			return
+		}
+
+		now := time.Now()
+		poll = &Poll{
+			Server:    server,
+			Channel:   channel,
+			Question:  question,
+			Options:   options,
+			Nick:      cmd.line.Nick,
+			Status:    pollOpen,
+			ClosesAt:  now.Add(duration),
+			CreatedAt: now,
+		}
+		if err := cmd.bot.Polls.SavePoll(poll); err != nil {
+			// Another poll was opened since OpenPoll.
+			if err == errPollOpen {
+				cmd.bot.Msg(cmd.conn, channel, "%s", err)
+				return
+			}
+			cmd.bot.LogError("PollCommand.Run()", err)
+			return
+		}
+
+		cmd.bot.Msg(cmd.conn, channel, "%s -- vote with %s <number>, closes %s.", poll, cmdVote, humanize.Time(poll.ClosesAt))
+	}
+}
+
+// Help shows the command help.
+func (cmd *PollCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("PollCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range pollHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+// VoteCommand votes in the channel's open poll.
+type VoteCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewVoteCommand returns a new VoteCommand instance.
+func NewVoteCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *VoteCommand {
+	return &VoteCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *VoteCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("VoteCommand.Run()", err)
+		return
+	}
+
+	if !cmd.line.Public() {
+		cmd.bot.Msg(cmd.conn, cmd.line.Nick, "Vote in the poll's channel.")
+		return
+	}
+
+	if len(args) <= 0 || strings.TrimSpace(args[0]) == "" {
+		cmd.Help()
+		return
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(args[0]))
+	if err != nil {
+		cmd.Help()
+		return
+	}
+
+	poll, err := cmd.bot.Polls.OpenPoll(cmd.conn.Config().Server, channel)
+	if err != nil {
+		cmd.bot.LogError("VoteCommand.Run()", err)
+		return
+	}
+	if poll == nil || !time.Now().Before(poll.ClosesAt) {
+		cmd.bot.Msg(cmd.conn, channel, "No poll open in %s.", channel)
+		return
+	}
+	if choice < 1 || choice > len(poll.Options) {
+		cmd.bot.Msg(cmd.conn, channel, "Pick 1 to %d.", len(poll.Options))
+		return
+	}
+
+	vote := &PollVote{PollID: poll.ID, Voter: pollVoter(cmd.line), Nick: cmd.line.Nick, Choice: choice, CreatedAt: time.Now()}
+	if err := cmd.bot.Polls.SaveVote(vote); err != nil {
+		cmd.bot.LogError("VoteCommand.Run()", err)
+		return
+	}
+
+	// Confirmed privately to keep the channel quiet.
+	cmd.bot.Msg(cmd.conn, cmd.line.Nick, "Your vote for %q in poll #%d is in.", poll.Options[choice-1], poll.ID)
+}
+
+// Help shows the command help.
+func (cmd *VoteCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("VoteCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, voteHelp)
+}
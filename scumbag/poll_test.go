@@ -0,0 +1,73 @@
+package scumbag
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+func TestParsePoll(t *testing.T) {
+	for _, tc := range []struct {
+		args     string
+		question string
+		options  []string
+		duration time.Duration
+		isErr    bool
+	}{
+		{args: `"Lunch?" pizza | tacos | sushi 10m`, question: "Lunch?", options: []string{"pizza", "tacos", "sushi"}, duration: 10 * time.Minute},
+		{args: `"Lunch?" pizza|tacos`, question: "Lunch?", options: []string{"pizza", "tacos"}, duration: pollDefaultDuration},
+		{args: `"Best editor?" vim | emacs | VS Code 2d`, question: "Best editor?", options: []string{"vim", "emacs", "VS Code"}, duration: 48 * time.Hour},
+		{args: `"Release on friday?" yes | no way`, question: "Release on friday?", options: []string{"yes", "no way"}, duration: pollDefaultDuration},
+		{args: `Lunch? pizza | tacos`, isErr: true},
+		{args: `"Lunch? pizza | tacos`, isErr: true},
+		{args: `"" pizza | tacos`, isErr: true},
+		{args: `"Lunch?" pizza`, isErr: true},
+		{args: `"Lunch?" pizza | | tacos`, isErr: true},
+		{args: `"Lunch?" pizza | 10m`, isErr: true},
+		{args: `"Lunch?" pizza | tacos 2w`, isErr: true},
+		{args: `"Count" 1|2|3|4|5|6|7|8|9|10|11`, isErr: true},
+	} {
+		question, options, duration, err := parsePoll(tc.args)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.args)
+			}
+			continue
+		}
+
+		if err != nil || question != tc.question || !reflect.DeepEqual(options, tc.options) || duration != tc.duration {
+			t.Errorf("%s: got %q %q %s, %v", tc.args, question, options, duration, err)
+		}
+	}
+}
+
+func TestPollResults(t *testing.T) {
+	poll := &Poll{ID: 3, Question: "Lunch?", Options: []string{"pizza", "tacos", "sushi"}}
+
+	for _, tc := range []struct {
+		votes    map[int]int
+		expected string
+	}{
+		{map[int]int{1: 3, 2: 1}, `Poll #3 closed: "Lunch?" pizza: 3, tacos: 1, sushi: 0. pizza wins!`},
+		{map[int]int{2: 2, 3: 2}, `Poll #3 closed: "Lunch?" pizza: 0, tacos: 2, sushi: 2. Tie between tacos and sushi.`},
+		{map[int]int{}, `Poll #3 closed: "Lunch?" pizza: 0, tacos: 0, sushi: 0. No votes.`},
+	} {
+		if result := poll.results(tc.votes); result != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, result)
+		}
+	}
+}
+
+func TestPollVoter(t *testing.T) {
+	line := &irc.Line{Nick: "bob", Ident: "~bob", Host: "Example.com"}
+	if voter := pollVoter(line); voter != "host:~bob@example.com" {
+		t.Errorf("Wrong voter: %s", voter)
+	}
+
+	line.Tags = map[string]string{"account": "Bobby"}
+	if voter := pollVoter(line); voter != "account:bobby" {
+		t.Errorf("Wrong voter: %s", voter)
+	}
+}
@@ -0,0 +1,280 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+const (
+	quoteAdd    = "add"
+	quoteRandom = "random"
+	quoteSearch = "search"
+	quoteDelete = "del"
+
+	// Most search results listed.
+	quoteSearchLimit = 5
+)
+
+var quoteHelp = []string{
+	cmdQuote + " " + quoteAdd + " <text> -- save a quote",
+	cmdQuote + " <id> or " + cmdQuote + " " + quoteRandom + " -- show a quote",
+	cmdQuote + " " + quoteSearch + " </pattern/ or text> -- find quotes",
+	cmdQuote + " " + quoteDelete + " <id> -- delete a quote (admins only)",
+}
+
+var grabHelp = cmdGrab + " <nick> -- quote the nick's last line in the channel"
+
+// Quote is a line saved with "<cmdPrefix>quote add" or "<cmdPrefix>grab".
+type Quote struct {
+	ID      int64
+	Server  string
+	Channel string
+	// Who said it, for grabbed quotes; added quotes have no nick.
+	Nick      string
+	Text      string
+	AddedBy   string
+	CreatedAt time.Time
+}
+
+func (quote *Quote) String() string {
+	if quote.Nick != "" {
+		return fmt.Sprintf("#%d: <%s> %s", quote.ID, quote.Nick, quote.Text)
+	}
+	return fmt.Sprintf("#%d: %s", quote.ID, quote.Text)
+}
+
+// searchRegexp compiles "/pattern/" as a case insensitive regexp, and anything else as plain text.
+func searchRegexp(search string) (*regexp.Regexp, error) {
+	if len(search) > 1 && strings.HasPrefix(search, "/") && strings.HasSuffix(search, "/") {
+		return regexp.Compile("(?i)" + search[1:len(search)-1])
+	}
+	return regexp.Compile("(?i)" + regexp.QuoteMeta(search))
+}
+
+// QuoteCommand saves and shows quotes.
+type QuoteCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewQuoteCommand returns a new QuoteCommand instance.
+func NewQuoteCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *QuoteCommand {
+	return &QuoteCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *QuoteCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("QuoteCommand.Run()", err)
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+	if len(fields) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	rest := strings.Join(fields[1:], " ")
+
+	switch fields[0] {
+	case quoteAdd:
+		if rest == "" {
+			cmd.Help()
+			return
+		}
+
+		quote := &Quote{Server: server, Channel: channel, Text: rest, AddedBy: cmd.line.Nick, CreatedAt: time.Now()}
+		if err := cmd.bot.Quotes.SaveQuote(quote); err != nil {
+			cmd.bot.LogError("QuoteCommand.Run()", err)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Saved quote #%d.", quote.ID)
+
+	case quoteRandom:
+		quote, err := cmd.bot.Quotes.RandomQuote(server, channel)
+		if err != nil {
+			cmd.bot.LogError("QuoteCommand.Run()", err)
+			return
+		}
+		if quote == nil {
+			cmd.bot.Msg(cmd.conn, channel, "No quotes in %s yet.", channel)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s", quote)
+
+	case quoteSearch:
+		cmd.search(channel, rest)
+
+	case quoteDelete:
+		if !cmd.bot.Admin(cmd.line.Nick) {
+			cmd.bot.Msg(cmd.conn, channel, "Only admins can delete quotes.")
+			return
+		}
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(rest, "#"), 10, 64)
+		if err != nil {
+			cmd.Help()
+			return
+		}
+
+		deleted, err := cmd.bot.Quotes.DeleteQuote(server, channel, id)
+		if err != nil {
+			cmd.bot.LogError("QuoteCommand.Run()", err)
+			return
+		}
+		if !deleted {
+			cmd.bot.Msg(cmd.conn, channel, "No quote #%d.", id)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Deleted quote #%d.", id)
+
+	default:
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "#"), 10, 64)
+		if err != nil || len(fields) > 1 {
+			cmd.Help()
+			return
+		}
+
+		quote, err := cmd.bot.Quotes.GetQuote(server, channel, id)
+		if err != nil {
+			cmd.bot.LogError("QuoteCommand.Run()", err)
+			return
+		}
+		if quote == nil {
+			cmd.bot.Msg(cmd.conn, channel, "No quote #%d.", id)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s", quote)
+	}
+}
+
+// search lists the channel's quotes matching `search`, newest first.
+func (cmd *QuoteCommand) search(channel, search string) {
+	if search == "" {
+		cmd.Help()
+		return
+	}
+
+	pattern, err := searchRegexp(search)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "Invalid pattern: %s", err)
+		return
+	}
+
+	quotes, err := cmd.bot.Quotes.Quotes(cmd.conn.Config().Server, channel)
+	if err != nil {
+		cmd.bot.LogError("QuoteCommand.search()", err)
+		return
+	}
+
+	var matches []*Quote
+	for i := len(quotes) - 1; i >= 0; i-- {
+		if pattern.MatchString(quotes[i].Text) || pattern.MatchString(quotes[i].Nick) {
+			matches = append(matches, quotes[i])
+		}
+	}
+
+	if len(matches) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "No quotes found.")
+		return
+	}
+
+	for i, quote := range matches {
+		if i >= quoteSearchLimit {
+			cmd.bot.Msg(cmd.conn, channel, "...and %d more.", len(matches)-quoteSearchLimit)
+			break
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s", quote)
+	}
+}
+
+// Help shows the command help.
+func (cmd *QuoteCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("QuoteCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range quoteHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+// GrabCommand quotes a nick's last line in the channel.
+type GrabCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewGrabCommand returns a new GrabCommand instance.
+func NewGrabCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *GrabCommand {
+	return &GrabCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *GrabCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("GrabCommand.Run()", err)
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+	if len(fields) != 1 {
+		cmd.Help()
+		return
+	}
+
+	nick := fields[0]
+	if strings.EqualFold(nick, cmd.line.Nick) {
+		cmd.bot.Msg(cmd.conn, channel, "Grabbing yourself in public? Gross.")
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	last := cmd.bot.recentLines.last(server, channel, nick)
+	if last == nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s hasn't said anything lately.", nick)
+		return
+	}
+
+	quote := &Quote{Server: server, Channel: channel, Nick: last.Nick, Text: last.Text, AddedBy: cmd.line.Nick, CreatedAt: time.Now()}
+	if err := cmd.bot.Quotes.SaveQuote(quote); err != nil {
+		cmd.bot.LogError("GrabCommand.Run()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "Grabbed %s", quote)
+}
+
+// Help shows the command help.
+func (cmd *GrabCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("GrabCommand.Help()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, grabHelp)
+}
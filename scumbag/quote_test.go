@@ -0,0 +1,41 @@
+package scumbag
+
+import (
+	"testing"
+)
+
+func TestSearchRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		search  string
+		text    string
+		matches bool
+	}{
+		{"/^foo.*bar$/", "Foo and bar", true},
+		{"/^foo.*bar$/", "a foo and bar", false},
+		{"foo.*bar", "Foo and bar", false},
+		{"foo.*bar", "it's FOO.*BAR", true},
+		{"/", "a / b", true},
+	} {
+		pattern, err := searchRegexp(tc.search)
+		if err != nil {
+			t.Errorf("%q: %s", tc.search, err)
+			continue
+		}
+		if pattern.MatchString(tc.text) != tc.matches {
+			t.Errorf("%q matching %q: expected %v", tc.search, tc.text, tc.matches)
+		}
+	}
+
+	if _, err := searchRegexp("/(/"); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	if actual := (&Quote{ID: 3, Nick: "bob", Text: "hi"}).String(); actual != "#3: <bob> hi" {
+		t.Errorf("Wrong grabbed quote: %s", actual)
+	}
+	if actual := (&Quote{ID: 4, Text: "<bob> hi"}).String(); actual != "#4: <bob> hi" {
+		t.Errorf("Wrong added quote: %s", actual)
+	}
+}
@@ -0,0 +1,75 @@
+package scumbag
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+// Lines kept for each channel.
+const recentLinesMax = 100
+
+// recentLine is a channel message kept in memory.
+type recentLine struct {
+	Nick string
+	Text string
+	Time time.Time
+}
+
+// recentLines keeps the last recentLinesMax messages in each channel.
+type recentLines struct {
+	sync.Mutex
+	channels map[string][]*recentLine
+}
+
+func newRecentLines() *recentLines {
+	return &recentLines{channels: make(map[string][]*recentLine)}
+}
+
+// add keeps `line`, dropping the channel's oldest line if it's full.
+func (r *recentLines) add(server, channel string, line *recentLine) {
+	r.Lock()
+	defer r.Unlock()
+
+	key := server + " " + channel
+	lines := r.channels[key]
+	if len(lines) >= recentLinesMax {
+		lines = lines[1:]
+	}
+	r.channels[key] = append(lines, line)
+}
+
+// last returns the newest line `nick` said in the channel, or nil.
+func (r *recentLines) last(server, channel, nick string) *recentLine {
+	lines := r.nickLines(server, channel, nick, 1)
+	if len(lines) <= 0 {
+		return nil
+	}
+	return lines[0]
+}
+
+// nickLines returns up to `limit` of the lines `nick` said in the channel, newest first.
+func (r *recentLines) nickLines(server, channel, nick string, limit int) []*recentLine {
+	r.Lock()
+	defer r.Unlock()
+
+	var found []*recentLine
+	lines := r.channels[server+" "+channel]
+	for i := len(lines) - 1; i >= 0 && len(found) < limit; i-- {
+		if strings.EqualFold(lines[i].Nick, nick) {
+			found = append(found, lines[i])
+		}
+	}
+	return found
+}
+
+// rememberLine keeps channel messages that aren't bot commands or corrections.
+func (bot *Scumbag) rememberLine(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) <= 1 || !line.Public() || strings.HasPrefix(line.Args[1], cmdPrefix) || correctionRegexp.MatchString(line.Args[1]) {
+		return
+	}
+
+	bot.recentLines.add(conn.Config().Server, line.Target(), &recentLine{Nick: line.Nick, Text: line.Args[1], Time: line.Time})
+}
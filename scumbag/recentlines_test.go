@@ -0,0 +1,34 @@
+package scumbag
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRecentLines(t *testing.T) {
+	lines := newRecentLines()
+
+	lines.add("irc.example.com", "#scumbag", &recentLine{Nick: "bob", Text: "first"})
+	lines.add("irc.example.com", "#scumbag", &recentLine{Nick: "Bob", Text: "second"})
+	lines.add("irc.example.com", "#other", &recentLine{Nick: "bob", Text: "elsewhere"})
+
+	if last := lines.last("irc.example.com", "#scumbag", "BOB"); last == nil || last.Text != "second" {
+		t.Errorf("Wrong last line: %+v", last)
+	}
+	if last := lines.last("irc.example.com", "#scumbag", "alice"); last != nil {
+		t.Errorf("Expected no line, got %+v", last)
+	}
+	if nickLines := lines.nickLines("irc.example.com", "#scumbag", "bob", 5); len(nickLines) != 2 || nickLines[0].Text != "second" || nickLines[1].Text != "first" {
+		t.Errorf("Wrong nick lines: %+v", nickLines)
+	}
+
+	for i := 0; i < recentLinesMax; i++ {
+		lines.add("irc.example.com", "#scumbag", &recentLine{Nick: "alice", Text: fmt.Sprint(i)})
+	}
+	if last := lines.last("irc.example.com", "#scumbag", "bob"); last != nil {
+		t.Errorf("Expected old lines to be dropped, got %+v", last)
+	}
+	if n := len(lines.channels["irc.example.com #scumbag"]); n != recentLinesMax {
+		t.Errorf("Expected %d lines, got %d", recentLinesMax, n)
+	}
+}
@@ -1,24 +1,43 @@
 package scumbag
 
 import (
+	"encoding/json"
 	"errors"
-	"math/rand"
+	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
-	"time"
 
 	irc "github.com/fluffle/goirc/client"
 	"github.com/jzelinskie/geddit"
 )
 
 const (
-	redditHelp = cmdPrefix + "reddit <subreddit>"
+	redditByIDURL = "https://www.reddit.com/by_id/t3_%s.json"
+	redditHelp    = cmdPrefix + "reddit <subreddit>"
 )
 
 var (
 	selfPostRegexp = regexp.MustCompile(`\Aself\.`)
 )
 
+func init() {
+	RegisterUnfurler(&Unfurler{
+		Name:   "reddit",
+		Match:  regexp.MustCompile(`\Ahttps?://(?:(?:www|old|np)\.)?(?:reddit\.com/r/\w+/comments|redd\.it)/(\w+)`),
+		Format: unfurlReddit,
+	})
+}
+
+// redditListing is the envelope around submissions returned from the JSON API.
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data geddit.Submission `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
 // RedditCommand interacts with the Reddit API.
 type RedditCommand struct {
 	BaseCommand
@@ -116,10 +135,8 @@ func (cmd *RedditCommand) randomSubredditSubmission(subreddit string) {
 		return
 	}
 
-	rand.Seed(time.Now().Unix())
-
 	if len(submissions) > 0 {
-		submission := submissions[rand.Intn(len(submissions))]
+		submission := submissions[rng.Intn(len(submissions))]
 		cmd.msg(submission)
 	}
 }
@@ -135,3 +152,37 @@ func (cmd *RedditCommand) msg(submission *geddit.Submission) {
 	url := strings.Replace(submission.URL, "&amp;", "&", -1)
 	cmd.bot.Msg(cmd.conn, channel, url)
 }
+
+func (cmd *RedditCommand) getSubmission(id string) (*geddit.Submission, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf(redditByIDURL, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Reddit throttles the default Go user agent.
+	req.Header.Set("User-Agent", VersionString())
+
+	content, err := getContentBytes(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var listing redditListing
+	if err := json.Unmarshal(content, &listing); err != nil {
+		return nil, err
+	}
+
+	if len(listing.Data.Children) <= 0 {
+		return nil, errors.New("Submission not found: " + id)
+	}
+
+	return &listing.Data.Children[0].Data, nil
+}
+
+func unfurlReddit(bot *Scumbag, conn *irc.Conn, line *irc.Line, match []string) (string, error) {
+	submission, err := NewRedditCommand(bot, conn, line).getSubmission(match[1])
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("r/%s: %s", submission.Subreddit, submission.String()), nil
+}
@@ -0,0 +1,500 @@
+package scumbag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// How often due reminders are checked for.
+	reminderInterval = 15 * time.Second
+
+	reminderDefaultQuota = 10
+
+	reminderClockFormat = "15:04"
+	reminderTimeFormat  = "Mon Jan 2 15:04 MST"
+
+	reminderMe = "me"
+
+	remindersCancel = "cancel"
+)
+
+// Reminder repeats; any lowercase weekday name ("monday") also works.
+const (
+	reminderDaily    = "day"
+	reminderWeekdays = "weekday"
+	reminderWeekends = "weekend"
+)
+
+var remindHelp = []string{
+	cmdRemind + " <me|#channel> in <20m/2h/3d/1w> <message> -- remind once after a while",
+	cmdRemind + " <me|#channel> at <17:00> [timezone] <message> -- remind once at the next 17:00",
+	cmdRemind + " <me|#channel> every <day/weekday/weekend/monday> <09:30> [timezone] <message> -- remind repeatedly",
+}
+
+var remindersHelp = []string{
+	cmdReminders + " -- list the reminders you set",
+	cmdReminders + " " + remindersCancel + " <id> -- cancel one of them",
+}
+
+var reminderDurationRegexp = regexp.MustCompile(`\A(\d+)([dw])\z`)
+
+// Reminder is a message sent to a channel or nick at RemindAt, and again
+// after that if it repeats.
+type Reminder struct {
+	ID     int64
+	Server string
+	// Where the reminder is sent: a channel, or Nick for private reminders.
+	Channel string
+	// Who set it.
+	Nick string
+	// Mention addresses the message to Nick, for "me" reminders.
+	Mention bool
+	Message string
+	// Location name the reminder's clock times are in, e.g. "Europe/London".
+	Timezone string
+	// How the reminder repeats, or empty if it doesn't.
+	Repeat    string
+	RemindAt  time.Time
+	CreatedAt time.Time
+}
+
+// Location returns the reminder's timezone, or local time if it isn't valid.
+func (reminder *Reminder) Location() *time.Location {
+	loc, err := time.LoadLocation(reminder.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// String describes when and where the reminder goes off, for listing.
+func (reminder *Reminder) String() string {
+	remindAt := reminder.RemindAt.In(reminder.Location())
+
+	when := remindAt.Format(reminderTimeFormat)
+	if reminder.Repeat != "" {
+		when = fmt.Sprintf("every %s %s %s (next %s)", reminder.Repeat, remindAt.Format(reminderClockFormat), reminder.Timezone, humanize.Time(reminder.RemindAt))
+	}
+
+	return fmt.Sprintf("#%d %s in %s: %s", reminder.ID, when, reminder.Channel, reminder.Message)
+}
+
+// parseReminder parses "<me|#channel> <in|at|every> ... <message>". Times
+// are in `loc` unless a timezone follows the clock time.
+func parseReminder(args string, now time.Time, loc *time.Location) (*Reminder, error) {
+	fields := strings.Fields(args)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("Usage: %s <me|#channel> <in|at|every> ... <message>", cmdRemind)
+	}
+
+	reminder := &Reminder{Timezone: loc.String()}
+	if strings.EqualFold(fields[0], reminderMe) {
+		reminder.Mention = true
+	} else if strings.HasPrefix(fields[0], "#") {
+		reminder.Channel = fields[0]
+	} else {
+		return nil, fmt.Errorf("Reminders are for \"%s\" or a #channel, not %s", reminderMe, fields[0])
+	}
+
+	// parseClock parses the clock time at fields[i] and an optional timezone
+	// after it, and returns the index of the message.
+	var clock time.Time
+	parseClock := func(i int) (int, error) {
+		parsed, err := time.Parse(reminderClockFormat, fields[i])
+		if err != nil {
+			return 0, fmt.Errorf("Invalid time: %s (use 24 hour HH:MM)", fields[i])
+		}
+
+		if i+2 < len(fields) && reminderTimezone(fields[i+1]) {
+			if tz, err := time.LoadLocation(fields[i+1]); err == nil {
+				loc = tz
+				reminder.Timezone = tz.String()
+				i++
+			}
+		}
+
+		clock = time.Date(0, 1, 1, parsed.Hour(), parsed.Minute(), 0, 0, loc)
+		return i + 1, nil
+	}
+
+	var message int
+	switch strings.ToLower(fields[1]) {
+	case "in":
+		duration, err := parseReminderDuration(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		reminder.RemindAt = now.Add(duration)
+		message = 3
+
+	case "at":
+		i, err := parseClock(2)
+		if err != nil {
+			return nil, err
+		}
+		reminder.RemindAt = nextReminderTime(reminderDaily, clock, now)
+		message = i
+
+	case "every":
+		repeat := strings.ToLower(fields[2])
+		if !validReminderRepeat(repeat) {
+			return nil, fmt.Errorf("Invalid repeat: %s (use day, weekday, weekend or a day name)", fields[2])
+		}
+
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("Usage: %s <me|#channel> every %s <HH:MM> [timezone] <message>", cmdRemind, repeat)
+		}
+
+		i, err := parseClock(3)
+		if err != nil {
+			return nil, err
+		}
+		reminder.Repeat = repeat
+		reminder.RemindAt = nextReminderTime(repeat, clock, now)
+		message = i
+
+	default:
+		return nil, fmt.Errorf("Usage: %s <me|#channel> <in|at|every> ... <message>", cmdRemind)
+	}
+
+	if message >= len(fields) {
+		return nil, fmt.Errorf("What should I remind you about?")
+	}
+	reminder.Message = strings.Join(fields[message:], " ")
+
+	return reminder, nil
+}
+
+// reminderTimezone returns whether `word` after a clock time is meant as a
+// timezone. Only "Area/City" names and UTC are, so messages starting with a
+// word like "Japan" or "EST" aren't taken for one.
+func reminderTimezone(word string) bool {
+	return word == "UTC" || strings.Contains(word, "/")
+}
+
+// parseReminderDuration parses a Go duration (20m, 1h30m) or a number of days or weeks (3d, 1w).
+func parseReminderDuration(value string) (time.Duration, error) {
+	if match := reminderDurationRegexp.FindStringSubmatch(value); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		if match[2] == "w" {
+			n *= 7
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("Invalid duration: %s", value)
+	}
+	return duration, nil
+}
+
+func validReminderRepeat(repeat string) bool {
+	switch repeat {
+	case reminderDaily, reminderWeekdays, reminderWeekends:
+		return true
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if repeat == strings.ToLower(day.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextReminderTime returns the first time after `after` on a day matching
+// `repeat`, at the clock time of `clock` in its location.
+func nextReminderTime(repeat string, clock, after time.Time) time.Time {
+	loc := clock.Location()
+	after = after.In(loc)
+
+	for day := 0; day <= 7; day++ {
+		next := time.Date(after.Year(), after.Month(), after.Day()+day, clock.Hour(), clock.Minute(), 0, 0, loc)
+		if !next.After(after) {
+			continue
+		}
+
+		weekday := next.Weekday()
+		switch repeat {
+		case reminderDaily:
+			return next
+		case reminderWeekdays:
+			if weekday != time.Saturday && weekday != time.Sunday {
+				return next
+			}
+		case reminderWeekends:
+			if weekday == time.Saturday || weekday == time.Sunday {
+				return next
+			}
+		default:
+			if repeat == strings.ToLower(weekday.String()) {
+				return next
+			}
+		}
+	}
+
+	// Only an invalid repeat gets here; don't fire it in a loop.
+	return after.AddDate(1, 0, 0)
+}
+
+// startReminders sends due reminders until the bot quits.
+func (bot *Scumbag) startReminders() {
+	go func() {
+		ticker := time.NewTicker(reminderInterval)
+		defer ticker.Stop()
+
+		for {
+			bot.sendReminders(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-bot.quit:
+				return
+			}
+		}
+	}()
+}
+
+// sendReminders sends the reminders due at `now` and reschedules repeating ones.
+// Reminders missed while the bot was down are sent late. Each is deleted or
+// rescheduled before it's sent, so one that can't be isn't sent every interval.
+func (bot *Scumbag) sendReminders(now time.Time) {
+	if bot.databaseDown() {
+		return
+	}
+
+	reminders, err := bot.Reminders.DueReminders(now)
+	if err != nil {
+		if !bot.checkDatabase(err) {
+			bot.LogError("sendReminders()", err)
+		}
+		return
+	}
+
+	for _, reminder := range reminders {
+		conn, ok := bot.ircClients[reminder.Server]
+		if !ok || !conn.Connected() {
+			// Sent once the server is back.
+			continue
+		}
+
+		if reminder.Repeat != "" {
+			next := nextReminderTime(reminder.Repeat, reminder.RemindAt.In(reminder.Location()), now)
+			err = bot.Reminders.RescheduleReminder(reminder.ID, next)
+		} else {
+			err = bot.Reminders.DeleteReminder(reminder.ID)
+		}
+		if err != nil {
+			if bot.checkDatabase(err) {
+				return
+			}
+			bot.LogError("sendReminders()", err)
+			continue
+		}
+
+		if reminder.Mention {
+			bot.Msg(conn, reminder.Channel, "%s: %s", reminder.Nick, reminder.Message)
+		} else {
+			bot.Msg(conn, reminder.Channel, "Reminder from %s: %s", reminder.Nick, reminder.Message)
+		}
+		bot.Log.WithFields(log.Fields{"id": reminder.ID, "server": reminder.Server, "channel": reminder.Channel}).Debug("sendReminders()")
+	}
+}
+
+// reminderQuota returns the most pending reminders one nick can set.
+func (bot *Scumbag) reminderQuota() int {
+	if bot.Config.Reminders == nil || bot.Config.Reminders.Quota <= 0 {
+		return reminderDefaultQuota
+	}
+	return bot.Config.Reminders.Quota
+}
+
+// RemindCommand sets a reminder.
+type RemindCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewRemindCommand returns a new RemindCommand instance.
+func NewRemindCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *RemindCommand {
+	return &RemindCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *RemindCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RemindCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 || args[0] == "" {
+		cmd.Help()
+		return
+	}
+
+	now := time.Now()
+	reminder, err := parseReminder(args[0], now, time.Local)
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s", err)
+		return
+	}
+
+	server := cmd.conn.Config().Server
+	reminder.Server = server
+	reminder.Nick = cmd.line.Nick
+	reminder.CreatedAt = now
+
+	if reminder.Mention {
+		// Reminders set in private are sent in private.
+		reminder.Channel = channel
+		if !cmd.line.Public() {
+			reminder.Channel = cmd.line.Nick
+		}
+	} else {
+		// Only admins can send reminders to a channel from anywhere else.
+		inChannel := cmd.line.Public() && strings.EqualFold(reminder.Channel, channel)
+		if !inChannel && !cmd.bot.Admin(cmd.line.Nick) {
+			cmd.bot.Msg(cmd.conn, channel, "Set reminders for %s in %s.", reminder.Channel, reminder.Channel)
+			return
+		}
+
+		serverConfig, err := cmd.bot.Config.Server(server)
+		if err != nil {
+			cmd.bot.LogError("RemindCommand.Run()", err)
+			return
+		}
+		if _, ok := serverConfig.Channels[reminder.Channel]; !ok {
+			cmd.bot.Msg(cmd.conn, channel, "I'm not in %s.", reminder.Channel)
+			return
+		}
+	}
+
+	pending, err := cmd.bot.Reminders.NickReminders(server, reminder.Nick)
+	if err != nil {
+		cmd.bot.LogError("RemindCommand.Run()", err)
+		return
+	}
+
+	if quota := cmd.bot.reminderQuota(); len(pending) >= quota {
+		cmd.bot.Msg(cmd.conn, channel, "You already have %d reminders set; cancel one with %s %s <id>", len(pending), cmdReminders, remindersCancel)
+		return
+	}
+
+	if err := cmd.bot.Reminders.SaveReminder(reminder); err != nil {
+		cmd.bot.LogError("RemindCommand.Run()", err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "OK, reminder #%d is set for %s.", reminder.ID, reminder.RemindAt.In(reminder.Location()).Format(reminderTimeFormat))
+}
+
+// Help shows the command help.
+func (cmd *RemindCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RemindCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range remindHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
+
+// RemindersCommand lists or cancels the reminders a nick set.
+type RemindersCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewRemindersCommand returns a new RemindersCommand instance.
+func NewRemindersCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *RemindersCommand {
+	return &RemindersCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *RemindersCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RemindersCommand.Run()", err)
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+
+	server := cmd.conn.Config().Server
+	nick := cmd.line.Nick
+
+	if len(fields) > 0 {
+		if fields[0] != remindersCancel || len(fields) != 2 {
+			cmd.Help()
+			return
+		}
+
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			cmd.Help()
+			return
+		}
+
+		cancelled, err := cmd.bot.Reminders.CancelReminder(server, nick, id)
+		if err != nil {
+			cmd.bot.LogError("RemindersCommand.Run()", err)
+			return
+		}
+
+		if !cancelled {
+			cmd.bot.Msg(cmd.conn, channel, "You have no reminder #%d.", id)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Cancelled reminder #%d.", id)
+		return
+	}
+
+	reminders, err := cmd.bot.Reminders.NickReminders(server, nick)
+	if err != nil {
+		cmd.bot.LogError("RemindersCommand.Run()", err)
+		return
+	}
+
+	if len(reminders) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "You have no reminders.")
+		return
+	}
+
+	for _, reminder := range reminders {
+		cmd.bot.Msg(cmd.conn, nick, "%s", reminder)
+	}
+}
+
+// Help shows the command help.
+func (cmd *RemindersCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("RemindersCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range remindersHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
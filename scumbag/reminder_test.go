@@ -0,0 +1,183 @@
+package scumbag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReminder(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("No timezone data: %s", err)
+	}
+
+	// A Friday.
+	now := time.Date(2020, 3, 13, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		args     string
+		expected *Reminder
+	}{
+		{"me in 20m deploy check", &Reminder{Mention: true, Message: "deploy check", Timezone: "UTC", RemindAt: now.Add(20 * time.Minute)}},
+		{"ME in 2d renew", &Reminder{Mention: true, Message: "renew", Timezone: "UTC", RemindAt: now.AddDate(0, 0, 2)}},
+		{"#scumbag at 17:00 standup", &Reminder{Channel: "#scumbag", Message: "standup", Timezone: "UTC", RemindAt: time.Date(2020, 3, 13, 17, 0, 0, 0, time.UTC)}},
+		{"#scumbag at 09:00 standup", &Reminder{Channel: "#scumbag", Message: "standup", Timezone: "UTC", RemindAt: time.Date(2020, 3, 14, 9, 0, 0, 0, time.UTC)}},
+		{"me at 12:00 Europe/London lunch time", &Reminder{Mention: true, Message: "lunch time", Timezone: "Europe/London", RemindAt: time.Date(2020, 3, 14, 12, 0, 0, 0, london)}},
+		{"me every weekday 09:30 standup", &Reminder{Mention: true, Message: "standup", Timezone: "UTC", Repeat: reminderWeekdays, RemindAt: time.Date(2020, 3, 16, 9, 30, 0, 0, time.UTC)}},
+		{"me every Saturday 09:30 Europe/London coffee", &Reminder{Mention: true, Message: "coffee", Timezone: "Europe/London", Repeat: "saturday", RemindAt: time.Date(2020, 3, 14, 9, 30, 0, 0, london)}},
+		// Zone names without a "/" are part of the message.
+		{"me at 17:00 Japan trip", &Reminder{Mention: true, Message: "Japan trip", Timezone: "UTC", RemindAt: time.Date(2020, 3, 13, 17, 0, 0, 0, time.UTC)}},
+		{"#scumbag every day 09:00 EST is over", &Reminder{Channel: "#scumbag", Message: "EST is over", Timezone: "UTC", Repeat: reminderDaily, RemindAt: time.Date(2020, 3, 14, 9, 0, 0, 0, time.UTC)}},
+		{"me at 17:00 Local call", &Reminder{Mention: true, Message: "Local call", Timezone: "UTC", RemindAt: time.Date(2020, 3, 13, 17, 0, 0, 0, time.UTC)}},
+		{"me at 17:00 Nowhere/Special cake", &Reminder{Mention: true, Message: "Nowhere/Special cake", Timezone: "UTC", RemindAt: time.Date(2020, 3, 13, 17, 0, 0, 0, time.UTC)}},
+		// A timezone needs a message after it.
+		{"me at 17:00 UTC", &Reminder{Mention: true, Message: "UTC", Timezone: "UTC", RemindAt: time.Date(2020, 3, 13, 17, 0, 0, 0, time.UTC)}},
+	} {
+		reminder, err := parseReminder(tc.args, now, time.UTC)
+		if err != nil {
+			t.Errorf("%q: %s", tc.args, err)
+			continue
+		}
+
+		if reminder.Mention != tc.expected.Mention || reminder.Channel != tc.expected.Channel || reminder.Message != tc.expected.Message ||
+			reminder.Timezone != tc.expected.Timezone || reminder.Repeat != tc.expected.Repeat || !reminder.RemindAt.Equal(tc.expected.RemindAt) {
+			t.Errorf("%q: expected %+v, got %+v", tc.args, tc.expected, reminder)
+		}
+	}
+
+	for _, args := range []string{
+		"",
+		"me in 20m",
+		"bob in 20m hi",
+		"me in soon hi",
+		"me in -5m hi",
+		"me at 25:00 hi",
+		"me at 5pm hi",
+		"me every fortnight 09:00 hi",
+		"me every day hi",
+		"me sometime 09:00 hi",
+	} {
+		if reminder, err := parseReminder(args, now, time.UTC); err == nil {
+			t.Errorf("%q: expected an error, got %+v", args, reminder)
+		}
+	}
+}
+
+func TestNextReminderTime(t *testing.T) {
+	// A Friday.
+	after := time.Date(2020, 3, 13, 9, 30, 0, 0, time.UTC)
+	clock := time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		repeat   string
+		expected time.Time
+	}{
+		{reminderDaily, time.Date(2020, 3, 14, 9, 30, 0, 0, time.UTC)},
+		{reminderWeekdays, time.Date(2020, 3, 16, 9, 30, 0, 0, time.UTC)},
+		{reminderWeekends, time.Date(2020, 3, 14, 9, 30, 0, 0, time.UTC)},
+		{"friday", time.Date(2020, 3, 20, 9, 30, 0, 0, time.UTC)},
+		{"tuesday", time.Date(2020, 3, 17, 9, 30, 0, 0, time.UTC)},
+	} {
+		if actual := nextReminderTime(tc.repeat, clock, after); !actual.Equal(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", tc.repeat, tc.expected, actual)
+		}
+	}
+}
+
+// unsavedReminders fails to delete or reschedule reminders.
+type unsavedReminders struct {
+	*MemoryStore
+}
+
+func (store unsavedReminders) DeleteReminder(id int64) error {
+	return errors.New("Database is read only.")
+}
+
+func (store unsavedReminders) RescheduleReminder(id int64, remindAt time.Time) error {
+	return errors.New("Database is read only.")
+}
+
+func TestSendReminders(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := newTestConn(t, bot)
+
+	store := NewMemoryStore()
+	now := time.Now()
+	for _, reminder := range []*Reminder{
+		{Channel: "#scumbag", Nick: "alice", Message: "standup", RemindAt: now.Add(-time.Minute)},
+		{Channel: "#scumbag", Nick: "bob", Message: "coffee", Repeat: reminderDaily, RemindAt: now.Add(-time.Minute)},
+		{Channel: "#scumbag", Nick: "alice", Message: "later", RemindAt: now.Add(time.Hour)},
+	} {
+		reminder.Server = conn.Config().Server
+		reminder.Timezone = "UTC"
+		if err := store.SaveReminder(reminder); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The test database doesn't answer, so it's marked down.
+	bot.Reminders = unsavedReminders{store}
+	bot.sendReminders(now)
+	if said := conn.said(t); len(said) != 0 {
+		t.Errorf("Expected reminders that can't be saved not to be sent, got %q", said)
+	}
+	if !bot.databaseDown() {
+		t.Error("Expected the database to be marked down")
+	}
+
+	bot.db = nil
+	bot.dbHealth.down = false
+	bot.sendReminders(now)
+	if said := conn.said(t); len(said) != 0 {
+		t.Errorf("Expected reminders that can't be saved not to be sent, got %q", said)
+	}
+
+	bot.Reminders = store
+	bot.sendReminders(now)
+	said := conn.said(t)
+	if len(said) != 2 || said[0] != "#scumbag Reminder from alice: standup" || said[1] != "#scumbag Reminder from bob: coffee" {
+		t.Errorf("Expected the due reminders, got %q", said)
+	}
+
+	bot.sendReminders(now)
+	if said := conn.said(t); len(said) != 0 {
+		t.Errorf("Expected reminders to be sent once, got %q", said)
+	}
+}
+
+func TestRemindCommand(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Reminders = NewMemoryStore()
+	bot.Config.Reminders = &ReminderConfig{Quota: 2}
+	conn := newTestConn(t, bot)
+	bot.Config.Servers[0].Server = conn.Config().Server
+
+	run := func(nick, target, args string) []string {
+		NewRemindCommand(bot, conn.Conn, testLine(nick, target, cmdRemind+" "+args)).Run(args)
+		return conn.said(t)
+	}
+
+	for _, tc := range []struct {
+		nick, target, args string
+		expected           string
+	}{
+		{"alice", "#scumbag", "#scumbag in 1h standup", "#scumbag OK, reminder #1 is set for "},
+		{"alice", "#scumbag", "#scumbag_two in 1h hi", "#scumbag Set reminders for #scumbag_two in #scumbag_two."},
+		{"alice", "scumbag", "#scumbag in 1h hi", "scumbag Set reminders for #scumbag in #scumbag."},
+		{"admin_nick", "scumbag", "#scumbag_two in 1h hi", "scumbag OK, reminder #2 is set for "},
+		{"alice", "#scumbag", "me in 3h lunch", "#scumbag OK, reminder #3 is set for "},
+		{"alice", "#scumbag", "me in 4h dinner", "#scumbag You already have 2 reminders set; cancel one with " + cmdReminders + " " + remindersCancel + " <id>"},
+	} {
+		if said := run(tc.nick, tc.target, tc.args); len(said) != 1 || !strings.HasPrefix(said[0], tc.expected) {
+			t.Errorf("%s %q: expected %q, got %q", tc.nick, tc.args, tc.expected, said)
+		}
+	}
+}
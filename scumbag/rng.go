@@ -0,0 +1,55 @@
+package scumbag
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rng is the random number generator everything shares, seeded once at startup.
+var rng = newLockedRand(randomSeed())
+
+// lockedRand is a math/rand generator that's safe to use from many goroutines.
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+// randomSeed returns a seed from crypto/rand, or the time if that fails.
+func randomSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// Intn returns a number in [0, n).
+func (l *lockedRand) Intn(n int) int {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.r.Intn(n)
+}
+
+// Perm returns a random permutation of [0, n).
+func (l *lockedRand) Perm(n int) []int {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.r.Perm(n)
+}
+
+// Shuffle shuffles n items with `swap`.
+func (l *lockedRand) Shuffle(n int, swap func(i, j int)) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.r.Shuffle(n, swap)
+}
@@ -1,6 +1,7 @@
 package scumbag
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -36,21 +37,43 @@ const (
 	cmdPrefix = "?"
 
 	cmdAdmin      = cmdPrefix + "admin"
+	cmdCalc       = cmdPrefix + "calc"
+	cmdChoose     = cmdPrefix + "choose"
+	cmdConvert    = cmdPrefix + "convert"
 	cmdCorona     = cmdPrefix + "corona"
+	cmdEightBall  = cmdPrefix + "8ball"
+	cmdFactoid    = cmdPrefix + "factoid"
 	cmdFiglet     = cmdPrefix + "fig"
+	cmdFlip       = cmdPrefix + "flip"
+	cmdForget     = cmdPrefix + "forget"
 	cmdGame       = cmdPrefix + "game"
 	cmdGithub     = cmdPrefix + "gh"
+	cmdGrab       = cmdPrefix + "grab"
+	cmdGrep       = cmdPrefix + "grep"
 	cmdHackerNews = cmdPrefix + "hn"
 	cmdHelp       = cmdPrefix + "help"
+	cmdKarma      = cmdPrefix + "karma"
+	cmdLearn      = cmdPrefix + "learn"
+	cmdMemos      = cmdPrefix + "memos"
 	cmdMovie      = cmdPrefix + "movie"
 	cmdNews       = cmdPrefix + "news"
+	cmdPoll       = cmdPrefix + "poll"
+	cmdQuote      = cmdPrefix + "quote"
 	cmdReddit     = cmdPrefix + "reddit"
+	cmdRemind     = cmdPrefix + "remind"
+	cmdReminders  = cmdPrefix + "reminders"
+	cmdRoll       = cmdPrefix + "roll"
+	cmdSeen       = cmdPrefix + "seen"
 	cmdSpell      = cmdPrefix + "sp"
+	cmdTell       = cmdPrefix + "tell"
+	cmdTrivia     = cmdPrefix + "trivia"
 	cmdTwitter    = cmdPrefix + "twitter"
 	cmdURL        = cmdPrefix + "url"
+	cmdURLStats   = cmdPrefix + "urlstats"
 	cmdUptime     = cmdPrefix + "uptime"
 	cmdUrbanDict  = cmdPrefix + "ud"
 	cmdVersion    = cmdPrefix + "version"
+	cmdVote       = cmdPrefix + "vote"
 	cmdWeather    = cmdPrefix + "weather"
 	cmdWiki       = cmdPrefix + "wp"
 	cmdWolfram    = cmdPrefix + "wolfram"
@@ -71,16 +94,34 @@ func VersionString() string {
 type Scumbag struct {
 	Environment string
 
-	Config  *BotConfig
-	DB      *sql.DB
-	Log     *log.Logger
-	News    *newsapi.Client
-	Reddit  *geddit.Session
-	Twitter *twitter.Client
-
-	ircClients   map[string]*irc.Conn
-	disconnected map[string]chan struct{}
-	startTime    time.Time
+	Config     *BotConfig
+	Links      LinkStore
+	Ignores    IgnoreStore
+	Seen       SeenStore
+	Memos      MemoStore
+	Reminders  ReminderStore
+	Karma      KarmaStore
+	Quotes     QuoteStore
+	Factoids   FactoidStore
+	ChannelLog ChannelLogStore
+	Polls      PollStore
+	Trivia     TriviaStore
+	Log        *log.Logger
+	News       *newsapi.Client
+	Reddit     *geddit.Session
+	Twitter    *twitter.Client
+
+	db            *sql.DB
+	ircClients    map[string]*irc.Conn
+	disconnected  map[string]chan struct{}
+	startTime     time.Time
+	quit          chan struct{}
+	archiveServer *http.Server
+	forgetMe      *confirmations
+	dbHealth      *dbHealth
+	recentLines   *recentLines
+	triviaGames   *triviaGames
+	calcVariables *calcVariables
 }
 
 // NewBot returns a new Scumbag instance.
@@ -91,9 +132,15 @@ func NewBot(configFile, logFilename, environment *string) (*Scumbag, error) {
 	}
 
 	bot := &Scumbag{
-		Environment:  *environment,
-		Config:       botConfig,
-		disconnected: make(map[string]chan struct{}),
+		Environment:   *environment,
+		Config:        botConfig,
+		disconnected:  make(map[string]chan struct{}),
+		quit:          make(chan struct{}),
+		forgetMe:      newConfirmations(),
+		dbHealth:      &dbHealth{},
+		recentLines:   newRecentLines(),
+		triviaGames:   newTriviaGames(),
+		calcVariables: newCalcVariables(),
 	}
 
 	bot.setupRollbar()
@@ -119,6 +166,14 @@ func NewBot(configFile, logFilename, environment *string) (*Scumbag, error) {
 func (bot *Scumbag) Start() error {
 	bot.Log.Info("Starting.")
 
+	if err := bot.pingDatabase(); err != nil {
+		return err
+	}
+
+	if err := bot.autoMigrate(); err != nil {
+		return err
+	}
+
 	// Keeps track of how many servers in bot.ircClients that fail to connect.
 	connectErrors := 0
 
@@ -135,6 +190,13 @@ func (bot *Scumbag) Start() error {
 
 	bot.startTime = time.Now()
 
+	bot.startDatabaseMonitor()
+	bot.startLinkChecker()
+	bot.startLinkRetention()
+	bot.startReminders()
+	bot.startChannelLog()
+	bot.startPolls()
+
 	return nil
 }
 
@@ -150,6 +212,13 @@ func (bot *Scumbag) Wait() {
 func (bot *Scumbag) Shutdown() {
 	bot.Log.Info("Shutting down.")
 
+	// Stops background jobs.
+	close(bot.quit)
+
+	if bot.archiveServer != nil {
+		bot.archiveServer.Shutdown(context.Background())
+	}
+
 	for server, client := range bot.ircClients {
 		bot.Log.WithField("server", server).Debug("Shutdown()")
 		if client.Connected() {
@@ -159,7 +228,12 @@ func (bot *Scumbag) Shutdown() {
 		}
 	}
 
-	bot.DB.Close()
+	bot.Close()
+}
+
+// Close closes the database connection.
+func (bot *Scumbag) Close() error {
+	return bot.db.Close()
 }
 
 // Admin returns true if the given nick string is an admin.
@@ -178,6 +252,11 @@ func (bot *Scumbag) Msg(conn *irc.Conn, channelOrNick string, message string, a
 	bot.ircClients[conn.Config().Server].Privmsg(channelOrNick, fmt.Sprintf(message, a...))
 }
 
+// Action sends a CTCP ACTION ("/me") to `channel_or_nick` on `conn.Config().Server`'s client.
+func (bot *Scumbag) Action(conn *irc.Conn, channelOrNick string, message string, a ...interface{}) {
+	bot.ircClients[conn.Config().Server].Action(channelOrNick, fmt.Sprintf(message, a...))
+}
+
 func (bot *Scumbag) LogError(msg string, err error) {
 	bot.Log.WithField("err", err).Error(msg)
 	rollbar.ErrorWithExtras(rollbar.ERR, err, map[string]interface{}{
@@ -233,13 +312,24 @@ func (bot *Scumbag) setupLogger(logFilename *string) error {
 func (bot *Scumbag) setupDatabase() error {
 	bot.Log.Debug("setupDatabase()")
 
-	databaseParams := fmt.Sprintf("host=%s sslmode=%s dbname=%s user=%s password=%s", bot.Config.Database.Host, bot.Config.Database.SSL, bot.Config.Database.Name, bot.Config.Database.User, bot.Config.Database.Password)
-	session, err := sql.Open("postgres", databaseParams)
+	db, store, err := openDatabase(bot.Config.Database)
 	if err != nil {
 		bot.Log.WithField("error", err).Fatal("Database Connection Error")
 		return err
 	}
-	bot.DB = session
+	bot.db = db
+
+	bot.Links = store
+	bot.Ignores = store
+	bot.Seen = store
+	bot.Memos = store
+	bot.Reminders = store
+	bot.Karma = store
+	bot.Quotes = store
+	bot.Factoids = store
+	bot.ChannelLog = store
+	bot.Polls = store
+	bot.Trivia = store
 
 	return nil
 }
@@ -303,6 +393,10 @@ func (bot *Scumbag) setupHandlers() {
 
 		client.HandleFunc("CONNECTED", func(conn *irc.Conn, line *irc.Line) {
 			bot.Log.WithField("server", conn.Config().Server).Info("Connected to server.")
+
+			// Servers which support it tag lines with the sender's account, for pollVoter.
+			conn.Cap("REQ", accountTagCap)
+
 			for channel, _ := range serverConfig.Channels {
 				bot.Log.WithField("channel", channel).Info("Joining channel.")
 				conn.Join(channel)
@@ -312,6 +406,7 @@ func (bot *Scumbag) setupHandlers() {
 		client.HandleFunc("DISCONNECTED", func(conn *irc.Conn, line *irc.Line) {
 			bot.Log.WithField("server", conn.Config().Server).Info("Disconnected.")
 			close(bot.disconnected[conn.Config().Server])
+			bot.triviaGames.stopServer(conn.Config().Server)
 
 			err := bot.connectClient(client)
 			if err != nil {
@@ -322,6 +417,16 @@ func (bot *Scumbag) setupHandlers() {
 		})
 
 		client.HandleFunc("PRIVMSG", bot.msgHandler)
+
+		for _, event := range seenEvents {
+			client.HandleFunc(event, bot.seenHandler)
+		}
+		client.HandleFunc("JOIN", bot.memoHandler)
+		for _, event := range channelLogEvents {
+			client.HandleFunc(event, bot.channelLogHandler)
+		}
+		client.HandleFunc("PART", bot.triviaHandler)
+		client.HandleFunc("KICK", bot.triviaHandler)
 	}
 }
 
@@ -334,9 +439,19 @@ func (bot *Scumbag) msgHandler(conn *irc.Conn, line *irc.Line) {
 		"line.Args":            line.Args,
 	}).Debug("Scumbag.msgHandler(): Channel message.")
 
+	// Not in a goroutine, so "<cmdPrefix>grab" always sees the lines before it.
+	bot.rememberLine(conn, line)
+
 	// These functions check the line text and act accordingly.
 	go bot.SaveURLs(conn, line)
+	go bot.UnfurlURLs(conn, line)
 	go bot.SpellcheckLine(conn, line)
+	go bot.KarmaLine(conn, line)
+	go bot.CorrectLine(conn, line)
+	go bot.TriviaLine(conn, line)
+	go bot.RecordSeen(conn, line)
+	go bot.DeliverMemos(conn, line)
+	go bot.LogChannelLine(conn, line)
 
 	// This function handles explicit bot commands.
 	go bot.processCommands(conn, line)
@@ -362,26 +477,66 @@ func (bot *Scumbag) processCommands(conn *irc.Conn, line *irc.Line) {
 	switch commandName {
 	case cmdAdmin:
 		command = NewAdminCommand(bot, conn, line)
+	case cmdCalc:
+		command = NewCalcCommand(bot, conn, line)
+	case cmdChoose:
+		command = NewChooseCommand(bot, conn, line)
+	case cmdConvert:
+		command = NewConvertCommand(bot, conn, line)
 	case cmdCorona:
 		command = NewCoronaCommand(bot, conn, line)
+	case cmdEightBall:
+		command = NewEightBallCommand(bot, conn, line)
+	case cmdFactoid:
+		command = NewFactoidCommand(bot, conn, line)
 	case cmdFiglet:
 		command = NewFigletCommand(bot, conn, line)
+	case cmdFlip:
+		command = NewFlipCommand(bot, conn, line)
+	case cmdForget:
+		command = NewForgetCommand(bot, conn, line)
 	case cmdGame:
 		command = NewGameCommand(bot, conn, line)
 	case cmdGithub:
 		command = NewGithubCommand(bot, conn, line)
+	case cmdGrab:
+		command = NewGrabCommand(bot, conn, line)
+	case cmdGrep:
+		command = NewGrepCommand(bot, conn, line)
 	case cmdHackerNews:
 		command = NewHackerNewsCommand(bot, conn, line)
 	case cmdHelp:
 		command = NewHelpCommand(bot, conn, line)
+	case cmdKarma:
+		command = NewKarmaCommand(bot, conn, line)
+	case cmdLearn:
+		command = NewLearnCommand(bot, conn, line)
+	case cmdMemos:
+		command = NewMemosCommand(bot, conn, line)
 	case cmdMovie:
 		command = NewMovieCommand(bot, conn, line)
 	case cmdNews:
 		command = NewNewsCommand(bot, conn, line)
+	case cmdPoll:
+		command = NewPollCommand(bot, conn, line)
+	case cmdQuote:
+		command = NewQuoteCommand(bot, conn, line)
 	case cmdReddit:
 		command = NewRedditCommand(bot, conn, line)
+	case cmdRemind:
+		command = NewRemindCommand(bot, conn, line)
+	case cmdReminders:
+		command = NewRemindersCommand(bot, conn, line)
+	case cmdRoll:
+		command = NewRollCommand(bot, conn, line)
+	case cmdSeen:
+		command = NewSeenCommand(bot, conn, line)
 	case cmdSpell:
 		command = NewSpellcheckCommand(bot, conn, line)
+	case cmdTell:
+		command = NewTellCommand(bot, conn, line)
+	case cmdTrivia:
+		command = NewTriviaCommand(bot, conn, line)
 	case cmdTwitter:
 		command = NewTwitterCommand(bot, conn, line)
 	case cmdUptime:
@@ -390,8 +545,12 @@ func (bot *Scumbag) processCommands(conn *irc.Conn, line *irc.Line) {
 		command = NewUrbanDictionaryCommand(bot, conn, line)
 	case cmdURL:
 		command = NewLinkCommand(bot, conn, line)
+	case cmdURLStats:
+		command = NewLinkStatsCommand(bot, conn, line)
 	case cmdVersion:
 		command = NewVersionCommand(bot, conn, line)
+	case cmdVote:
+		command = NewVoteCommand(bot, conn, line)
 	case cmdWeather:
 		command = NewWeatherCommand(bot, conn, line)
 	case cmdWiki:
@@ -399,6 +558,10 @@ func (bot *Scumbag) processCommands(conn *irc.Conn, line *irc.Line) {
 	case cmdWolfram:
 		command = NewWolframAlphaCommand(bot, conn, line)
 	default:
+		// Anything else might be a factoid.
+		if strings.HasPrefix(commandName, cmdPrefix) && bot.lookupFactoid(conn, line, strings.TrimPrefix(commandName, cmdPrefix)) {
+			return
+		}
 		bot.Log.WithField("commandName", commandName).Debug("Scumbag.processCommands(): Unknown command")
 	}
 
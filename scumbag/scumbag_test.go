@@ -1,9 +1,15 @@
 package scumbag
 
 import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"fmt"
+	irc "github.com/fluffle/goirc/client"
 )
 
 func newTestBot() (*Scumbag, error) {
@@ -13,6 +19,96 @@ func newTestBot() (*Scumbag, error) {
 	return NewBot(&configFile, &logFilename, &environment)
 }
 
+// testConn is an IRC client connected to a local listener, recording what it sends.
+type testConn struct {
+	*irc.Conn
+
+	lock  sync.Mutex
+	lines []string
+	syncs int
+}
+
+// newTestConn connects a client for `bot` to a local listener; bot.Msg()
+// and friends on it are recorded for said().
+func newTestConn(t *testing.T, bot *Scumbag) *testConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := irc.NewConfig("scumbag")
+	config.Server = listener.Addr().String()
+	config.Flood = true
+	conn := &testConn{Conn: irc.Client(config)}
+
+	go func() {
+		server, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer server.Close()
+
+		scanner := bufio.NewScanner(server)
+		for scanner.Scan() {
+			conn.lock.Lock()
+			conn.lines = append(conn.lines, scanner.Text())
+			conn.lock.Unlock()
+		}
+	}()
+
+	if err := conn.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		listener.Close()
+	})
+
+	if bot.ircClients == nil {
+		bot.ircClients = make(map[string]*irc.Conn)
+	}
+	bot.ircClients[config.Server] = conn.Conn
+	return conn
+}
+
+// said waits for everything sent so far, and returns the messages sent since
+// the last call as "<target> <text>".
+func (conn *testConn) said(t *testing.T) []string {
+	conn.syncs++
+	marker := fmt.Sprintf("PRIVMSG #sync :%d", conn.syncs)
+	conn.Raw(marker)
+
+	for i := 0; i < 400; i++ {
+		conn.lock.Lock()
+		lines := conn.lines
+		for j, line := range lines {
+			if line != marker {
+				continue
+			}
+
+			var said []string
+			for _, line := range lines[:j] {
+				if strings.HasPrefix(line, "PRIVMSG ") && !strings.HasPrefix(line, "PRIVMSG #sync ") {
+					said = append(said, strings.Replace(strings.TrimPrefix(line, "PRIVMSG "), " :", " ", 1))
+				}
+			}
+			conn.lines = lines[j+1:]
+			conn.lock.Unlock()
+			return said
+		}
+		conn.lock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatal("Never got what the bot said")
+	return nil
+}
+
+// testLine returns a PRIVMSG from `nick` to `target`.
+func testLine(nick, target, text string) *irc.Line {
+	return &irc.Line{Nick: nick, Ident: nick, Host: "example.com", Src: nick + "!" + nick + "@example.com", Cmd: "PRIVMSG", Args: []string{target, text}, Time: time.Now()}
+}
+
 func TestVersionString(t *testing.T) {
 	expected := fmt.Sprintf("scumbag v%s-%s", Version, BuildTag)
 	if expected != VersionString() {
@@ -0,0 +1,203 @@
+package scumbag
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	irc "github.com/fluffle/goirc/client"
+)
+
+// Seen actions.
+const (
+	seenMessage = "message"
+	seenJoin    = "join"
+	seenPart    = "part"
+	seenQuit    = "quit"
+	seenNick    = "nick"
+	seenKick    = "kick"
+)
+
+var seenHelp = []string{
+	cmdSeen + " <nick> -- when the nick was last active anywhere on the server",
+	cmdSeen + " <nick> <#channel> -- when the nick was last active in the channel",
+}
+
+// seenEvents are the IRC events recorded besides PRIVMSG, which goes through msgHandler().
+var seenEvents = []string{"JOIN", "PART", "QUIT", "NICK", "KICK"}
+
+// Seen is the last thing a nick did in a channel. Quits and nick changes
+// aren't tied to a channel, so their Channel is empty.
+type Seen struct {
+	Server  string
+	Channel string
+	Nick    string
+	Action  string
+	// The message, or the part, quit or kick reason.
+	Message string
+	// The new nick for a nick change, or who kicked them.
+	Target    string
+	CreatedAt time.Time
+}
+
+// newSeen returns the activity in `line`, or nil if it isn't something to record.
+func newSeen(server string, line *irc.Line) *Seen {
+	seen := &Seen{Server: server, Nick: line.Nick, CreatedAt: line.Time}
+	arg := func(i int) string {
+		if i < len(line.Args) {
+			return line.Args[i]
+		}
+		return ""
+	}
+
+	switch line.Cmd {
+	case "PRIVMSG":
+		// Private messages stay private.
+		if !line.Public() {
+			return nil
+		}
+		seen.Action, seen.Channel, seen.Message = seenMessage, line.Target(), line.Text()
+	case "JOIN":
+		seen.Action, seen.Channel = seenJoin, arg(0)
+	case "PART":
+		seen.Action, seen.Channel, seen.Message = seenPart, arg(0), arg(1)
+	case "QUIT":
+		seen.Action, seen.Message = seenQuit, arg(0)
+	case "NICK":
+		seen.Action, seen.Target = seenNick, arg(0)
+	case "KICK":
+		seen.Action, seen.Channel, seen.Nick, seen.Message, seen.Target = seenKick, arg(0), arg(1), arg(2), line.Nick
+	default:
+		return nil
+	}
+
+	if seen.Nick == "" {
+		return nil
+	}
+
+	return seen
+}
+
+// String describes the activity, e.g. "bob was last seen 2 hours ago, joining #scumbag".
+func (seen *Seen) String() string {
+	var what string
+	switch seen.Action {
+	case seenMessage:
+		if seen.Message == "" {
+			what = fmt.Sprintf("talking in %s", seen.Channel)
+		} else {
+			what = fmt.Sprintf("saying in %s: %s", seen.Channel, seen.Message)
+		}
+	case seenJoin:
+		what = fmt.Sprintf("joining %s", seen.Channel)
+	case seenPart:
+		what = fmt.Sprintf("leaving %s", seen.Channel)
+	case seenQuit:
+		what = "quitting"
+	case seenNick:
+		what = fmt.Sprintf("changing nick to %s", seen.Target)
+	case seenKick:
+		what = fmt.Sprintf("being kicked from %s by %s", seen.Channel, seen.Target)
+	default:
+		what = seen.Action
+	}
+
+	if seen.Action != seenMessage && seen.Message != "" {
+		what += fmt.Sprintf(" (%s)", seen.Message)
+	}
+
+	return fmt.Sprintf("%s was last seen %s, %s", seen.Nick, humanize.Time(seen.CreatedAt), what)
+}
+
+// seenHandler handles the events in seenEvents.
+func (bot *Scumbag) seenHandler(conn *irc.Conn, line *irc.Line) {
+	go bot.RecordSeen(conn, line)
+}
+
+// RecordSeen is called from a goroutine to save the activity in `line`.
+func (bot *Scumbag) RecordSeen(conn *irc.Conn, line *irc.Line) {
+	seen := newSeen(conn.Config().Server, line)
+	if seen == nil || bot.databaseDown() {
+		return
+	}
+
+	if err := bot.Seen.SaveSeen(seen); err != nil && !bot.checkDatabase(err) {
+		bot.LogError("RecordSeen()", err)
+	}
+}
+
+// SeenCommand shows when a nick was last active.
+type SeenCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewSeenCommand returns a new SeenCommand instance.
+func NewSeenCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *SeenCommand {
+	return &SeenCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *SeenCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("SeenCommand.Run()", err)
+		return
+	}
+
+	if len(args) <= 0 {
+		cmd.bot.Log.WithField("args", args).Debug("SeenCommand.Run(): No args")
+		return
+	}
+
+	fields := strings.Fields(args[0])
+	if len(fields) <= 0 || len(fields) > 2 {
+		cmd.Help()
+		return
+	}
+
+	nick := fields[0]
+	inChannel := ""
+	if len(fields) > 1 {
+		inChannel = fields[1]
+	}
+
+	seen, err := cmd.bot.Seen.LastSeen(cmd.conn.Config().Server, inChannel, nick)
+	if err != nil {
+		cmd.bot.LogError("SeenCommand.Run()", err)
+		return
+	}
+
+	if seen == nil {
+		if inChannel != "" {
+			cmd.bot.Msg(cmd.conn, channel, "I haven't seen %s in %s.", nick, inChannel)
+		} else {
+			cmd.bot.Msg(cmd.conn, channel, "I haven't seen %s.", nick)
+		}
+		return
+	}
+
+	// What was said in other channels stays there, unless an admin asks.
+	if seen.Channel != "" && !strings.EqualFold(seen.Channel, channel) && !cmd.bot.Admin(cmd.line.Nick) {
+		seen.Message = ""
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s", seen)
+}
+
+// Help shows the command help.
+func (cmd *SeenCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("SeenCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range seenHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
@@ -0,0 +1,94 @@
+package scumbag
+
+import (
+	"testing"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+)
+
+func TestNewSeen(t *testing.T) {
+	for _, tc := range []struct {
+		cmd      string
+		args     []string
+		expected *Seen
+	}{
+		{"PRIVMSG", []string{"#scumbag", "hi"}, &Seen{Channel: "#scumbag", Nick: "bob", Action: seenMessage, Message: "hi"}},
+		{"PRIVMSG", []string{"scumbag", "psst"}, nil},
+		{"JOIN", []string{"#scumbag"}, &Seen{Channel: "#scumbag", Nick: "bob", Action: seenJoin}},
+		{"PART", []string{"#scumbag"}, &Seen{Channel: "#scumbag", Nick: "bob", Action: seenPart}},
+		{"PART", []string{"#scumbag", "later"}, &Seen{Channel: "#scumbag", Nick: "bob", Action: seenPart, Message: "later"}},
+		{"QUIT", []string{"Ping timeout"}, &Seen{Nick: "bob", Action: seenQuit, Message: "Ping timeout"}},
+		{"NICK", []string{"bob_"}, &Seen{Nick: "bob", Action: seenNick, Target: "bob_"}},
+		{"KICK", []string{"#scumbag", "alice", "spam"}, &Seen{Channel: "#scumbag", Nick: "alice", Action: seenKick, Message: "spam", Target: "bob"}},
+		{"KICK", []string{"#scumbag"}, nil},
+		{"TOPIC", []string{"#scumbag", "new topic"}, nil},
+	} {
+		now := time.Now()
+		line := &irc.Line{Nick: "bob", Cmd: tc.cmd, Args: tc.args, Time: now}
+
+		seen := newSeen("irc.example.com", line)
+		if tc.expected == nil {
+			if seen != nil {
+				t.Errorf("%s %v: expected nil, got %+v", tc.cmd, tc.args, seen)
+			}
+			continue
+		}
+
+		tc.expected.Server = "irc.example.com"
+		tc.expected.CreatedAt = now
+		if seen == nil || *seen != *tc.expected {
+			t.Errorf("%s %v: expected %+v, got %+v", tc.cmd, tc.args, tc.expected, seen)
+		}
+	}
+}
+
+func TestSeenString(t *testing.T) {
+	ago := time.Now().Add(-2 * time.Hour)
+
+	for _, tc := range []struct {
+		seen     *Seen
+		expected string
+	}{
+		{&Seen{Nick: "bob", Channel: "#scumbag", Action: seenMessage, Message: "hi (there)"}, "bob was last seen 2 hours ago, saying in #scumbag: hi (there)"},
+		{&Seen{Nick: "bob", Channel: "#scumbag", Action: seenMessage}, "bob was last seen 2 hours ago, talking in #scumbag"},
+		{&Seen{Nick: "bob", Channel: "#scumbag", Action: seenPart, Message: "later"}, "bob was last seen 2 hours ago, leaving #scumbag (later)"},
+		{&Seen{Nick: "bob", Action: seenQuit}, "bob was last seen 2 hours ago, quitting"},
+		{&Seen{Nick: "bob", Action: seenNick, Target: "bob_"}, "bob was last seen 2 hours ago, changing nick to bob_"},
+		{&Seen{Nick: "alice", Channel: "#scumbag", Action: seenKick, Target: "bob", Message: "spam"}, "alice was last seen 2 hours ago, being kicked from #scumbag by bob (spam)"},
+	} {
+		tc.seen.CreatedAt = ago
+		if actual := tc.seen.String(); actual != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, actual)
+		}
+	}
+}
+
+func TestSeenCommand(t *testing.T) {
+	bot, err := newTestBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.Seen = NewMemoryStore()
+	conn := newTestConn(t, bot)
+
+	seen := &Seen{Server: conn.Config().Server, Channel: "#secret", Nick: "bob", Action: seenMessage, Message: "the password is hunter2", CreatedAt: time.Now()}
+	if err := bot.Seen.SaveSeen(seen); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		nick     string
+		channel  string
+		expected string
+	}{
+		{"alice", "#secret", "#secret bob was last seen now, saying in #secret: the password is hunter2"},
+		{"alice", "#scumbag", "#scumbag bob was last seen now, talking in #secret"},
+		{"admin_nick", "#scumbag", "#scumbag bob was last seen now, saying in #secret: the password is hunter2"},
+	} {
+		NewSeenCommand(bot, conn.Conn, testLine(tc.nick, tc.channel, "?seen bob")).Run("bob")
+		if said := conn.said(t); len(said) != 1 || said[0] != tc.expected {
+			t.Errorf("%s in %s: expected %q, got %q", tc.nick, tc.channel, tc.expected, said)
+		}
+	}
+}
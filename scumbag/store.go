@@ -0,0 +1,200 @@
+package scumbag
+
+import (
+	"time"
+)
+
+// LinkStore stores saved links and everything hanging off them.
+type LinkStore interface {
+	// FindLink returns the oldest link in a channel matching `url` or `canonicalURL`, or nil.
+	FindLink(server, channel, url, canonicalURL string) (*Link, error)
+	// SaveLink inserts a new link and sets its ID.
+	SaveLink(link *Link) error
+	// SaveLinks inserts new links in one transaction and sets their IDs.
+	SaveLinks(links []*Link) error
+	AddRepost(id int64) error
+
+	// GetLink returns link `id` if it was saved in the channel, or nil.
+	GetLink(server, channel string, id int64) (*Link, error)
+	SearchLinks(query *LinkQuery, server, channel string) ([]*Link, error)
+	// TopLinks returns the most reposted links in a channel.
+	TopLinks(server, channel string, limit int) ([]*Link, error)
+	LinkStats(query *LinkQuery, server, channel string) (*LinkStats, error)
+
+	// Links returns every link matching `filter`, oldest first.
+	Links(filter *LinkFilter) ([]*Link, error)
+	CountLinks(filter *LinkFilter) (int, error)
+	// CanonicalizeLinks sets links' canonical URLs, by ID, and makes `merges`,
+	// all in one transaction.
+	CanonicalizeLinks(canonicalURLs map[int64]string, merges []*LinkMerge) error
+	// PurgeLinks deletes links, logs the purge if it deleted any and returns the deleted IDs.
+	PurgeLinks(purge *LinkPurge) ([]int64, error)
+
+	// LinksToCheck returns links not checked since `checkedBefore`, least recently checked first.
+	LinksToCheck(checkedBefore time.Time, limit int) ([]*Link, error)
+	SetLinkStatus(id int64, statusCode int, dead bool, checkedAt time.Time) error
+	SetLinkArchived(id int64, archivedAt time.Time) error
+
+	TagLink(id int64, tag, nick string, createdAt time.Time) error
+	UntagLink(id int64, tag string) error
+	LinkTags(id int64) ([]string, error)
+	// FavoriteLink and UnfavoriteLink return false if nothing changed.
+	FavoriteLink(id int64, server, nick string, createdAt time.Time) (bool, error)
+	UnfavoriteLink(id int64, nick string) (bool, error)
+}
+
+// IgnoreStore stores nicks the bot ignores.
+type IgnoreStore interface {
+	IgnoredNick(server, nick string) (bool, error)
+	// IgnoreNick returns false if the nick was already ignored.
+	IgnoreNick(server, nick string, createdAt time.Time) (bool, error)
+	UnignoreNick(server, nick string) error
+}
+
+// SeenStore stores the last thing each nick did in each channel.
+type SeenStore interface {
+	// SaveSeen replaces the nick's last activity in `seen.Channel`.
+	SaveSeen(seen *Seen) error
+	// LastSeen returns the nick's latest activity on the server, or in
+	// `channel` if it isn't empty, or nil. Nicks are case insensitive.
+	LastSeen(server, channel, nick string) (*Seen, error)
+}
+
+// MemoStore stores memos waiting to be delivered. Nicks are case insensitive.
+type MemoStore interface {
+	// SaveMemo inserts a new memo and sets its ID.
+	SaveMemo(memo *Memo) error
+	// SentMemos returns the undelivered memos `sender` left, oldest first.
+	SentMemos(server, sender string) ([]*Memo, error)
+	// CancelMemo deletes memo `id` if `sender` left it, and returns false if it didn't.
+	CancelMemo(server, sender string, id int64) (bool, error)
+	// TakeMemos deletes and returns the memos for `recipient`, oldest first.
+	TakeMemos(server, recipient string) ([]*Memo, error)
+}
+
+// ReminderStore stores scheduled reminders.
+type ReminderStore interface {
+	// SaveReminder inserts a new reminder and sets its ID.
+	SaveReminder(reminder *Reminder) error
+	// NickReminders returns the reminders `nick` set, soonest first. Nicks are case insensitive.
+	NickReminders(server, nick string) ([]*Reminder, error)
+	// CancelReminder deletes reminder `id` if `nick` set it, and returns false if it didn't.
+	CancelReminder(server, nick string, id int64) (bool, error)
+
+	// DueReminders returns the reminders due at or before `now`, oldest first.
+	DueReminders(now time.Time) ([]*Reminder, error)
+	RescheduleReminder(id int64, remindAt time.Time) error
+	DeleteReminder(id int64) error
+}
+
+// KarmaStore stores karma votes. Things are lowercase.
+type KarmaStore interface {
+	AddKarma(vote *KarmaVote) error
+	// LastKarmaVote returns when `nick` last voted on `thing` in the channel, or the zero time.
+	LastKarmaVote(server, channel, nick, thing string) (time.Time, error)
+	// Karma returns the score of `thing` in the channel, with up to `reasons` recent reasons.
+	Karma(server, channel, thing string, reasons int) (*Karma, error)
+	// TopKarma returns the highest scores in the channel, or the lowest if `lowest` is set.
+	TopKarma(server, channel string, limit int, lowest bool) ([]*Karma, error)
+}
+
+// QuoteStore stores each channel's quotes.
+type QuoteStore interface {
+	// SaveQuote inserts a new quote and sets its ID.
+	SaveQuote(quote *Quote) error
+	// GetQuote returns quote `id` if it was saved in the channel, or nil.
+	GetQuote(server, channel string, id int64) (*Quote, error)
+	// RandomQuote returns a random quote from the channel, or nil if it has none.
+	RandomQuote(server, channel string) (*Quote, error)
+	// Quotes returns every quote in the channel, oldest first.
+	Quotes(server, channel string) ([]*Quote, error)
+	// DeleteQuote returns false if the channel had no quote `id`.
+	DeleteQuote(server, channel string, id int64) (bool, error)
+}
+
+// FactoidStore stores factoids and their edit history. Global factoids have
+// an empty channel; names are lowercase.
+type FactoidStore interface {
+	// GetFactoid returns the factoid in exactly `channel`, or nil.
+	GetFactoid(server, channel, name string) (*Factoid, error)
+	// SaveFactoid inserts or updates a factoid, sets its ID and records
+	// `action` by factoid.Nick in its history.
+	SaveFactoid(factoid *Factoid, action string) error
+	// DeleteFactoid records the deletion in its history, and returns false if there was no factoid.
+	DeleteFactoid(server, channel, name, nick string, deletedAt time.Time) (bool, error)
+	// FactoidEdits returns a factoid's history, newest first.
+	FactoidEdits(server, channel, name string, limit int) ([]*FactoidEdit, error)
+}
+
+// ChannelLogStore stores the lines of logged channels.
+type ChannelLogStore interface {
+	// SaveLogLine inserts a new line and sets its ID.
+	SaveLogLine(line *LogLine) error
+	// LogLines returns the lines matching `filter`, oldest first. If `limit` is
+	// positive, only the newest `limit` lines are returned.
+	LogLines(filter *LinkFilter, limit int) ([]*LogLine, error)
+	// PurgeLog deletes the lines matching `filter` and returns how many it deleted.
+	PurgeLog(filter *LinkFilter) (int, error)
+}
+
+// PollStore stores polls and their votes.
+type PollStore interface {
+	// SavePoll inserts a new poll and sets its ID. It returns errPollOpen if the
+	// channel already has an open poll.
+	SavePoll(poll *Poll) error
+	// OpenPoll returns the channel's open poll, or nil.
+	OpenPoll(server, channel string) (*Poll, error)
+	// DuePolls returns the open polls closing at or before `now`, oldest first.
+	DuePolls(now time.Time) ([]*Poll, error)
+	// ClosePoll sets an open poll's status, and returns false if it wasn't open.
+	ClosePoll(id int64, status string) (bool, error)
+
+	// SaveVote records a vote, replacing the voter's earlier vote in the poll.
+	SaveVote(vote *PollVote) error
+	// PollVotes returns the number of votes for each choice.
+	PollVotes(id int64) (map[int]int, error)
+}
+
+// TriviaStore stores trivia answers for each channel's leaderboard. Nicks are case insensitive.
+type TriviaStore interface {
+	AddTriviaAnswer(answer *TriviaAnswer) error
+	// TriviaScores returns the channel's highest scores.
+	TriviaScores(server, channel string, limit int) ([]*TriviaScore, error)
+	// TriviaScore returns the nick's totals in the channel, which are zero if it never answered.
+	TriviaScore(server, channel, nick string) (*TriviaScore, error)
+}
+
+// LinkMerge deletes a duplicate link, moving its tags and favorites to the original.
+type LinkMerge struct {
+	DuplicateID int64
+	OriginalID  int64
+	// The original's repost count after the merge.
+	Reposts int
+}
+
+// LinkFilter selects links (or channel log lines); empty fields match everything.
+type LinkFilter struct {
+	Server  string
+	Channel string
+	Nick    string
+	Since   time.Time
+	Until   time.Time
+}
+
+// LinkStats is the result of a "<cmdPrefix>urlstats" query.
+type LinkStats struct {
+	Total        int
+	First        time.Time
+	TopPosters   []*LinkCount
+	TopDomains   []*LinkCount
+	MostReposted *Link
+	// Links on each of the last linkStatsDays days and linkStatsWeeks weeks, oldest first.
+	PerDay  []*LinkCount
+	PerWeek []*LinkCount
+}
+
+// LinkCount is a name with the number of links it has.
+type LinkCount struct {
+	Name  string
+	Count int
+}
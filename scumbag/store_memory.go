@@ -0,0 +1,1177 @@
+package scumbag
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Same as linkHostSQL.
+var linkHostRegexp = regexp.MustCompile(`://([^/:?#]+)`)
+
+// MemoryStore implements the stores in store.go in memory, for tests and
+// running without a database. Nothing is persisted.
+type MemoryStore struct {
+	sync.Mutex
+
+	nextID    int64
+	links     []*memoryLink
+	ignored   map[string]time.Time
+	purges    []*LinkPurge
+	seen      map[string]*Seen
+	memos     []*Memo
+	reminders []*Reminder
+	karma     []*KarmaVote
+	quotes    []*Quote
+	factoids  map[string]*Factoid
+	edits     []*memoryFactoidEdit
+	logLines  []*LogLine
+	polls     []*Poll
+	pollVotes []*PollVote
+	trivia    []*TriviaAnswer
+}
+
+// memoryFactoidEdit is a FactoidEdit plus the factoid it belongs to.
+type memoryFactoidEdit struct {
+	FactoidEdit
+
+	key string
+}
+
+// memoryLink is a Link plus the columns Link doesn't carry.
+type memoryLink struct {
+	Link
+
+	statusCode int
+	checkedAt  time.Time
+	tags       map[string]bool
+	favorites  map[string]bool
+}
+
+// NewMemoryStore returns a new MemoryStore instance.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ignored:  make(map[string]time.Time),
+		seen:     make(map[string]*Seen),
+		factoids: make(map[string]*Factoid),
+	}
+}
+
+// FindLink implements LinkStore.
+func (store *MemoryStore) FindLink(server, channel, url, canonicalURL string) (*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var found *Link
+	for _, link := range store.links {
+		if link.Server != server || link.Channel != channel || (link.CanonicalURL != canonicalURL && link.URL != url) {
+			continue
+		}
+		if found == nil || link.CreatedAt.Before(found.CreatedAt) {
+			found = &link.Link
+		}
+	}
+
+	return copyLink(found), nil
+}
+
+// SaveLink implements LinkStore.
+func (store *MemoryStore) SaveLink(link *Link) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.saveLink(link)
+	return nil
+}
+
+// SaveLinks implements LinkStore.
+func (store *MemoryStore) SaveLinks(links []*Link) error {
+	store.Lock()
+	defer store.Unlock()
+
+	for _, link := range links {
+		store.saveLink(link)
+	}
+	return nil
+}
+
+func (store *MemoryStore) saveLink(link *Link) {
+	store.nextID++
+	link.ID = store.nextID
+
+	store.links = append(store.links, &memoryLink{
+		Link:      *link,
+		tags:      make(map[string]bool),
+		favorites: make(map[string]bool),
+	})
+}
+
+// AddRepost implements LinkStore.
+func (store *MemoryStore) AddRepost(id int64) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if link := store.link(id); link != nil {
+		link.Reposts++
+	}
+	return nil
+}
+
+// GetLink implements LinkStore.
+func (store *MemoryStore) GetLink(server, channel string, id int64) (*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	link := store.link(id)
+	if link == nil || link.Server != server || link.Channel != channel {
+		return nil, nil
+	}
+	return copyLink(&link.Link), nil
+}
+
+// SearchLinks implements LinkStore.
+func (store *MemoryStore) SearchLinks(query *LinkQuery, server, channel string) ([]*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	matches := store.match(query, server, channel)
+	if query.Random {
+		rng.Shuffle(len(matches), func(i, j int) { matches[i], matches[j] = matches[j], matches[i] })
+	} else {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].CreatedAt.After(matches[j].CreatedAt) })
+	}
+
+	if len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return copyLinks(matches), nil
+}
+
+// TopLinks implements LinkStore.
+func (store *MemoryStore) TopLinks(server, channel string, limit int) ([]*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var matches []*memoryLink
+	for _, link := range store.links {
+		if link.Server == server && link.Channel == channel && link.Reposts > 0 {
+			matches = append(matches, link)
+		}
+	}
+	sortByReposts(matches)
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return copyLinks(matches), nil
+}
+
+// LinkStats implements LinkStore.
+func (store *MemoryStore) LinkStats(query *LinkQuery, server, channel string) (*LinkStats, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	matches := store.match(query, server, channel)
+	stats := &LinkStats{Total: len(matches)}
+
+	nicks := make(map[string]int)
+	domains := make(map[string]int)
+	var reposted []*memoryLink
+	var times []time.Time
+	for _, link := range matches {
+		if stats.First.IsZero() || link.CreatedAt.Before(stats.First) {
+			stats.First = link.CreatedAt
+		}
+		times = append(times, link.CreatedAt)
+
+		nicks[link.Nick]++
+		if host := linkHost(&link.Link); host != "" {
+			domains[host]++
+		}
+
+		if link.Reposts > 0 {
+			reposted = append(reposted, link)
+		}
+	}
+
+	stats.TopPosters = topLinkCounts(nicks, query.Limit)
+	stats.TopDomains = topLinkCounts(domains, query.Limit)
+
+	sortByReposts(reposted)
+	if len(reposted) > 0 {
+		stats.MostReposted = copyLink(&reposted[0].Link)
+	}
+
+	stats.PerDay, stats.PerWeek = linkActivity(times, linkActivityEnd(query, time.Now()))
+
+	return stats, nil
+}
+
+// Links implements LinkStore.
+func (store *MemoryStore) Links(filter *LinkFilter) ([]*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var matches []*memoryLink
+	for _, link := range store.links {
+		if filter.match(&link.Link) {
+			matches = append(matches, link)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].CreatedAt.Before(matches[j].CreatedAt) })
+
+	return copyLinks(matches), nil
+}
+
+// CountLinks implements LinkStore.
+func (store *MemoryStore) CountLinks(filter *LinkFilter) (int, error) {
+	links, err := store.Links(filter)
+	return len(links), err
+}
+
+// CanonicalizeLinks implements LinkStore.
+func (store *MemoryStore) CanonicalizeLinks(canonicalURLs map[int64]string, merges []*LinkMerge) error {
+	store.Lock()
+	defer store.Unlock()
+
+	for id, canonicalURL := range canonicalURLs {
+		if link := store.link(id); link != nil {
+			link.CanonicalURL = canonicalURL
+		}
+	}
+
+	for _, merge := range merges {
+		original, duplicate := store.link(merge.OriginalID), store.link(merge.DuplicateID)
+		if original != nil {
+			original.Reposts = merge.Reposts
+
+			if duplicate != nil {
+				for tag := range duplicate.tags {
+					original.tags[tag] = true
+				}
+				for nick := range duplicate.favorites {
+					original.favorites[nick] = true
+				}
+			}
+		}
+		store.remove(func(link *memoryLink) bool { return link.ID == merge.DuplicateID })
+	}
+	return nil
+}
+
+// PurgeLinks implements LinkStore.
+func (store *MemoryStore) PurgeLinks(purge *LinkPurge) ([]int64, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	ids := store.remove(func(link *memoryLink) bool { return purge.match(&link.Link) })
+	if len(ids) > 0 {
+		store.purges = append(store.purges, purge)
+	}
+	return ids, nil
+}
+
+// LinksToCheck implements LinkStore.
+func (store *MemoryStore) LinksToCheck(checkedBefore time.Time, limit int) ([]*Link, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var matches []*memoryLink
+	for _, link := range store.links {
+		if link.checkedAt.Before(checkedBefore) {
+			matches = append(matches, link)
+		}
+	}
+	// Never checked (zero time) sorts first.
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].checkedAt.Before(matches[j].checkedAt) })
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return copyLinks(matches), nil
+}
+
+// SetLinkStatus implements LinkStore.
+func (store *MemoryStore) SetLinkStatus(id int64, statusCode int, dead bool, checkedAt time.Time) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if link := store.link(id); link != nil {
+		link.statusCode = statusCode
+		link.checkedAt = checkedAt
+		link.Dead = dead
+	}
+	return nil
+}
+
+// SetLinkArchived implements LinkStore.
+func (store *MemoryStore) SetLinkArchived(id int64, archivedAt time.Time) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if link := store.link(id); link != nil {
+		link.Archived = true
+	}
+	return nil
+}
+
+// TagLink implements LinkStore.
+func (store *MemoryStore) TagLink(id int64, tag, nick string, createdAt time.Time) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if link := store.link(id); link != nil {
+		link.tags[tag] = true
+	}
+	return nil
+}
+
+// UntagLink implements LinkStore.
+func (store *MemoryStore) UntagLink(id int64, tag string) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if link := store.link(id); link != nil {
+		delete(link.tags, tag)
+	}
+	return nil
+}
+
+// LinkTags implements LinkStore.
+func (store *MemoryStore) LinkTags(id int64) ([]string, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	link := store.link(id)
+	if link == nil {
+		return nil, nil
+	}
+
+	var tags []string
+	for tag := range link.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// FavoriteLink implements LinkStore.
+func (store *MemoryStore) FavoriteLink(id int64, server, nick string, createdAt time.Time) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	link := store.link(id)
+	if link == nil || link.favorites[nick] {
+		return false, nil
+	}
+
+	link.favorites[nick] = true
+	return true, nil
+}
+
+// UnfavoriteLink implements LinkStore.
+func (store *MemoryStore) UnfavoriteLink(id int64, nick string) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	link := store.link(id)
+	if link == nil || !link.favorites[nick] {
+		return false, nil
+	}
+
+	delete(link.favorites, nick)
+	return true, nil
+}
+
+// IgnoredNick implements IgnoreStore.
+func (store *MemoryStore) IgnoredNick(server, nick string) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	_, ok := store.ignored[server+" "+nick]
+	return ok, nil
+}
+
+// IgnoreNick implements IgnoreStore.
+func (store *MemoryStore) IgnoreNick(server, nick string, createdAt time.Time) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	key := server + " " + nick
+	if _, ok := store.ignored[key]; ok {
+		return false, nil
+	}
+
+	store.ignored[key] = createdAt
+	return true, nil
+}
+
+// UnignoreNick implements IgnoreStore.
+func (store *MemoryStore) UnignoreNick(server, nick string) error {
+	store.Lock()
+	defer store.Unlock()
+
+	delete(store.ignored, server+" "+nick)
+	return nil
+}
+
+// SaveSeen implements SeenStore.
+func (store *MemoryStore) SaveSeen(seen *Seen) error {
+	store.Lock()
+	defer store.Unlock()
+
+	saved := *seen
+	store.seen[seen.Server+" "+seen.Channel+" "+strings.ToLower(seen.Nick)] = &saved
+	return nil
+}
+
+// LastSeen implements SeenStore.
+func (store *MemoryStore) LastSeen(server, channel, nick string) (*Seen, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var last *Seen
+	for _, seen := range store.seen {
+		if seen.Server != server || (channel != "" && seen.Channel != channel) || !strings.EqualFold(seen.Nick, nick) {
+			continue
+		}
+		if last == nil || seen.CreatedAt.After(last.CreatedAt) {
+			last = seen
+		}
+	}
+
+	if last == nil {
+		return nil, nil
+	}
+	found := *last
+	return &found, nil
+}
+
+// SaveMemo implements MemoStore.
+func (store *MemoryStore) SaveMemo(memo *Memo) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.nextID++
+	memo.ID = store.nextID
+
+	saved := *memo
+	store.memos = append(store.memos, &saved)
+	return nil
+}
+
+// SentMemos implements MemoStore.
+func (store *MemoryStore) SentMemos(server, sender string) ([]*Memo, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var memos []*Memo
+	for _, memo := range store.memos {
+		if memo.Server == server && strings.EqualFold(memo.Sender, sender) {
+			found := *memo
+			memos = append(memos, &found)
+		}
+	}
+	return memos, nil
+}
+
+// CancelMemo implements MemoStore.
+func (store *MemoryStore) CancelMemo(server, sender string, id int64) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	removed := store.removeMemos(func(memo *Memo) bool {
+		return memo.ID == id && memo.Server == server && strings.EqualFold(memo.Sender, sender)
+	})
+	return len(removed) > 0, nil
+}
+
+// TakeMemos implements MemoStore.
+func (store *MemoryStore) TakeMemos(server, recipient string) ([]*Memo, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	return store.removeMemos(func(memo *Memo) bool {
+		return memo.Server == server && strings.EqualFold(memo.Recipient, recipient)
+	}), nil
+}
+
+// SaveReminder implements ReminderStore.
+func (store *MemoryStore) SaveReminder(reminder *Reminder) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.nextID++
+	reminder.ID = store.nextID
+
+	saved := *reminder
+	store.reminders = append(store.reminders, &saved)
+	return nil
+}
+
+// NickReminders implements ReminderStore.
+func (store *MemoryStore) NickReminders(server, nick string) ([]*Reminder, error) {
+	return store.findReminders(func(reminder *Reminder) bool {
+		return reminder.Server == server && strings.EqualFold(reminder.Nick, nick)
+	}), nil
+}
+
+// CancelReminder implements ReminderStore.
+func (store *MemoryStore) CancelReminder(server, nick string, id int64) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	removed := store.removeReminders(func(reminder *Reminder) bool {
+		return reminder.ID == id && reminder.Server == server && strings.EqualFold(reminder.Nick, nick)
+	})
+	return removed > 0, nil
+}
+
+// DueReminders implements ReminderStore.
+func (store *MemoryStore) DueReminders(now time.Time) ([]*Reminder, error) {
+	return store.findReminders(func(reminder *Reminder) bool {
+		return !reminder.RemindAt.After(now)
+	}), nil
+}
+
+// RescheduleReminder implements ReminderStore.
+func (store *MemoryStore) RescheduleReminder(id int64, remindAt time.Time) error {
+	store.Lock()
+	defer store.Unlock()
+
+	for _, reminder := range store.reminders {
+		if reminder.ID == id {
+			reminder.RemindAt = remindAt
+		}
+	}
+	return nil
+}
+
+// DeleteReminder implements ReminderStore.
+func (store *MemoryStore) DeleteReminder(id int64) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.removeReminders(func(reminder *Reminder) bool { return reminder.ID == id })
+	return nil
+}
+
+// AddKarma implements KarmaStore.
+func (store *MemoryStore) AddKarma(vote *KarmaVote) error {
+	store.Lock()
+	defer store.Unlock()
+
+	saved := *vote
+	store.karma = append(store.karma, &saved)
+	return nil
+}
+
+// LastKarmaVote implements KarmaStore.
+func (store *MemoryStore) LastKarmaVote(server, channel, nick, thing string) (time.Time, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var last time.Time
+	for _, vote := range store.karma {
+		if vote.Server == server && vote.Channel == channel && strings.EqualFold(vote.Nick, nick) && vote.Thing == thing && vote.CreatedAt.After(last) {
+			last = vote.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+// Karma implements KarmaStore.
+func (store *MemoryStore) Karma(server, channel, thing string, reasons int) (*Karma, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	karma := &Karma{Thing: thing}
+	// Newest first, for the reasons.
+	for i := len(store.karma) - 1; i >= 0; i-- {
+		vote := store.karma[i]
+		if vote.Server != server || vote.Channel != channel || vote.Thing != thing {
+			continue
+		}
+
+		if vote.Delta > 0 {
+			karma.Up++
+		} else {
+			karma.Down++
+		}
+		if vote.Reason != "" && len(karma.Reasons) < reasons {
+			karma.Reasons = append(karma.Reasons, vote.Reason)
+		}
+	}
+	karma.Score = karma.Up - karma.Down
+
+	return karma, nil
+}
+
+// TopKarma implements KarmaStore.
+func (store *MemoryStore) TopKarma(server, channel string, limit int, lowest bool) ([]*Karma, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	scores := make(map[string]int)
+	for _, vote := range store.karma {
+		if vote.Server == server && vote.Channel == channel {
+			scores[vote.Thing] += vote.Delta
+		}
+	}
+
+	var top []*Karma
+	for thing, score := range scores {
+		top = append(top, &Karma{Thing: thing, Score: score})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Score != top[j].Score {
+			return (top[i].Score < top[j].Score) == lowest
+		}
+		return top[i].Thing < top[j].Thing
+	})
+
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top, nil
+}
+
+// SaveQuote implements QuoteStore.
+func (store *MemoryStore) SaveQuote(quote *Quote) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.nextID++
+	quote.ID = store.nextID
+
+	saved := *quote
+	store.quotes = append(store.quotes, &saved)
+	return nil
+}
+
+// GetQuote implements QuoteStore.
+func (store *MemoryStore) GetQuote(server, channel string, id int64) (*Quote, error) {
+	quotes, _ := store.Quotes(server, channel)
+	for _, quote := range quotes {
+		if quote.ID == id {
+			return quote, nil
+		}
+	}
+	return nil, nil
+}
+
+// RandomQuote implements QuoteStore.
+func (store *MemoryStore) RandomQuote(server, channel string) (*Quote, error) {
+	quotes, _ := store.Quotes(server, channel)
+	if len(quotes) <= 0 {
+		return nil, nil
+	}
+	return quotes[rng.Intn(len(quotes))], nil
+}
+
+// Quotes implements QuoteStore.
+func (store *MemoryStore) Quotes(server, channel string) ([]*Quote, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var quotes []*Quote
+	for _, quote := range store.quotes {
+		if quote.Server == server && quote.Channel == channel {
+			found := *quote
+			quotes = append(quotes, &found)
+		}
+	}
+	return quotes, nil
+}
+
+// DeleteQuote implements QuoteStore.
+func (store *MemoryStore) DeleteQuote(server, channel string, id int64) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	for i, quote := range store.quotes {
+		if quote.ID == id && quote.Server == server && quote.Channel == channel {
+			store.quotes = append(store.quotes[:i], store.quotes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetFactoid implements FactoidStore.
+func (store *MemoryStore) GetFactoid(server, channel, name string) (*Factoid, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	factoid, ok := store.factoids[server+" "+channel+" "+name]
+	if !ok {
+		return nil, nil
+	}
+	found := *factoid
+	return &found, nil
+}
+
+// SaveFactoid implements FactoidStore.
+func (store *MemoryStore) SaveFactoid(factoid *Factoid, action string) error {
+	store.Lock()
+	defer store.Unlock()
+
+	key := factoid.Server + " " + factoid.Channel + " " + factoid.Name
+	saved := *factoid
+	if existing, ok := store.factoids[key]; ok {
+		saved.ID = existing.ID
+		saved.CreatedAt = existing.CreatedAt
+	} else {
+		store.nextID++
+		saved.ID = store.nextID
+	}
+	store.factoids[key] = &saved
+	factoid.ID = saved.ID
+
+	store.edits = append(store.edits, &memoryFactoidEdit{
+		FactoidEdit: FactoidEdit{Action: action, Value: factoid.Value, Nick: factoid.Nick, CreatedAt: factoid.UpdatedAt},
+		key:         key,
+	})
+	return nil
+}
+
+// DeleteFactoid implements FactoidStore.
+func (store *MemoryStore) DeleteFactoid(server, channel, name, nick string, deletedAt time.Time) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	key := server + " " + channel + " " + name
+	if _, ok := store.factoids[key]; !ok {
+		return false, nil
+	}
+	delete(store.factoids, key)
+
+	store.edits = append(store.edits, &memoryFactoidEdit{
+		FactoidEdit: FactoidEdit{Action: factoidForgotten, Nick: nick, CreatedAt: deletedAt},
+		key:         key,
+	})
+	return true, nil
+}
+
+// FactoidEdits implements FactoidStore.
+func (store *MemoryStore) FactoidEdits(server, channel, name string, limit int) ([]*FactoidEdit, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	key := server + " " + channel + " " + name
+	var edits []*FactoidEdit
+	for i := len(store.edits) - 1; i >= 0 && len(edits) < limit; i-- {
+		if store.edits[i].key == key {
+			edit := store.edits[i].FactoidEdit
+			edits = append(edits, &edit)
+		}
+	}
+	return edits, nil
+}
+
+// SaveLogLine implements ChannelLogStore.
+func (store *MemoryStore) SaveLogLine(line *LogLine) error {
+	store.Lock()
+	defer store.Unlock()
+
+	store.nextID++
+	line.ID = store.nextID
+
+	saved := *line
+	store.logLines = append(store.logLines, &saved)
+	return nil
+}
+
+// LogLines implements ChannelLogStore.
+func (store *MemoryStore) LogLines(filter *LinkFilter, limit int) ([]*LogLine, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var lines []*LogLine
+	for _, line := range store.logLines {
+		if filter.matchFields(line.Server, line.Channel, line.Nick, line.CreatedAt) {
+			found := *line
+			lines = append(lines, &found)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].CreatedAt.Before(lines[j].CreatedAt) })
+
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines, nil
+}
+
+// PurgeLog implements ChannelLogStore.
+func (store *MemoryStore) PurgeLog(filter *LinkFilter) (int, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var kept []*LogLine
+	for _, line := range store.logLines {
+		if !filter.matchFields(line.Server, line.Channel, line.Nick, line.CreatedAt) {
+			kept = append(kept, line)
+		}
+	}
+
+	deleted := len(store.logLines) - len(kept)
+	store.logLines = kept
+	return deleted, nil
+}
+
+// SavePoll implements PollStore.
+func (store *MemoryStore) SavePoll(poll *Poll) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if poll.Status == pollOpen {
+		for _, open := range store.polls {
+			if open.Server == poll.Server && open.Channel == poll.Channel && open.Status == pollOpen {
+				return errPollOpen
+			}
+		}
+	}
+
+	store.nextID++
+	poll.ID = store.nextID
+
+	store.polls = append(store.polls, copyPoll(poll))
+	return nil
+}
+
+// OpenPoll implements PollStore.
+func (store *MemoryStore) OpenPoll(server, channel string) (*Poll, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	for _, poll := range store.polls {
+		if poll.Server == server && poll.Channel == channel && poll.Status == pollOpen {
+			return copyPoll(poll), nil
+		}
+	}
+	return nil, nil
+}
+
+// DuePolls implements PollStore.
+func (store *MemoryStore) DuePolls(now time.Time) ([]*Poll, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	var polls []*Poll
+	for _, poll := range store.polls {
+		if poll.Status == pollOpen && !poll.ClosesAt.After(now) {
+			polls = append(polls, copyPoll(poll))
+		}
+	}
+	sort.SliceStable(polls, func(i, j int) bool { return polls[i].ClosesAt.Before(polls[j].ClosesAt) })
+
+	return polls, nil
+}
+
+// ClosePoll implements PollStore.
+func (store *MemoryStore) ClosePoll(id int64, status string) (bool, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	for _, poll := range store.polls {
+		if poll.ID == id && poll.Status == pollOpen {
+			poll.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SaveVote implements PollStore.
+func (store *MemoryStore) SaveVote(vote *PollVote) error {
+	store.Lock()
+	defer store.Unlock()
+
+	saved := *vote
+	for i, existing := range store.pollVotes {
+		if existing.PollID == vote.PollID && existing.Voter == vote.Voter {
+			store.pollVotes[i] = &saved
+			return nil
+		}
+	}
+	store.pollVotes = append(store.pollVotes, &saved)
+	return nil
+}
+
+// PollVotes implements PollStore.
+func (store *MemoryStore) PollVotes(id int64) (map[int]int, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	votes := make(map[int]int)
+	for _, vote := range store.pollVotes {
+		if vote.PollID == id {
+			votes[vote.Choice]++
+		}
+	}
+	return votes, nil
+}
+
+func copyPoll(poll *Poll) *Poll {
+	pollCopy := *poll
+	pollCopy.Options = append([]string(nil), poll.Options...)
+	return &pollCopy
+}
+
+// AddTriviaAnswer implements TriviaStore.
+func (store *MemoryStore) AddTriviaAnswer(answer *TriviaAnswer) error {
+	store.Lock()
+	defer store.Unlock()
+
+	saved := *answer
+	store.trivia = append(store.trivia, &saved)
+	return nil
+}
+
+// TriviaScores implements TriviaStore.
+func (store *MemoryStore) TriviaScores(server, channel string, limit int) ([]*TriviaScore, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	byNick := make(map[string]*TriviaScore)
+	var scores []*TriviaScore
+	for _, answer := range store.trivia {
+		if answer.Server != server || answer.Channel != channel {
+			continue
+		}
+
+		key := strings.ToLower(answer.Nick)
+		score, ok := byNick[key]
+		if !ok {
+			score = &TriviaScore{Nick: answer.Nick}
+			byNick[key] = score
+			scores = append(scores, score)
+		}
+		addTriviaAnswer(score, answer)
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Points != scores[j].Points {
+			return scores[i].Points > scores[j].Points
+		}
+		return strings.ToLower(scores[i].Nick) < strings.ToLower(scores[j].Nick)
+	})
+
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores, nil
+}
+
+// TriviaScore implements TriviaStore.
+func (store *MemoryStore) TriviaScore(server, channel, nick string) (*TriviaScore, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	score := &TriviaScore{Nick: nick}
+	for _, answer := range store.trivia {
+		if answer.Server == server && answer.Channel == channel && strings.EqualFold(answer.Nick, nick) {
+			addTriviaAnswer(score, answer)
+		}
+	}
+	return score, nil
+}
+
+func addTriviaAnswer(score *TriviaScore, answer *TriviaAnswer) {
+	score.Points += answer.Points
+	score.Answers++
+	if answer.Streak > score.BestStreak {
+		score.BestStreak = answer.Streak
+	}
+}
+
+// findReminders returns copies of the reminders `match` returns true for, soonest first.
+func (store *MemoryStore) findReminders(match func(*Reminder) bool) []*Reminder {
+	store.Lock()
+	defer store.Unlock()
+
+	var reminders []*Reminder
+	for _, reminder := range store.reminders {
+		if match(reminder) {
+			found := *reminder
+			reminders = append(reminders, &found)
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool { return reminders[i].RemindAt.Before(reminders[j].RemindAt) })
+
+	return reminders
+}
+
+// removeReminders deletes the reminders `match` returns true for and returns how many.
+// The caller must hold the lock.
+func (store *MemoryStore) removeReminders(match func(*Reminder) bool) int {
+	kept := store.reminders[:0]
+	for _, reminder := range store.reminders {
+		if !match(reminder) {
+			kept = append(kept, reminder)
+		}
+	}
+	removed := len(store.reminders) - len(kept)
+	store.reminders = kept
+
+	return removed
+}
+
+// removeMemos deletes and returns the memos `match` returns true for.
+// The caller must hold the lock.
+func (store *MemoryStore) removeMemos(match func(*Memo) bool) []*Memo {
+	var removed []*Memo
+	kept := store.memos[:0]
+	for _, memo := range store.memos {
+		if match(memo) {
+			removed = append(removed, memo)
+		} else {
+			kept = append(kept, memo)
+		}
+	}
+	store.memos = kept
+
+	return removed
+}
+
+// link returns link `id`, or nil. The caller must hold the lock.
+func (store *MemoryStore) link(id int64) *memoryLink {
+	for _, link := range store.links {
+		if link.ID == id {
+			return link
+		}
+	}
+	return nil
+}
+
+// remove deletes the links `match` returns true for and returns their IDs.
+// The caller must hold the lock.
+func (store *MemoryStore) remove(match func(*memoryLink) bool) []int64 {
+	var ids []int64
+	kept := store.links[:0]
+	for _, link := range store.links {
+		if match(link) {
+			ids = append(ids, link.ID)
+		} else {
+			kept = append(kept, link)
+		}
+	}
+	store.links = kept
+
+	return ids
+}
+
+// match returns the links matching `query`, the same as LinkQuery.Where().
+// The caller must hold the lock.
+func (store *MemoryStore) match(query *LinkQuery, server, channel string) []*memoryLink {
+	var matches []*memoryLink
+	for _, link := range store.links {
+		switch {
+		case link.Server != server:
+		case !query.AllChannels && link.Channel != channel:
+		case query.Nick != "" && link.Nick != query.Nick:
+		case query.Pattern != "" && !strings.Contains(strings.ToLower(link.URL), strings.ToLower(query.Pattern)):
+		case query.Domain != "" && !matchDomain(linkHost(&link.Link), query.Domain):
+		case !hasTags(link, query.Tags):
+		case query.Favorite != "" && !link.favorites[query.Favorite]:
+		case !query.Since.IsZero() && link.CreatedAt.Before(query.Since):
+		case !query.Until.IsZero() && !link.CreatedAt.Before(query.Until):
+		default:
+			matches = append(matches, link)
+		}
+	}
+	return matches
+}
+
+func (filter *LinkFilter) match(link *Link) bool {
+	return filter.matchFields(link.Server, link.Channel, link.Nick, link.CreatedAt)
+}
+
+func (filter *LinkFilter) matchFields(server, channel, nick string, createdAt time.Time) bool {
+	switch {
+	case filter.Server != "" && server != filter.Server:
+	case filter.Channel != "" && channel != filter.Channel:
+	case filter.Nick != "" && nick != filter.Nick:
+	case !filter.Since.IsZero() && createdAt.Before(filter.Since):
+	case !filter.Until.IsZero() && !createdAt.Before(filter.Until):
+	default:
+		return true
+	}
+	return false
+}
+
+func hasTags(link *memoryLink, tags []string) bool {
+	for _, tag := range tags {
+		if !link.tags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// linkHost returns the lowercased host of a link.
+func linkHost(link *Link) string {
+	url := link.CanonicalURL
+	if url == "" {
+		url = link.URL
+	}
+
+	match := linkHostRegexp.FindStringSubmatch(url)
+	if match == nil {
+		return ""
+	}
+	return strings.ToLower(match[1])
+}
+
+// matchDomain returns true if `host` is `domain` or one of its subdomains.
+func matchDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// topLinkCounts returns the `limit` highest counts, ties broken by name.
+func topLinkCounts(counts map[string]int, limit int) []*LinkCount {
+	var result []*LinkCount
+	for name, count := range counts {
+		result = append(result, &LinkCount{Name: name, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func sortByReposts(links []*memoryLink) {
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Reposts != links[j].Reposts {
+			return links[i].Reposts > links[j].Reposts
+		}
+		return links[i].CreatedAt.After(links[j].CreatedAt)
+	})
+}
+
+func copyLink(link *Link) *Link {
+	if link == nil {
+		return nil
+	}
+	linkCopy := *link
+	return &linkCopy
+}
+
+func copyLinks(links []*memoryLink) []*Link {
+	result := make([]*Link, len(links))
+	for i, link := range links {
+		result[i] = copyLink(&link.Link)
+	}
+	return result
+}
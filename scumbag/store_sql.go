@@ -0,0 +1,1093 @@
+package scumbag
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Database drivers for DatabaseConfig.Driver.
+const (
+	databaseDriverPostgres = "postgres"
+	databaseDriverSQLite   = "sqlite"
+)
+
+// SQLStore implements the stores in store.go on a SQL database.
+type SQLStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+}
+
+// sqlDialect adapts the (Postgres) queries in this file to a database driver.
+type sqlDialect struct {
+	// Rewrites Postgres-only SQL, or nil.
+	replacer *strings.Replacer
+
+	// bindTime converts time arguments before they're written;
+	// scanTime converts them back when read.
+	bindTime func(time.Time) time.Time
+	scanTime func(time.Time) time.Time
+
+	// Moves a table's ID sequence past rows inserted with explicit IDs, or empty.
+	resetIDSQL string
+
+	// Embedded migrations for the driver.
+	migrationDir string
+}
+
+var postgresDialect = &sqlDialect{
+	// Columns are "timestamp without time zone" in local time; see localTime().
+	bindTime:     func(t time.Time) time.Time { return t.Local() },
+	scanTime:     localTime,
+	resetIDSQL:   "SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s;",
+	migrationDir: "migrations/postgres",
+}
+
+// rebind rewrites `query` and `args` for the dialect.
+func (dialect *sqlDialect) rebind(query string, args []interface{}) (string, []interface{}) {
+	if dialect.replacer != nil {
+		query = dialect.replacer.Replace(query)
+	}
+
+	bound := make([]interface{}, len(args))
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			arg = dialect.bindTime(t)
+		}
+		bound[i] = arg
+	}
+
+	return query, bound
+}
+
+// openDatabase connects to the database in `config` and returns a store on it.
+func openDatabase(config *DatabaseConfig) (*sql.DB, *SQLStore, error) {
+	var db *sql.DB
+	var store *SQLStore
+	var err error
+
+	switch config.Driver {
+	case "", databaseDriverPostgres:
+		params := fmt.Sprintf("host=%s sslmode=%s dbname=%s user=%s password=%s", config.Host, config.SSL, config.Name, config.User, config.Password)
+		if db, err = sql.Open("postgres", params); err != nil {
+			return nil, nil, err
+		}
+		store = NewPostgresStore(db)
+
+		// SQLite is limited to one connection by openSQLite().
+		if config.MaxOpenConns > 0 {
+			db.SetMaxOpenConns(config.MaxOpenConns)
+		}
+
+	case databaseDriverSQLite:
+		if db, err = openSQLite(config.Path); err != nil {
+			return nil, nil, err
+		}
+		store = NewSQLiteStore(db)
+
+	default:
+		return nil, nil, fmt.Errorf("Unknown database driver: %s", config.Driver)
+	}
+
+	if config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+
+	if config.ConnMaxLifetime != "" {
+		lifetime, err := time.ParseDuration(config.ConnMaxLifetime)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		db.SetConnMaxLifetime(lifetime)
+	}
+
+	return db, store, nil
+}
+
+// sqlQueryer is a *sql.DB or *sql.Tx.
+type sqlQueryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// NewPostgresStore returns a new SQLStore on a Postgres database.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: postgresDialect}
+}
+
+// FindLink implements LinkStore.
+func (store *SQLStore) FindLink(server, channel, url, canonicalURL string) (*Link, error) {
+	// Links saved before canonical_url existed only match on the exact URL.
+	return store.queryLink("SELECT "+linkColumns+" FROM links WHERE (canonical_url=$1 OR url=$2) AND server=$3 AND channel=$4 ORDER BY created_at ASC LIMIT 1;", canonicalURL, url, server, channel)
+}
+
+// SaveLink implements LinkStore.
+func (store *SQLStore) SaveLink(link *Link) error {
+	return store.saveLink(store.db, link)
+}
+
+// SaveLinks implements LinkStore.
+func (store *SQLStore) SaveLinks(links []*Link) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, link := range links {
+		if err := store.saveLink(tx, link); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (store *SQLStore) saveLink(q sqlQueryer, link *Link) error {
+	return store.queryRow(q, "INSERT INTO links(nick, url, canonical_url, server, channel, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id;",
+		link.Nick, link.URL, link.CanonicalURL, link.Server, link.Channel, link.CreatedAt).Scan(&link.ID)
+}
+
+// AddRepost implements LinkStore.
+func (store *SQLStore) AddRepost(id int64) error {
+	_, err := store.exec(store.db, "UPDATE links SET reposts = reposts + 1 WHERE id=$1;", id)
+	return err
+}
+
+// GetLink implements LinkStore.
+func (store *SQLStore) GetLink(server, channel string, id int64) (*Link, error) {
+	return store.queryLink("SELECT "+linkColumns+" FROM links WHERE id=$1 AND server=$2 AND channel=$3;", id, server, channel)
+}
+
+// SearchLinks implements LinkStore.
+func (store *SQLStore) SearchLinks(query *LinkQuery, server, channel string) ([]*Link, error) {
+	sql, args := query.SQL(server, channel)
+	return store.queryLinks(sql, args...)
+}
+
+// TopLinks implements LinkStore.
+func (store *SQLStore) TopLinks(server, channel string, limit int) ([]*Link, error) {
+	return store.queryLinks("SELECT "+linkColumns+" FROM links WHERE reposts > 0 AND server=$1 AND channel=$2 ORDER BY reposts DESC, created_at DESC LIMIT $3;", server, channel, limit)
+}
+
+// LinkStats implements LinkStore.
+func (store *SQLStore) LinkStats(query *LinkQuery, server, channel string) (*LinkStats, error) {
+	where, args := query.Where(server, channel)
+	stats := &LinkStats{}
+
+	if err := store.queryRow(store.db, "SELECT count(*) FROM links WHERE "+where+";", args...).Scan(&stats.Total); err != nil {
+		return nil, err
+	}
+
+	// Not min(created_at): SQLite returns aggregates of timestamps as strings.
+	err := store.queryRow(store.db, "SELECT created_at FROM links WHERE "+where+" ORDER BY created_at ASC LIMIT 1;", args...).Scan(&stats.First)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	stats.First = store.dialect.scanTime(stats.First)
+
+	if stats.TopPosters, err = store.linkCounts("nick", where, args, query.Limit); err != nil {
+		return nil, err
+	}
+
+	if stats.TopDomains, err = store.linkCounts(linkHostSQL, where, args, query.Limit); err != nil {
+		return nil, err
+	}
+
+	stats.MostReposted, err = store.queryLink("SELECT "+linkColumns+" FROM links WHERE "+where+" AND reposts > 0 ORDER BY reposts DESC, created_at DESC LIMIT 1;", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	end := linkActivityEnd(query, time.Now())
+	activityArgs := append(append([]interface{}(nil), args...), linkActivityStart(end))
+	rows, err := store.query(store.db, fmt.Sprintf("SELECT created_at FROM links WHERE %s AND created_at >= $%d;", where, len(activityArgs)), activityArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, err
+		}
+		times = append(times, store.dialect.scanTime(createdAt))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	stats.PerDay, stats.PerWeek = linkActivity(times, end)
+
+	return stats, nil
+}
+
+// linkCounts returns the `limit` most common values of the `column` expression.
+func (store *SQLStore) linkCounts(column, where string, args []interface{}, limit int) ([]*LinkCount, error) {
+	args = append(args, limit)
+	sql := fmt.Sprintf("SELECT %s AS name, count(*) AS total FROM links WHERE %s GROUP BY name ORDER BY total DESC, name LIMIT $%d;", column, where, len(args))
+
+	rows, err := store.query(store.db, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*LinkCount
+	for rows.Next() {
+		count := &LinkCount{}
+		var name *string
+		if err := rows.Scan(&name, &count.Count); err != nil {
+			return nil, err
+		}
+
+		if name != nil {
+			count.Name = *name
+			counts = append(counts, count)
+		}
+	}
+
+	return counts, rows.Err()
+}
+
+// Links implements LinkStore.
+func (store *SQLStore) Links(filter *LinkFilter) ([]*Link, error) {
+	where, args := filter.where()
+	return store.queryLinks("SELECT "+linkColumns+" FROM links"+where+" ORDER BY created_at ASC, id ASC;", args...)
+}
+
+// CountLinks implements LinkStore.
+func (store *SQLStore) CountLinks(filter *LinkFilter) (int, error) {
+	where, args := filter.where()
+
+	var count int
+	err := store.queryRow(store.db, "SELECT count(*) FROM links"+where+";", args...).Scan(&count)
+	return count, err
+}
+
+// CanonicalizeLinks implements LinkStore.
+func (store *SQLStore) CanonicalizeLinks(canonicalURLs map[int64]string, merges []*LinkMerge) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, canonicalURL := range canonicalURLs {
+		if _, err := store.exec(tx, "UPDATE links SET canonical_url=$1 WHERE id=$2;", canonicalURL, id); err != nil {
+			return err
+		}
+	}
+
+	for _, merge := range merges {
+		if err := store.mergeLink(tx, merge); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (store *SQLStore) mergeLink(tx *sql.Tx, merge *LinkMerge) error {
+	if _, err := store.exec(tx, "UPDATE links SET reposts=$1 WHERE id=$2;", merge.Reposts, merge.OriginalID); err != nil {
+		return err
+	}
+
+	// Tags and favorites would be deleted with the duplicate, so move them to
+	// the original first, except ones it already has.
+	if _, err := store.exec(tx, "INSERT INTO link_tags(link_id, tag, nick, created_at) SELECT $1, tag, nick, created_at FROM link_tags WHERE link_id=$2 ON CONFLICT (link_id, tag) DO NOTHING;", merge.OriginalID, merge.DuplicateID); err != nil {
+		return err
+	}
+	if _, err := store.exec(tx, "INSERT INTO link_favorites(link_id, server, nick, created_at) SELECT $1, server, nick, created_at FROM link_favorites WHERE link_id=$2 ON CONFLICT (link_id, nick) DO NOTHING;", merge.OriginalID, merge.DuplicateID); err != nil {
+		return err
+	}
+	if _, err := store.exec(tx, "DELETE FROM link_tags WHERE link_id=$1;", merge.DuplicateID); err != nil {
+		return err
+	}
+	if _, err := store.exec(tx, "DELETE FROM link_favorites WHERE link_id=$1;", merge.DuplicateID); err != nil {
+		return err
+	}
+
+	_, err := store.exec(tx, "DELETE FROM links WHERE id=$1;", merge.DuplicateID)
+	return err
+}
+
+// PurgeLinks implements LinkStore.
+func (store *SQLStore) PurgeLinks(purge *LinkPurge) ([]int64, error) {
+	where, args := purge.where()
+
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := store.query(tx, "DELETE FROM links"+where+" RETURNING id;", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Retention runs hourly on every channel; only log purges that did something.
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	_, err = store.exec(tx, "INSERT INTO link_purges(server, channel, nick, requested_by, reason, deleted, created_at) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		purge.Server, purge.Channel, purge.Nick, purge.RequestedBy, purge.Reason, len(ids), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, tx.Commit()
+}
+
+// LinksToCheck implements LinkStore.
+func (store *SQLStore) LinksToCheck(checkedBefore time.Time, limit int) ([]*Link, error) {
+	return store.queryLinks("SELECT "+linkColumns+" FROM links WHERE checked_at IS NULL OR checked_at < $1 ORDER BY checked_at ASC NULLS FIRST LIMIT $2;", checkedBefore, limit)
+}
+
+// SetLinkStatus implements LinkStore.
+func (store *SQLStore) SetLinkStatus(id int64, statusCode int, dead bool, checkedAt time.Time) error {
+	var err error
+	if dead {
+		_, err = store.exec(store.db, "UPDATE links SET status_code=$1, checked_at=$2, dead_since=COALESCE(dead_since, $2) WHERE id=$3;", statusCode, checkedAt, id)
+	} else {
+		_, err = store.exec(store.db, "UPDATE links SET status_code=$1, checked_at=$2, dead_since=NULL WHERE id=$3;", statusCode, checkedAt, id)
+	}
+	return err
+}
+
+// SetLinkArchived implements LinkStore.
+func (store *SQLStore) SetLinkArchived(id int64, archivedAt time.Time) error {
+	_, err := store.exec(store.db, "UPDATE links SET archived_at=$1 WHERE id=$2;", archivedAt, id)
+	return err
+}
+
+// TagLink implements LinkStore.
+func (store *SQLStore) TagLink(id int64, tag, nick string, createdAt time.Time) error {
+	_, err := store.exec(store.db, "INSERT INTO link_tags(link_id, tag, nick, created_at) VALUES($1, $2, $3, $4) ON CONFLICT (link_id, tag) DO NOTHING;", id, tag, nick, createdAt)
+	return err
+}
+
+// UntagLink implements LinkStore.
+func (store *SQLStore) UntagLink(id int64, tag string) error {
+	_, err := store.exec(store.db, "DELETE FROM link_tags WHERE link_id=$1 AND tag=$2;", id, tag)
+	return err
+}
+
+// LinkTags implements LinkStore.
+func (store *SQLStore) LinkTags(id int64) ([]string, error) {
+	rows, err := store.query(store.db, "SELECT tag FROM link_tags WHERE link_id=$1 ORDER BY tag;", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// FavoriteLink implements LinkStore.
+func (store *SQLStore) FavoriteLink(id int64, server, nick string, createdAt time.Time) (bool, error) {
+	return store.execChanged("INSERT INTO link_favorites(link_id, server, nick, created_at) VALUES($1, $2, $3, $4) ON CONFLICT (link_id, nick) DO NOTHING;", id, server, nick, createdAt)
+}
+
+// UnfavoriteLink implements LinkStore.
+func (store *SQLStore) UnfavoriteLink(id int64, nick string) (bool, error) {
+	return store.execChanged("DELETE FROM link_favorites WHERE link_id=$1 AND nick=$2;", id, nick)
+}
+
+// IgnoredNick implements IgnoreStore.
+func (store *SQLStore) IgnoredNick(server, nick string) (bool, error) {
+	var result bool
+	err := store.queryRow(store.db, "SELECT EXISTS (SELECT 1 FROM ignored_nicks WHERE server=$1 AND nick=$2);", server, nick).Scan(&result)
+	return result, err
+}
+
+// IgnoreNick implements IgnoreStore.
+func (store *SQLStore) IgnoreNick(server, nick string, createdAt time.Time) (bool, error) {
+	ignored, err := store.IgnoredNick(server, nick)
+	if err != nil || ignored {
+		return false, err
+	}
+
+	_, err = store.exec(store.db, "INSERT INTO ignored_nicks(server, nick, created_at) VALUES($1, $2, $3) RETURNING id;", server, nick, createdAt)
+	return err == nil, err
+}
+
+// UnignoreNick implements IgnoreStore.
+func (store *SQLStore) UnignoreNick(server, nick string) error {
+	_, err := store.exec(store.db, "DELETE FROM ignored_nicks WHERE server=$1 AND nick=$2;", server, nick)
+	return err
+}
+
+// SaveSeen implements SeenStore.
+func (store *SQLStore) SaveSeen(seen *Seen) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := store.exec(tx, "DELETE FROM seen WHERE server=$1 AND channel=$2 AND lower(nick)=lower($3);", seen.Server, seen.Channel, seen.Nick); err != nil {
+		return err
+	}
+
+	_, err = store.exec(tx, "INSERT INTO seen(server, channel, nick, action, message, target, created_at) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		seen.Server, seen.Channel, seen.Nick, seen.Action, seen.Message, seen.Target, seen.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LastSeen implements SeenStore.
+func (store *SQLStore) LastSeen(server, channel, nick string) (*Seen, error) {
+	query := "SELECT server, channel, nick, action, message, target, created_at FROM seen WHERE server=$1 AND lower(nick)=lower($2)"
+	args := []interface{}{server, nick}
+	if channel != "" {
+		query += " AND channel=$3"
+		args = append(args, channel)
+	}
+	query += " ORDER BY created_at DESC LIMIT 1;"
+
+	seen := &Seen{}
+	err := store.queryRow(store.db, query, args...).Scan(&seen.Server, &seen.Channel, &seen.Nick, &seen.Action, &seen.Message, &seen.Target, &seen.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen.CreatedAt = store.dialect.scanTime(seen.CreatedAt)
+
+	return seen, nil
+}
+
+// memoColumns are the columns scanned by queryMemos().
+const memoColumns = "id, server, channel, sender, recipient, message, private, created_at"
+
+// SaveMemo implements MemoStore.
+func (store *SQLStore) SaveMemo(memo *Memo) error {
+	return store.queryRow(store.db, "INSERT INTO memos(server, channel, sender, recipient, message, private, created_at) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id;",
+		memo.Server, memo.Channel, memo.Sender, memo.Recipient, memo.Message, memo.Private, memo.CreatedAt).Scan(&memo.ID)
+}
+
+// SentMemos implements MemoStore.
+func (store *SQLStore) SentMemos(server, sender string) ([]*Memo, error) {
+	return store.queryMemos(store.db, "SELECT "+memoColumns+" FROM memos WHERE server=$1 AND lower(sender)=lower($2) ORDER BY created_at, id;", server, sender)
+}
+
+// CancelMemo implements MemoStore.
+func (store *SQLStore) CancelMemo(server, sender string, id int64) (bool, error) {
+	return store.execChanged("DELETE FROM memos WHERE id=$1 AND server=$2 AND lower(sender)=lower($3);", id, server, sender)
+}
+
+// TakeMemos implements MemoStore.
+func (store *SQLStore) TakeMemos(server, recipient string) ([]*Memo, error) {
+	memos, err := store.queryMemos(store.db, "DELETE FROM memos WHERE server=$1 AND lower(recipient)=lower($2) RETURNING "+memoColumns+";", server, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(memos, func(i, j int) bool { return memos[i].ID < memos[j].ID })
+	return memos, nil
+}
+
+func (store *SQLStore) queryMemos(q sqlQueryer, query string, args ...interface{}) ([]*Memo, error) {
+	rows, err := store.query(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memos []*Memo
+	for rows.Next() {
+		memo := &Memo{}
+		if err := rows.Scan(&memo.ID, &memo.Server, &memo.Channel, &memo.Sender, &memo.Recipient, &memo.Message, &memo.Private, &memo.CreatedAt); err != nil {
+			return nil, err
+		}
+		memo.CreatedAt = store.dialect.scanTime(memo.CreatedAt)
+
+		memos = append(memos, memo)
+	}
+
+	return memos, rows.Err()
+}
+
+// reminderColumns are the columns scanned by queryReminders().
+const reminderColumns = "id, server, channel, nick, mention, message, timezone, repeat, remind_at, created_at"
+
+// SaveReminder implements ReminderStore.
+func (store *SQLStore) SaveReminder(reminder *Reminder) error {
+	return store.queryRow(store.db, "INSERT INTO reminders(server, channel, nick, mention, message, timezone, repeat, remind_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;",
+		reminder.Server, reminder.Channel, reminder.Nick, reminder.Mention, reminder.Message, reminder.Timezone, reminder.Repeat, reminder.RemindAt, reminder.CreatedAt).Scan(&reminder.ID)
+}
+
+// NickReminders implements ReminderStore.
+func (store *SQLStore) NickReminders(server, nick string) ([]*Reminder, error) {
+	return store.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE server=$1 AND lower(nick)=lower($2) ORDER BY remind_at, id;", server, nick)
+}
+
+// CancelReminder implements ReminderStore.
+func (store *SQLStore) CancelReminder(server, nick string, id int64) (bool, error) {
+	return store.execChanged("DELETE FROM reminders WHERE id=$1 AND server=$2 AND lower(nick)=lower($3);", id, server, nick)
+}
+
+// DueReminders implements ReminderStore.
+func (store *SQLStore) DueReminders(now time.Time) ([]*Reminder, error) {
+	return store.queryReminders("SELECT "+reminderColumns+" FROM reminders WHERE remind_at <= $1 ORDER BY remind_at, id;", now)
+}
+
+// RescheduleReminder implements ReminderStore.
+func (store *SQLStore) RescheduleReminder(id int64, remindAt time.Time) error {
+	_, err := store.exec(store.db, "UPDATE reminders SET remind_at=$1 WHERE id=$2;", remindAt, id)
+	return err
+}
+
+// DeleteReminder implements ReminderStore.
+func (store *SQLStore) DeleteReminder(id int64) error {
+	_, err := store.exec(store.db, "DELETE FROM reminders WHERE id=$1;", id)
+	return err
+}
+
+func (store *SQLStore) queryReminders(query string, args ...interface{}) ([]*Reminder, error) {
+	rows, err := store.query(store.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*Reminder
+	for rows.Next() {
+		r := &Reminder{}
+		if err := rows.Scan(&r.ID, &r.Server, &r.Channel, &r.Nick, &r.Mention, &r.Message, &r.Timezone, &r.Repeat, &r.RemindAt, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.RemindAt = store.dialect.scanTime(r.RemindAt)
+		r.CreatedAt = store.dialect.scanTime(r.CreatedAt)
+
+		reminders = append(reminders, r)
+	}
+
+	return reminders, rows.Err()
+}
+
+// AddKarma implements KarmaStore.
+func (store *SQLStore) AddKarma(vote *KarmaVote) error {
+	_, err := store.exec(store.db, "INSERT INTO karma(server, channel, thing, nick, delta, reason, created_at) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		vote.Server, vote.Channel, vote.Thing, vote.Nick, vote.Delta, vote.Reason, vote.CreatedAt)
+	return err
+}
+
+// LastKarmaVote implements KarmaStore.
+func (store *SQLStore) LastKarmaVote(server, channel, nick, thing string) (time.Time, error) {
+	var last time.Time
+	err := store.queryRow(store.db, "SELECT created_at FROM karma WHERE server=$1 AND channel=$2 AND lower(nick)=lower($3) AND thing=$4 ORDER BY created_at DESC LIMIT 1;", server, channel, nick, thing).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return store.dialect.scanTime(last), nil
+}
+
+// Karma implements KarmaStore.
+func (store *SQLStore) Karma(server, channel, thing string, reasons int) (*Karma, error) {
+	karma := &Karma{Thing: thing}
+	err := store.queryRow(store.db, "SELECT COALESCE(SUM(CASE WHEN delta > 0 THEN 1 ELSE 0 END), 0), COALESCE(SUM(CASE WHEN delta < 0 THEN 1 ELSE 0 END), 0) FROM karma WHERE server=$1 AND channel=$2 AND thing=$3;",
+		server, channel, thing).Scan(&karma.Up, &karma.Down)
+	if err != nil {
+		return nil, err
+	}
+	karma.Score = karma.Up - karma.Down
+
+	rows, err := store.query(store.db, "SELECT reason FROM karma WHERE server=$1 AND channel=$2 AND thing=$3 AND reason <> '' ORDER BY created_at DESC, id DESC LIMIT $4;", server, channel, thing, reasons)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reason string
+		if err := rows.Scan(&reason); err != nil {
+			return nil, err
+		}
+		karma.Reasons = append(karma.Reasons, reason)
+	}
+
+	return karma, rows.Err()
+}
+
+// TopKarma implements KarmaStore.
+func (store *SQLStore) TopKarma(server, channel string, limit int, lowest bool) ([]*Karma, error) {
+	order := "DESC"
+	if lowest {
+		order = "ASC"
+	}
+
+	rows, err := store.query(store.db, "SELECT thing, SUM(delta) AS score FROM karma WHERE server=$1 AND channel=$2 GROUP BY thing ORDER BY score "+order+", thing LIMIT $3;", server, channel, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []*Karma
+	for rows.Next() {
+		karma := &Karma{}
+		if err := rows.Scan(&karma.Thing, &karma.Score); err != nil {
+			return nil, err
+		}
+		scores = append(scores, karma)
+	}
+
+	return scores, rows.Err()
+}
+
+// quoteColumns are the columns scanned by queryQuotes().
+const quoteColumns = "id, server, channel, nick, text, added_by, created_at"
+
+// SaveQuote implements QuoteStore.
+func (store *SQLStore) SaveQuote(quote *Quote) error {
+	return store.queryRow(store.db, "INSERT INTO quotes(server, channel, nick, text, added_by, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id;",
+		quote.Server, quote.Channel, quote.Nick, quote.Text, quote.AddedBy, quote.CreatedAt).Scan(&quote.ID)
+}
+
+// GetQuote implements QuoteStore.
+func (store *SQLStore) GetQuote(server, channel string, id int64) (*Quote, error) {
+	return store.queryQuote("SELECT "+quoteColumns+" FROM quotes WHERE id=$1 AND server=$2 AND channel=$3;", id, server, channel)
+}
+
+// RandomQuote implements QuoteStore.
+func (store *SQLStore) RandomQuote(server, channel string) (*Quote, error) {
+	return store.queryQuote("SELECT "+quoteColumns+" FROM quotes WHERE server=$1 AND channel=$2 ORDER BY random() LIMIT 1;", server, channel)
+}
+
+// Quotes implements QuoteStore.
+func (store *SQLStore) Quotes(server, channel string) ([]*Quote, error) {
+	return store.queryQuotes("SELECT "+quoteColumns+" FROM quotes WHERE server=$1 AND channel=$2 ORDER BY id;", server, channel)
+}
+
+// DeleteQuote implements QuoteStore.
+func (store *SQLStore) DeleteQuote(server, channel string, id int64) (bool, error) {
+	return store.execChanged("DELETE FROM quotes WHERE id=$1 AND server=$2 AND channel=$3;", id, server, channel)
+}
+
+func (store *SQLStore) queryQuote(query string, args ...interface{}) (*Quote, error) {
+	quotes, err := store.queryQuotes(query, args...)
+	if err != nil || len(quotes) <= 0 {
+		return nil, err
+	}
+	return quotes[0], nil
+}
+
+func (store *SQLStore) queryQuotes(query string, args ...interface{}) ([]*Quote, error) {
+	rows, err := store.query(store.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []*Quote
+	for rows.Next() {
+		quote := &Quote{}
+		if err := rows.Scan(&quote.ID, &quote.Server, &quote.Channel, &quote.Nick, &quote.Text, &quote.AddedBy, &quote.CreatedAt); err != nil {
+			return nil, err
+		}
+		quote.CreatedAt = store.dialect.scanTime(quote.CreatedAt)
+
+		quotes = append(quotes, quote)
+	}
+
+	return quotes, rows.Err()
+}
+
+// GetFactoid implements FactoidStore.
+func (store *SQLStore) GetFactoid(server, channel, name string) (*Factoid, error) {
+	factoid := &Factoid{}
+	err := store.queryRow(store.db, "SELECT id, server, channel, name, value, locked, nick, created_at, updated_at FROM factoids WHERE server=$1 AND channel=$2 AND name=$3;", server, channel, name).
+		Scan(&factoid.ID, &factoid.Server, &factoid.Channel, &factoid.Name, &factoid.Value, &factoid.Locked, &factoid.Nick, &factoid.CreatedAt, &factoid.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	factoid.CreatedAt = store.dialect.scanTime(factoid.CreatedAt)
+	factoid.UpdatedAt = store.dialect.scanTime(factoid.UpdatedAt)
+
+	return factoid, nil
+}
+
+// SaveFactoid implements FactoidStore.
+func (store *SQLStore) SaveFactoid(factoid *Factoid, action string) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = store.queryRow(tx, "INSERT INTO factoids(server, channel, name, value, locked, nick, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) "+
+		"ON CONFLICT (server, channel, name) DO UPDATE SET value=excluded.value, locked=excluded.locked, nick=excluded.nick, updated_at=excluded.updated_at RETURNING id;",
+		factoid.Server, factoid.Channel, factoid.Name, factoid.Value, factoid.Locked, factoid.Nick, factoid.CreatedAt, factoid.UpdatedAt).Scan(&factoid.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := store.addFactoidEdit(tx, factoid.Server, factoid.Channel, factoid.Name, action, factoid.Value, factoid.Nick, factoid.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteFactoid implements FactoidStore.
+func (store *SQLStore) DeleteFactoid(server, channel, name, nick string, deletedAt time.Time) (bool, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := store.exec(tx, "DELETE FROM factoids WHERE server=$1 AND channel=$2 AND name=$3;", server, channel, name)
+	if err != nil {
+		return false, err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted <= 0 {
+		return false, err
+	}
+
+	if err := store.addFactoidEdit(tx, server, channel, name, factoidForgotten, "", nick, deletedAt); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// FactoidEdits implements FactoidStore.
+func (store *SQLStore) FactoidEdits(server, channel, name string, limit int) ([]*FactoidEdit, error) {
+	rows, err := store.query(store.db, "SELECT action, value, nick, created_at FROM factoid_edits WHERE server=$1 AND channel=$2 AND name=$3 ORDER BY created_at DESC, id DESC LIMIT $4;", server, channel, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []*FactoidEdit
+	for rows.Next() {
+		edit := &FactoidEdit{}
+		if err := rows.Scan(&edit.Action, &edit.Value, &edit.Nick, &edit.CreatedAt); err != nil {
+			return nil, err
+		}
+		edit.CreatedAt = store.dialect.scanTime(edit.CreatedAt)
+
+		edits = append(edits, edit)
+	}
+
+	return edits, rows.Err()
+}
+
+func (store *SQLStore) addFactoidEdit(q sqlQueryer, server, channel, name, action, value, nick string, createdAt time.Time) error {
+	_, err := store.exec(q, "INSERT INTO factoid_edits(server, channel, name, action, value, nick, created_at) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		server, channel, name, action, value, nick, createdAt)
+	return err
+}
+
+// SaveLogLine implements ChannelLogStore.
+func (store *SQLStore) SaveLogLine(line *LogLine) error {
+	return store.queryRow(store.db, "INSERT INTO channel_log(server, channel, nick, kind, text, created_at) VALUES($1, $2, $3, $4, $5, $6) RETURNING id;",
+		line.Server, line.Channel, line.Nick, line.Kind, line.Text, line.CreatedAt).Scan(&line.ID)
+}
+
+// LogLines implements ChannelLogStore.
+func (store *SQLStore) LogLines(filter *LinkFilter, limit int) ([]*LogLine, error) {
+	where, args := filter.where()
+	query := "SELECT id, server, channel, nick, kind, text, created_at FROM channel_log" + where + " ORDER BY created_at DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := store.query(store.db, query+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*LogLine
+	for rows.Next() {
+		line := &LogLine{}
+		if err := rows.Scan(&line.ID, &line.Server, &line.Channel, &line.Nick, &line.Kind, &line.Text, &line.CreatedAt); err != nil {
+			return nil, err
+		}
+		line.CreatedAt = store.dialect.scanTime(line.CreatedAt)
+
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Oldest first.
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines, nil
+}
+
+// PurgeLog implements ChannelLogStore.
+func (store *SQLStore) PurgeLog(filter *LinkFilter) (int, error) {
+	where, args := filter.where()
+	result, err := store.exec(store.db, "DELETE FROM channel_log"+where+";", args...)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// pollColumns are the columns scanned by queryPolls().
+const pollColumns = "id, server, channel, question, options, nick, status, closes_at, created_at"
+
+// SavePoll implements PollStore.
+func (store *SQLStore) SavePoll(poll *Poll) error {
+	// Nothing is returned if polls_server_channel_open_idx already has the channel.
+	err := store.queryRow(store.db, `INSERT INTO polls(server, channel, question, options, nick, status, closes_at, created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (server, channel) WHERE status='open' DO NOTHING RETURNING id;`,
+		poll.Server, poll.Channel, poll.Question, strings.Join(poll.Options, "\n"), poll.Nick, poll.Status, poll.ClosesAt, poll.CreatedAt).Scan(&poll.ID)
+	if err == sql.ErrNoRows {
+		return errPollOpen
+	}
+	return err
+}
+
+// OpenPoll implements PollStore.
+func (store *SQLStore) OpenPoll(server, channel string) (*Poll, error) {
+	polls, err := store.queryPolls("SELECT "+pollColumns+" FROM polls WHERE server=$1 AND channel=$2 AND status=$3 ORDER BY id LIMIT 1;", server, channel, pollOpen)
+	if err != nil || len(polls) <= 0 {
+		return nil, err
+	}
+	return polls[0], nil
+}
+
+// DuePolls implements PollStore.
+func (store *SQLStore) DuePolls(now time.Time) ([]*Poll, error) {
+	return store.queryPolls("SELECT "+pollColumns+" FROM polls WHERE status=$1 AND closes_at <= $2 ORDER BY closes_at, id;", pollOpen, now)
+}
+
+// ClosePoll implements PollStore.
+func (store *SQLStore) ClosePoll(id int64, status string) (bool, error) {
+	return store.execChanged("UPDATE polls SET status=$1 WHERE id=$2 AND status=$3;", status, id, pollOpen)
+}
+
+// SaveVote implements PollStore.
+func (store *SQLStore) SaveVote(vote *PollVote) error {
+	_, err := store.exec(store.db, `INSERT INTO poll_votes(poll_id, voter, nick, choice, created_at) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (poll_id, voter) DO UPDATE SET nick=excluded.nick, choice=excluded.choice, created_at=excluded.created_at;`,
+		vote.PollID, vote.Voter, vote.Nick, vote.Choice, vote.CreatedAt)
+	return err
+}
+
+// PollVotes implements PollStore.
+func (store *SQLStore) PollVotes(id int64) (map[int]int, error) {
+	rows, err := store.query(store.db, "SELECT choice, count(*) FROM poll_votes WHERE poll_id=$1 GROUP BY choice;", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make(map[int]int)
+	for rows.Next() {
+		var choice, count int
+		if err := rows.Scan(&choice, &count); err != nil {
+			return nil, err
+		}
+		votes[choice] = count
+	}
+
+	return votes, rows.Err()
+}
+
+func (store *SQLStore) queryPolls(query string, args ...interface{}) ([]*Poll, error) {
+	rows, err := store.query(store.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var polls []*Poll
+	for rows.Next() {
+		poll := &Poll{}
+		var options string
+		if err := rows.Scan(&poll.ID, &poll.Server, &poll.Channel, &poll.Question, &options, &poll.Nick, &poll.Status, &poll.ClosesAt, &poll.CreatedAt); err != nil {
+			return nil, err
+		}
+		poll.Options = strings.Split(options, "\n")
+		poll.ClosesAt = store.dialect.scanTime(poll.ClosesAt)
+		poll.CreatedAt = store.dialect.scanTime(poll.CreatedAt)
+
+		polls = append(polls, poll)
+	}
+
+	return polls, rows.Err()
+}
+
+// AddTriviaAnswer implements TriviaStore.
+func (store *SQLStore) AddTriviaAnswer(answer *TriviaAnswer) error {
+	_, err := store.exec(store.db, "INSERT INTO trivia_answers(server, channel, nick, question, points, streak, created_at) VALUES($1, $2, $3, $4, $5, $6, $7);",
+		answer.Server, answer.Channel, answer.Nick, answer.Question, answer.Points, answer.Streak, answer.CreatedAt)
+	return err
+}
+
+// TriviaScores implements TriviaStore.
+func (store *SQLStore) TriviaScores(server, channel string, limit int) ([]*TriviaScore, error) {
+	rows, err := store.query(store.db, "SELECT MAX(nick), SUM(points) AS score, COUNT(*), MAX(streak) FROM trivia_answers WHERE server=$1 AND channel=$2 GROUP BY lower(nick) ORDER BY score DESC, lower(nick) LIMIT $3;", server, channel, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []*TriviaScore
+	for rows.Next() {
+		score := &TriviaScore{}
+		if err := rows.Scan(&score.Nick, &score.Points, &score.Answers, &score.BestStreak); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+
+	return scores, rows.Err()
+}
+
+// TriviaScore implements TriviaStore.
+func (store *SQLStore) TriviaScore(server, channel, nick string) (*TriviaScore, error) {
+	score := &TriviaScore{Nick: nick}
+	err := store.queryRow(store.db, "SELECT COALESCE(SUM(points), 0), COUNT(*), COALESCE(MAX(streak), 0) FROM trivia_answers WHERE server=$1 AND channel=$2 AND lower(nick)=lower($3);",
+		server, channel, nick).Scan(&score.Points, &score.Answers, &score.BestStreak)
+	if err != nil {
+		return nil, err
+	}
+	return score, nil
+}
+
+func (store *SQLStore) execChanged(query string, args ...interface{}) (bool, error) {
+	result, err := store.exec(store.db, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+	return changed > 0, err
+}
+
+// queryLink returns the first link from a query selecting linkColumns, or nil.
+func (store *SQLStore) queryLink(query string, args ...interface{}) (*Link, error) {
+	links, err := store.queryLinks(query, args...)
+	if err != nil || len(links) <= 0 {
+		return nil, err
+	}
+	return links[0], nil
+}
+
+func (store *SQLStore) queryLinks(query string, args ...interface{}) ([]*Link, error) {
+	rows, err := store.query(store.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*Link
+	for rows.Next() {
+		link := &Link{}
+		err := rows.Scan(&link.ID, &link.Nick, &link.URL, &link.CanonicalURL, &link.Server, &link.Channel, &link.Reposts, &link.CreatedAt, &link.Dead, &link.Archived)
+		if err != nil {
+			return nil, err
+		}
+		link.CreatedAt = store.dialect.scanTime(link.CreatedAt)
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// where compiles the filter into a parameterized WHERE clause, or an empty string.
+func (filter *LinkFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Server != "" {
+		add("server=$%d", filter.Server)
+	}
+	if filter.Channel != "" {
+		add("channel=$%d", filter.Channel)
+	}
+	if filter.Nick != "" {
+		add("nick=$%d", filter.Nick)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	if len(conditions) <= 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (store *SQLStore) exec(q sqlQueryer, query string, args ...interface{}) (sql.Result, error) {
+	query, args = store.dialect.rebind(query, args)
+	return q.Exec(query, args...)
+}
+
+func (store *SQLStore) query(q sqlQueryer, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = store.dialect.rebind(query, args)
+	return q.Query(query, args...)
+}
+
+func (store *SQLStore) queryRow(q sqlQueryer, query string, args ...interface{}) *sql.Row {
+	query, args = store.dialect.rebind(query, args)
+	return q.QueryRow(query, args...)
+}
+
+// localTime reinterprets a "timestamp without time zone" column, which the
+// driver returns as UTC, as local time (the zone it was saved in).
+func localTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
@@ -0,0 +1,56 @@
+package scumbag
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// SQLite has no regexp substring(), so linkHostSQL calls back into Go.
+const sqliteLinkHostSQL = "link_host(COALESCE(canonical_url, url))"
+
+var sqliteDialect = &sqlDialect{
+	replacer: strings.NewReplacer(linkHostSQL, sqliteLinkHostSQL, " ILIKE ", " LIKE "),
+
+	// Timestamps are stored as text and compared as strings, so they all need the same zone.
+	bindTime: func(t time.Time) time.Time { return t.UTC() },
+	scanTime: func(t time.Time) time.Time { return t.Local() },
+
+	migrationDir: "migrations/sqlite",
+}
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("link_host", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		url, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+
+		match := linkHostRegexp.FindStringSubmatch(url)
+		if match == nil {
+			return nil, nil
+		}
+		return strings.ToLower(match[1]), nil
+	})
+}
+
+// NewSQLiteStore returns a new SQLStore on a SQLite database.
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: sqliteDialect}
+}
+
+// openSQLite opens (or creates) the SQLite database at `path`.
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; serialize everything rather than handle SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
@@ -0,0 +1,824 @@
+package scumbag
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	_ LinkStore       = (*MemoryStore)(nil)
+	_ IgnoreStore     = (*MemoryStore)(nil)
+	_ SeenStore       = (*MemoryStore)(nil)
+	_ MemoStore       = (*MemoryStore)(nil)
+	_ ReminderStore   = (*MemoryStore)(nil)
+	_ KarmaStore      = (*MemoryStore)(nil)
+	_ QuoteStore      = (*MemoryStore)(nil)
+	_ FactoidStore    = (*MemoryStore)(nil)
+	_ ChannelLogStore = (*MemoryStore)(nil)
+	_ PollStore       = (*MemoryStore)(nil)
+	_ TriviaStore     = (*MemoryStore)(nil)
+	_ LinkStore       = (*SQLStore)(nil)
+	_ IgnoreStore     = (*SQLStore)(nil)
+	_ SeenStore       = (*SQLStore)(nil)
+	_ MemoStore       = (*SQLStore)(nil)
+	_ ReminderStore   = (*SQLStore)(nil)
+	_ KarmaStore      = (*SQLStore)(nil)
+	_ QuoteStore      = (*SQLStore)(nil)
+	_ FactoidStore    = (*SQLStore)(nil)
+	_ ChannelLogStore = (*SQLStore)(nil)
+	_ PollStore       = (*SQLStore)(nil)
+	_ TriviaStore     = (*SQLStore)(nil)
+)
+
+type testStore interface {
+	LinkStore
+	IgnoreStore
+	SeenStore
+	MemoStore
+	ReminderStore
+	KarmaStore
+	QuoteStore
+	FactoidStore
+	ChannelLogStore
+	PollStore
+	TriviaStore
+}
+
+// newTestSQLiteStore returns a SQLStore on a new, migrated SQLite database.
+func newTestSQLiteStore(t *testing.T) *SQLStore {
+	dir, err := ioutil.TempDir("", "scumbag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := openSQLite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLiteStore(db)
+	if _, err := store.migrateUp(0); err != nil {
+		t.Fatalf("Error migrating: %s", err)
+	}
+
+	return store
+}
+
+// countPurges returns how many purges `store` has logged.
+func countPurges(t *testing.T, store LinkStore) int {
+	switch store := store.(type) {
+	case *MemoryStore:
+		return len(store.purges)
+	case *SQLStore:
+		var count int
+		if err := store.db.QueryRow("SELECT COUNT(*) FROM link_purges;").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	t.Fatalf("Unknown store %T", store)
+	return 0
+}
+
+// testStores runs `test` against each store implementation.
+func testStores(t *testing.T, test func(*testing.T, testStore)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryStore()) })
+	t.Run("sqlite", func(t *testing.T) { test(t, newTestSQLiteStore(t)) })
+}
+
+func saveTestLinks(t *testing.T, store LinkStore) {
+	now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	for i, link := range []*Link{
+		{Nick: "alice", URL: "https://www.youtube.com/watch?v=1", Channel: "#scumbag"},
+		{Nick: "bob", URL: "https://m.youtube.com/watch?v=2", Channel: "#scumbag"},
+		{Nick: "alice", URL: "https://golang.org/doc/", Channel: "#scumbag"},
+		{Nick: "alice", URL: "https://example.com/", Channel: "#other"},
+	} {
+		link.Server = "irc.example.com"
+		link.CanonicalURL = CanonicalURL(link.URL)
+		link.CreatedAt = now.Add(time.Duration(i) * time.Hour)
+		if err := store.SaveLink(link); err != nil {
+			t.Fatalf("Error saving link: %s", err)
+		}
+	}
+}
+
+func TestStoreSearchLinks(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		saveTestLinks(t, store)
+		testSearchLinks(t, store)
+	})
+}
+
+func testSearchLinks(t *testing.T, store LinkStore) {
+	now := time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		query    string
+		expected []int64
+	}{
+		{"alice", []int64{3, 1}},
+		{"domain:youtube.com", []int64{2, 1}},
+		{"/GOLANG/", []int64{3}},
+		{"/_/", nil},
+		{"/%/", nil},
+		{"limit:1", []int64{3}},
+		{"until:2020-03-15", nil},
+	} {
+		query, err := ParseLinkQuery(tc.query, now)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s", tc.query, err)
+		}
+
+		links, err := store.SearchLinks(query, "irc.example.com", "#scumbag")
+		if err != nil {
+			t.Fatalf("Error searching %q: %s", tc.query, err)
+		}
+
+		if len(links) != len(tc.expected) {
+			t.Errorf("%q: expected %d links, got %d", tc.query, len(tc.expected), len(links))
+			continue
+		}
+		for i, link := range links {
+			if link.ID != tc.expected[i] {
+				t.Errorf("%q: expected link %d, got %d", tc.query, tc.expected[i], link.ID)
+			}
+		}
+	}
+}
+
+func TestStoreLinks(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		saveTestLinks(t, store)
+		testLinks(t, store)
+	})
+}
+
+func testLinks(t *testing.T, store LinkStore) {
+
+	link, err := store.FindLink("irc.example.com", "#scumbag", "http://youtube.com/watch?v=1", "https://youtube.com/watch?v=1")
+	if err != nil || link == nil || link.ID != 1 {
+		t.Fatalf("Expected link 1, got %+v (%v)", link, err)
+	}
+
+	store.AddRepost(1)
+	store.TagLink(1, "music", "bob", time.Now())
+	if changed, _ := store.FavoriteLink(1, "irc.example.com", "bob", time.Now()); !changed {
+		t.Errorf("Expected favorite to be added")
+	}
+	if changed, _ := store.FavoriteLink(1, "irc.example.com", "bob", time.Now()); changed {
+		t.Errorf("Expected duplicate favorite to be ignored")
+	}
+
+	query, _ := ParseLinkQuery("tag:music fav:bob", time.Now())
+	if links, _ := store.SearchLinks(query, "irc.example.com", "#scumbag"); len(links) != 1 || links[0].Reposts != 1 {
+		t.Errorf("Expected tagged favorite with 1 repost, got %+v", links)
+	}
+
+	stats, err := store.LinkStats(&LinkQuery{Limit: searchLimit}, "irc.example.com", "#scumbag")
+	if err != nil {
+		t.Fatalf("Error getting stats: %s", err)
+	}
+	if stats.Total != 3 || stats.TopPosters[0].Name != "alice" || len(stats.TopDomains) != 3 || stats.MostReposted.ID != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	stats, err = store.LinkStats(&LinkQuery{Limit: searchLimit, Until: time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC)}, "irc.example.com", "#scumbag")
+	if err != nil {
+		t.Fatalf("Error getting stats: %s", err)
+	}
+	if day := stats.PerDay[len(stats.PerDay)-2]; day.Name != "Mar 15" || day.Count != 3 {
+		t.Errorf("Expected 3 links on Mar 15, got %+v", day)
+	}
+	if week := stats.PerWeek[len(stats.PerWeek)-2]; week.Name != "Mar 9" || week.Count != 3 {
+		t.Errorf("Expected 3 links in the week of Mar 9, got %+v", week)
+	}
+
+	ids, err := store.PurgeLinks(&LinkPurge{LinkFilter: LinkFilter{Server: "irc.example.com", Nick: "alice"}})
+	if err != nil || len(ids) != 3 {
+		t.Errorf("Expected 3 links purged, got %v (%v)", ids, err)
+	}
+	if ids, err := store.PurgeLinks(&LinkPurge{LinkFilter: LinkFilter{Server: "irc.example.com", Nick: "alice"}}); err != nil || len(ids) != 0 {
+		t.Errorf("Expected nothing left to purge, got %v (%v)", ids, err)
+	}
+	if purges := countPurges(t, store); purges != 1 {
+		t.Errorf("Expected only the purge that deleted links to be logged, got %d", purges)
+	}
+	if count, _ := store.CountLinks(&LinkFilter{}); count != 1 {
+		t.Errorf("Expected 1 link left, got %d", count)
+	}
+	if tags, _ := store.LinkTags(1); len(tags) != 0 {
+		t.Errorf("Expected purged link to have no tags, got %v", tags)
+	}
+}
+
+func TestStoreSaveLinks(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		saveTestLinks(t, store)
+
+		links := []*Link{
+			{Nick: "alice", URL: "https://example.com/a", Server: "irc.example.com", Channel: "#scumbag", CreatedAt: time.Now()},
+			{Nick: "bob", URL: "https://example.com/b", Server: "irc.example.com", Channel: "#scumbag", CreatedAt: time.Now()},
+		}
+		if err := store.SaveLinks(links); err != nil {
+			t.Fatalf("Error saving links: %s", err)
+		}
+
+		if links[0].ID != 5 || links[1].ID != 6 {
+			t.Errorf("Expected IDs 5 and 6, got %d and %d", links[0].ID, links[1].ID)
+		}
+		if count, _ := store.CountLinks(&LinkFilter{}); count != 6 {
+			t.Errorf("Expected 6 links, got %d", count)
+		}
+	})
+}
+
+func TestStoreCanonicalizeLinks(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		saveTestLinks(t, store)
+		now := time.Now()
+
+		// Link 2 is a duplicate of link 1, with tags and favorites of its own.
+		store.TagLink(1, "music", "alice", now)
+		store.TagLink(2, "music", "bob", now)
+		store.TagLink(2, "video", "bob", now)
+		store.FavoriteLink(1, "irc.example.com", "alice", now)
+		store.FavoriteLink(2, "irc.example.com", "alice", now)
+		store.FavoriteLink(2, "irc.example.com", "carol", now)
+
+		if err := store.CanonicalizeLinks(map[int64]string{3: "golang.org/doc"}, []*LinkMerge{{DuplicateID: 2, OriginalID: 1, Reposts: 1}}); err != nil {
+			t.Fatalf("Error merging: %s", err)
+		}
+
+		if link, _ := store.GetLink("irc.example.com", "#scumbag", 3); link == nil || link.CanonicalURL != "golang.org/doc" {
+			t.Errorf("Expected canonical URL to be set, got %+v", link)
+		}
+
+		if link, _ := store.GetLink("irc.example.com", "#scumbag", 2); link != nil {
+			t.Errorf("Expected duplicate to be deleted, got %+v", link)
+		}
+		if tags, _ := store.LinkTags(1); !reflect.DeepEqual(tags, []string{"music", "video"}) {
+			t.Errorf("Expected merged tags, got %v", tags)
+		}
+		if tags, _ := store.LinkTags(2); len(tags) != 0 {
+			t.Errorf("Expected no tags left on the duplicate, got %v", tags)
+		}
+
+		for _, nick := range []string{"alice", "carol"} {
+			query, _ := ParseLinkQuery("fav:"+nick, now)
+			if links, _ := store.SearchLinks(query, "irc.example.com", "#scumbag"); len(links) != 1 || links[0].ID != 1 || links[0].Reposts != 1 {
+				t.Errorf("Expected %s's favorite to be link 1, got %+v", nick, links)
+			}
+		}
+	})
+}
+
+func TestStoreIgnores(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		testIgnores(t, store)
+	})
+}
+
+func testIgnores(t *testing.T, store IgnoreStore) {
+
+	if ignored, _ := store.IgnoreNick("irc.example.com", "troll", time.Now()); !ignored {
+		t.Errorf("Expected nick to be ignored")
+	}
+	if ignored, _ := store.IgnoreNick("irc.example.com", "troll", time.Now()); ignored {
+		t.Errorf("Expected nick to already be ignored")
+	}
+	if ignored, _ := store.IgnoredNick("irc.example.com", "troll"); !ignored {
+		t.Errorf("Expected IgnoredNick to be true")
+	}
+	if ignored, _ := store.IgnoredNick("irc.other.com", "troll"); ignored {
+		t.Errorf("Expected ignores to be per server")
+	}
+
+	store.UnignoreNick("irc.example.com", "troll")
+	if ignored, _ := store.IgnoredNick("irc.example.com", "troll"); ignored {
+		t.Errorf("Expected nick to be unignored")
+	}
+}
+
+func TestSQLStoreCopyTable(t *testing.T) {
+	source := newTestSQLiteStore(t)
+	saveTestLinks(t, source)
+	source.TagLink(1, "music", "bob", time.Now())
+
+	destination := newTestSQLiteStore(t)
+	for _, table := range copyTables {
+		if _, err := source.copyTable(destination, table); err != nil {
+			t.Fatalf("%s: %s", table.name, err)
+		}
+	}
+
+	expected, _ := source.Links(&LinkFilter{})
+	links, err := destination.Links(&LinkFilter{})
+	if err != nil || len(links) != len(expected) {
+		t.Fatalf("Expected %d links, got %d (%v)", len(expected), len(links), err)
+	}
+	for i, link := range links {
+		if *link != *expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], link)
+		}
+	}
+
+	if tags, _ := destination.LinkTags(1); len(tags) != 1 || tags[0] != "music" {
+		t.Errorf("Expected tag to be copied, got %v", tags)
+	}
+
+	if _, err := source.copyTable(destination, copyTables[0]); err == nil {
+		t.Errorf("Expected copying into a non-empty table to fail")
+	}
+}
+
+func TestStoreSeen(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		for i, seen := range []*Seen{
+			{Channel: "#scumbag", Nick: "Bob", Action: seenMessage, Message: "first"},
+			{Channel: "#other", Nick: "bob", Action: seenJoin},
+			{Channel: "#scumbag", Nick: "bob", Action: seenMessage, Message: "second"},
+			{Channel: "", Nick: "BOB", Action: seenQuit, Message: "bye"},
+			{Channel: "#scumbag", Nick: "alice", Action: seenPart},
+		} {
+			seen.Server = server
+			seen.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+			if err := store.SaveSeen(seen); err != nil {
+				t.Fatalf("Error saving seen: %s", err)
+			}
+		}
+
+		for _, tc := range []struct {
+			channel, nick string
+			action, msg   string
+			minutes       int
+		}{
+			{"", "bob", seenQuit, "bye", 3},
+			{"#scumbag", "BoB", seenMessage, "second", 2},
+			{"#other", "bob", seenJoin, "", 1},
+			{"#scumbag", "alice", seenPart, "", 4},
+		} {
+			seen, err := store.LastSeen(server, tc.channel, tc.nick)
+			if err != nil {
+				t.Fatalf("Error getting seen: %s", err)
+			}
+			if seen == nil {
+				t.Errorf("%s %s: not seen", tc.channel, tc.nick)
+				continue
+			}
+			if seen.Action != tc.action || seen.Message != tc.msg || !seen.CreatedAt.Equal(now.Add(time.Duration(tc.minutes)*time.Minute)) {
+				t.Errorf("%s %s: got %+v", tc.channel, tc.nick, seen)
+			}
+		}
+
+		for _, tc := range []struct{ server, channel, nick string }{
+			{server, "", "carol"},
+			{server, "#other", "alice"},
+			{"irc.other.com", "", "bob"},
+		} {
+			if seen, err := store.LastSeen(tc.server, tc.channel, tc.nick); err != nil || seen != nil {
+				t.Errorf("%s %s %s: expected nil, got %+v, %v", tc.server, tc.channel, tc.nick, seen, err)
+			}
+		}
+	})
+}
+
+func TestStoreMemos(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		var memos []*Memo
+		for i, memo := range []*Memo{
+			{Sender: "alice", Recipient: "Bob", Message: "one", Channel: "#scumbag"},
+			{Sender: "carol", Recipient: "bob", Message: "two", Private: true},
+			{Sender: "Alice", Recipient: "carol", Message: "three", Channel: "#scumbag"},
+			{Sender: "alice", Recipient: "BOB", Message: "four", Channel: "#scumbag"},
+		} {
+			memo.Server = server
+			memo.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+			if err := store.SaveMemo(memo); err != nil {
+				t.Fatalf("Error saving memo: %s", err)
+			}
+			if memo.ID == 0 {
+				t.Fatal("SaveMemo() didn't set the ID")
+			}
+			memos = append(memos, memo)
+		}
+
+		sent, err := store.SentMemos(server, "ALICE")
+		if err != nil {
+			t.Fatalf("Error getting sent memos: %s", err)
+		}
+		if len(sent) != 3 || sent[0].Message != "one" || sent[2].Message != "four" || !sent[0].CreatedAt.Equal(now) {
+			t.Errorf("Wrong sent memos: %+v", sent)
+		}
+
+		if cancelled, err := store.CancelMemo(server, "carol", memos[3].ID); err != nil || cancelled {
+			t.Errorf("Cancelled another nick's memo: %v, %v", cancelled, err)
+		}
+		if cancelled, err := store.CancelMemo(server, "alice", memos[3].ID); err != nil || !cancelled {
+			t.Errorf("Memo not cancelled: %v, %v", cancelled, err)
+		}
+
+		taken, err := store.TakeMemos(server, "bob")
+		if err != nil {
+			t.Fatalf("Error taking memos: %s", err)
+		}
+		if len(taken) != 2 || taken[0].Message != "one" || taken[1].Message != "two" || !taken[1].Private || taken[0].Channel != "#scumbag" {
+			t.Errorf("Wrong memos taken: %+v", taken)
+		}
+
+		if taken, err := store.TakeMemos(server, "bob"); err != nil || len(taken) != 0 {
+			t.Errorf("Memos delivered twice: %+v, %v", taken, err)
+		}
+
+		if taken, err := store.TakeMemos("irc.other.com", "carol"); err != nil || len(taken) != 0 {
+			t.Errorf("Memos taken on the wrong server: %+v, %v", taken, err)
+		}
+	})
+}
+
+func TestStoreReminders(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		var reminders []*Reminder
+		for i, reminder := range []*Reminder{
+			{Nick: "alice", Channel: "#scumbag", Mention: true, Message: "later"},
+			{Nick: "bob", Channel: "#scumbag", Message: "standup", Repeat: reminderWeekdays},
+			{Nick: "Alice", Channel: "alice", Mention: true, Message: "sooner"},
+		} {
+			reminder.Server = server
+			reminder.Timezone = "UTC"
+			reminder.RemindAt = now.Add(time.Duration(2-i) * time.Hour)
+			reminder.CreatedAt = now
+			if err := store.SaveReminder(reminder); err != nil {
+				t.Fatalf("Error saving reminder: %s", err)
+			}
+			reminders = append(reminders, reminder)
+		}
+
+		mine, err := store.NickReminders(server, "ALICE")
+		if err != nil {
+			t.Fatalf("Error getting reminders: %s", err)
+		}
+		if len(mine) != 2 || mine[0].Message != "sooner" || mine[1].Message != "later" || !mine[1].RemindAt.Equal(now.Add(2*time.Hour)) {
+			t.Errorf("Wrong reminders: %+v", mine)
+		}
+
+		due, err := store.DueReminders(now.Add(90 * time.Minute))
+		if err != nil {
+			t.Fatalf("Error getting due reminders: %s", err)
+		}
+		if len(due) != 2 || due[0].ID != reminders[2].ID || due[1].ID != reminders[1].ID || due[1].Repeat != reminderWeekdays {
+			t.Errorf("Wrong due reminders: %+v", due)
+		}
+
+		if err := store.RescheduleReminder(reminders[1].ID, now.Add(24*time.Hour)); err != nil {
+			t.Fatalf("Error rescheduling reminder: %s", err)
+		}
+		if err := store.DeleteReminder(reminders[2].ID); err != nil {
+			t.Fatalf("Error deleting reminder: %s", err)
+		}
+		if due, err := store.DueReminders(now.Add(90 * time.Minute)); err != nil || len(due) != 0 {
+			t.Errorf("Expected no due reminders, got %+v, %v", due, err)
+		}
+
+		if cancelled, err := store.CancelReminder(server, "bob", reminders[0].ID); err != nil || cancelled {
+			t.Errorf("Cancelled another nick's reminder: %v, %v", cancelled, err)
+		}
+		if cancelled, err := store.CancelReminder(server, "alice", reminders[0].ID); err != nil || !cancelled {
+			t.Errorf("Reminder not cancelled: %v, %v", cancelled, err)
+		}
+		if mine, err := store.NickReminders(server, "alice"); err != nil || len(mine) != 0 {
+			t.Errorf("Expected no reminders, got %+v, %v", mine, err)
+		}
+	})
+}
+
+func TestStoreKarma(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		for i, vote := range []*KarmaVote{
+			{Thing: "bob", Nick: "alice", Delta: 1, Reason: "first"},
+			{Thing: "bob", Nick: "carol", Delta: 1},
+			{Thing: "bob", Nick: "dave", Delta: -1, Reason: "second"},
+			{Thing: "go", Nick: "Alice", Delta: 1},
+			{Thing: "go", Nick: "bob", Delta: 1},
+			{Thing: "go", Nick: "carol", Delta: 1},
+			{Thing: "php", Nick: "bob", Delta: -1},
+			{Thing: "bob", Nick: "alice", Delta: 1, Channel: "#other"},
+		} {
+			vote.Server = server
+			if vote.Channel == "" {
+				vote.Channel = "#scumbag"
+			}
+			vote.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+			if err := store.AddKarma(vote); err != nil {
+				t.Fatalf("Error adding karma: %s", err)
+			}
+		}
+
+		karma, err := store.Karma(server, "#scumbag", "bob", 3)
+		if err != nil {
+			t.Fatalf("Error getting karma: %s", err)
+		}
+		if karma.Score != 1 || karma.Up != 2 || karma.Down != 1 || !reflect.DeepEqual(karma.Reasons, []string{"second", "first"}) {
+			t.Errorf("Wrong karma: %+v", karma)
+		}
+
+		if karma, err := store.Karma(server, "#scumbag", "nobody", 3); err != nil || karma.Up+karma.Down != 0 {
+			t.Errorf("Expected no karma, got %+v, %v", karma, err)
+		}
+
+		top, err := store.TopKarma(server, "#scumbag", 2, false)
+		if err != nil {
+			t.Fatalf("Error getting top karma: %s", err)
+		}
+		if len(top) != 2 || top[0].Thing != "go" || top[0].Score != 3 || top[1].Thing != "bob" || top[1].Score != 1 {
+			t.Errorf("Wrong top karma: %+v", top)
+		}
+
+		bottom, err := store.TopKarma(server, "#scumbag", 1, true)
+		if err != nil {
+			t.Fatalf("Error getting bottom karma: %s", err)
+		}
+		if len(bottom) != 1 || bottom[0].Thing != "php" || bottom[0].Score != -1 {
+			t.Errorf("Wrong bottom karma: %+v", bottom)
+		}
+
+		last, err := store.LastKarmaVote(server, "#scumbag", "ALICE", "go")
+		if err != nil || !last.Equal(now.Add(3*time.Minute)) {
+			t.Errorf("Wrong last vote: %s, %v", last, err)
+		}
+		if last, err := store.LastKarmaVote(server, "#scumbag", "alice", "php"); err != nil || !last.IsZero() {
+			t.Errorf("Expected no last vote, got %s, %v", last, err)
+		}
+	})
+}
+
+func TestStoreQuotes(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		var quotes []*Quote
+		for _, quote := range []*Quote{
+			{Channel: "#scumbag", Text: "<bob> first", AddedBy: "alice"},
+			{Channel: "#scumbag", Nick: "bob", Text: "second", AddedBy: "alice"},
+			{Channel: "#other", Nick: "bob", Text: "elsewhere", AddedBy: "carol"},
+		} {
+			quote.Server = server
+			quote.CreatedAt = now
+			if err := store.SaveQuote(quote); err != nil {
+				t.Fatalf("Error saving quote: %s", err)
+			}
+			quotes = append(quotes, quote)
+		}
+
+		quote, err := store.GetQuote(server, "#scumbag", quotes[1].ID)
+		if err != nil || quote == nil || quote.Nick != "bob" || quote.Text != "second" || quote.AddedBy != "alice" || !quote.CreatedAt.Equal(now) {
+			t.Errorf("Wrong quote: %+v, %v", quote, err)
+		}
+		if quote, err := store.GetQuote(server, "#scumbag", quotes[2].ID); err != nil || quote != nil {
+			t.Errorf("Got another channel's quote: %+v, %v", quote, err)
+		}
+
+		if quote, err := store.RandomQuote(server, "#other"); err != nil || quote == nil || quote.ID != quotes[2].ID {
+			t.Errorf("Wrong random quote: %+v, %v", quote, err)
+		}
+		if quote, err := store.RandomQuote(server, "#empty"); err != nil || quote != nil {
+			t.Errorf("Expected no random quote, got %+v, %v", quote, err)
+		}
+
+		all, err := store.Quotes(server, "#scumbag")
+		if err != nil || len(all) != 2 || all[0].ID != quotes[0].ID || all[1].ID != quotes[1].ID {
+			t.Errorf("Wrong quotes: %+v, %v", all, err)
+		}
+
+		if deleted, err := store.DeleteQuote(server, "#other", quotes[0].ID); err != nil || deleted {
+			t.Errorf("Deleted a quote from the wrong channel: %v, %v", deleted, err)
+		}
+		if deleted, err := store.DeleteQuote(server, "#scumbag", quotes[0].ID); err != nil || !deleted {
+			t.Errorf("Quote not deleted: %v, %v", deleted, err)
+		}
+		if all, err := store.Quotes(server, "#scumbag"); err != nil || len(all) != 1 {
+			t.Errorf("Expected one quote, got %+v, %v", all, err)
+		}
+	})
+}
+
+func TestStoreFactoids(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		factoid := &Factoid{Server: server, Channel: "#scumbag", Name: "faq", Value: "read it", Nick: "alice", CreatedAt: now, UpdatedAt: now}
+		if err := store.SaveFactoid(factoid, factoidLearned); err != nil {
+			t.Fatalf("Error saving factoid: %s", err)
+		}
+		id := factoid.ID
+
+		global := &Factoid{Server: server, Name: "faq", Value: "global", Nick: "admin_nick", CreatedAt: now, UpdatedAt: now}
+		if err := store.SaveFactoid(global, factoidLearned); err != nil {
+			t.Fatalf("Error saving factoid: %s", err)
+		}
+
+		edited := &Factoid{Server: server, Channel: "#scumbag", Name: "faq", Value: "read it again", Locked: true, Nick: "bob", CreatedAt: now.Add(time.Hour), UpdatedAt: now.Add(time.Hour)}
+		if err := store.SaveFactoid(edited, factoidEdited); err != nil {
+			t.Fatalf("Error saving factoid: %s", err)
+		}
+		if edited.ID != id {
+			t.Errorf("Editing changed the ID from %d to %d", id, edited.ID)
+		}
+
+		found, err := store.GetFactoid(server, "#scumbag", "faq")
+		if err != nil || found == nil || found.Value != "read it again" || !found.Locked || found.Nick != "bob" ||
+			!found.CreatedAt.Equal(now) || !found.UpdatedAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("Wrong factoid: %+v, %v", found, err)
+		}
+
+		if found, err := store.GetFactoid(server, "", "faq"); err != nil || found == nil || found.Value != "global" {
+			t.Errorf("Wrong global factoid: %+v, %v", found, err)
+		}
+		if found, err := store.GetFactoid(server, "#other", "faq"); err != nil || found != nil {
+			t.Errorf("Expected no factoid, got %+v, %v", found, err)
+		}
+
+		if deleted, err := store.DeleteFactoid(server, "#scumbag", "faq", "carol", now.Add(2*time.Hour)); err != nil || !deleted {
+			t.Errorf("Factoid not deleted: %v, %v", deleted, err)
+		}
+		if deleted, err := store.DeleteFactoid(server, "#scumbag", "faq", "carol", now.Add(2*time.Hour)); err != nil || deleted {
+			t.Errorf("Factoid deleted twice: %v, %v", deleted, err)
+		}
+
+		edits, err := store.FactoidEdits(server, "#scumbag", "faq", 5)
+		if err != nil {
+			t.Fatalf("Error getting edits: %s", err)
+		}
+		var actions []string
+		for _, edit := range edits {
+			actions = append(actions, edit.Nick+" "+edit.Action+" "+edit.Value)
+		}
+		expected := []string{"carol forgot ", "bob edited read it again", "alice learned read it"}
+		if !reflect.DeepEqual(actions, expected) {
+			t.Errorf("Expected edits %q, got %q", expected, actions)
+		}
+	})
+}
+
+func TestStoreChannelLog(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		start := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		for i, line := range []*LogLine{
+			{Channel: "#scumbag", Nick: "bob", Kind: logJoin},
+			{Channel: "#scumbag", Nick: "bob", Kind: logMessage, Text: "hi"},
+			{Channel: "#scumbag", Nick: "alice", Kind: logAction, Text: "waves"},
+			{Channel: "#other", Nick: "bob", Kind: logMessage, Text: "elsewhere"},
+		} {
+			line.Server = server
+			line.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+			if err := store.SaveLogLine(line); err != nil {
+				t.Fatalf("Error saving log line: %s", err)
+			}
+			if line.ID == 0 {
+				t.Error("Log line ID not set")
+			}
+		}
+
+		lines, err := store.LogLines(&LinkFilter{Server: server, Channel: "#scumbag"}, 0)
+		if err != nil || len(lines) != 3 || lines[0].Kind != logJoin || lines[2].Text != "waves" || !lines[2].CreatedAt.Equal(start.Add(2*time.Minute)) {
+			t.Errorf("Wrong log lines: %+v, %v", lines, err)
+		}
+
+		lines, err = store.LogLines(&LinkFilter{Server: server, Channel: "#scumbag"}, 2)
+		if err != nil || len(lines) != 2 || lines[0].Text != "hi" || lines[1].Text != "waves" {
+			t.Errorf("Expected the newest two lines, oldest first, got %+v, %v", lines, err)
+		}
+
+		if deleted, err := store.PurgeLog(&LinkFilter{Server: server, Channel: "#scumbag", Until: start.Add(time.Minute)}); err != nil || deleted != 1 {
+			t.Errorf("Expected one line purged, got %d, %v", deleted, err)
+		}
+		if deleted, err := store.PurgeLog(&LinkFilter{Server: server, Nick: "bob"}); err != nil || deleted != 2 {
+			t.Errorf("Expected bob's two lines purged, got %d, %v", deleted, err)
+		}
+
+		lines, err = store.LogLines(&LinkFilter{Server: server}, 0)
+		if err != nil || len(lines) != 1 || lines[0].Nick != "alice" {
+			t.Errorf("Expected only alice's line left, got %+v, %v", lines, err)
+		}
+	})
+}
+
+func TestStorePolls(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		poll := &Poll{Server: server, Channel: "#scumbag", Question: "Lunch?", Options: []string{"pizza", "tacos", "sushi"}, Nick: "bob", Status: pollOpen, ClosesAt: now.Add(10 * time.Minute), CreatedAt: now}
+		if err := store.SavePoll(poll); err != nil || poll.ID == 0 {
+			t.Fatalf("Error saving poll: %d, %v", poll.ID, err)
+		}
+
+		open, err := store.OpenPoll(server, "#scumbag")
+		if err != nil || open == nil || open.ID != poll.ID || !reflect.DeepEqual(open.Options, poll.Options) || !open.ClosesAt.Equal(poll.ClosesAt) {
+			t.Errorf("Wrong open poll: %+v, %v", open, err)
+		}
+		if open, err := store.OpenPoll(server, "#other"); err != nil || open != nil {
+			t.Errorf("Expected no open poll, got %+v, %v", open, err)
+		}
+		if err := store.SavePoll(&Poll{Server: server, Channel: "#scumbag", Question: "Dinner?", Options: []string{"yes", "no"}, Nick: "alice", Status: pollOpen, ClosesAt: now.Add(time.Hour), CreatedAt: now}); err != errPollOpen {
+			t.Errorf("Expected errPollOpen saving a second open poll, got %v", err)
+		}
+
+		for _, vote := range []*PollVote{
+			{Voter: "host:bob@example.com", Nick: "bob", Choice: 1},
+			{Voter: "host:alice@example.com", Nick: "alice", Choice: 2},
+			{Voter: "host:bob@example.com", Nick: "bob_", Choice: 2},
+		} {
+			vote.PollID = poll.ID
+			vote.CreatedAt = now
+			if err := store.SaveVote(vote); err != nil {
+				t.Fatalf("Error saving vote: %s", err)
+			}
+		}
+
+		if votes, err := store.PollVotes(poll.ID); err != nil || !reflect.DeepEqual(votes, map[int]int{2: 2}) {
+			t.Errorf("Wrong votes: %v, %v", votes, err)
+		}
+
+		if due, err := store.DuePolls(now); err != nil || len(due) != 0 {
+			t.Errorf("Expected no due polls, got %+v, %v", due, err)
+		}
+		if due, err := store.DuePolls(now.Add(10 * time.Minute)); err != nil || len(due) != 1 || due[0].ID != poll.ID {
+			t.Errorf("Expected the poll to be due, got %+v, %v", due, err)
+		}
+
+		if closed, err := store.ClosePoll(poll.ID, pollClosed); err != nil || !closed {
+			t.Errorf("Poll not closed: %v, %v", closed, err)
+		}
+		if closed, err := store.ClosePoll(poll.ID, pollCancelled); err != nil || closed {
+			t.Errorf("Closed poll closed again: %v, %v", closed, err)
+		}
+		if open, err := store.OpenPoll(server, "#scumbag"); err != nil || open != nil {
+			t.Errorf("Expected no open poll, got %+v, %v", open, err)
+		}
+		if err := store.SavePoll(&Poll{Server: server, Channel: "#scumbag", Question: "Dinner?", Options: []string{"yes", "no"}, Nick: "alice", Status: pollOpen, ClosesAt: now.Add(time.Hour), CreatedAt: now}); err != nil {
+			t.Errorf("Expected a new poll once the last one closed, got %v", err)
+		}
+	})
+}
+
+func TestStoreTrivia(t *testing.T) {
+	testStores(t, func(t *testing.T, store testStore) {
+		now := time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC)
+		server := "irc.example.com"
+
+		for _, answer := range []*TriviaAnswer{
+			{Channel: "#scumbag", Nick: "bob", Points: 4, Streak: 1},
+			{Channel: "#scumbag", Nick: "Bob", Points: 2, Streak: 2},
+			{Channel: "#scumbag", Nick: "alice", Points: 3, Streak: 1},
+			{Channel: "#other", Nick: "carol", Points: 10, Streak: 1},
+		} {
+			answer.Server = server
+			answer.Question = "Which planet is red?"
+			answer.CreatedAt = now
+			if err := store.AddTriviaAnswer(answer); err != nil {
+				t.Fatalf("Error adding trivia answer: %s", err)
+			}
+		}
+
+		scores, err := store.TriviaScores(server, "#scumbag", 5)
+		if err != nil || len(scores) != 2 || !strings.EqualFold(scores[0].Nick, "bob") || scores[0].Points != 6 || scores[0].Answers != 2 || scores[0].BestStreak != 2 || scores[1].Nick != "alice" {
+			t.Errorf("Wrong scores: %+v, %v", scores, err)
+		}
+		if scores, err := store.TriviaScores(server, "#scumbag", 1); err != nil || len(scores) != 1 {
+			t.Errorf("Expected one score, got %+v, %v", scores, err)
+		}
+
+		score, err := store.TriviaScore(server, "#scumbag", "BOB")
+		if err != nil || score.Points != 6 || score.Answers != 2 || score.BestStreak != 2 {
+			t.Errorf("Wrong score: %+v, %v", score, err)
+		}
+		if score, err := store.TriviaScore(server, "#scumbag", "carol"); err != nil || score.Points != 0 || score.Answers != 0 {
+			t.Errorf("Expected no score, got %+v, %v", score, err)
+		}
+	})
+}
@@ -0,0 +1,669 @@
+package scumbag
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	triviaDefaultRounds       = 10
+	triviaDefaultQuestionTime = 60 * time.Second
+	triviaMaxRounds           = 50
+
+	// Hints given per question, evenly spread over its time. Each reveals
+	// more of the answer's letters, and is worth a point less.
+	triviaHints = 3
+
+	// Pause between a question ending and the next one.
+	triviaPause = 5 * time.Second
+
+	// Streaks this long or longer are announced.
+	triviaStreakNotice = 3
+
+	triviaTopLimit = 5
+
+	triviaStart = "start"
+	triviaStop  = "stop"
+	triviaPacks = "packs"
+	triviaTop   = "top"
+	triviaScore = "score"
+)
+
+var triviaHelp = []string{
+	cmdTrivia + " " + triviaStart + " [pack] [rounds] -- start a game in the channel, from one pack or all of them",
+	cmdTrivia + " " + triviaStop + " -- stop the game (whoever started it, or admins)",
+	cmdTrivia + " " + triviaPacks + " -- list the question packs",
+	cmdTrivia + " " + triviaTop + " or " + cmdTrivia + " " + triviaScore + " [nick] -- the channel's leaderboard, or one nick's score",
+}
+
+// TriviaAnswer is a question answered correctly in a game.
+type TriviaAnswer struct {
+	Server   string
+	Channel  string
+	Nick     string
+	Question string
+	Points   int
+	// How many questions in a row the nick had answered, counting this one.
+	Streak    int
+	CreatedAt time.Time
+}
+
+// TriviaScore is a nick's trivia totals in a channel.
+type TriviaScore struct {
+	Nick       string
+	Points     int
+	Answers    int
+	BestStreak int
+}
+
+// triviaGuess is a channel message while a game is running.
+type triviaGuess struct {
+	nick string
+	text string
+	at   time.Time
+}
+
+// triviaGame is one channel's game. run() asks each question in turn, giving
+// hints until it's answered or times out, and returns when the questions run
+// out or the game is stopped.
+type triviaGame struct {
+	server       string
+	channel      string
+	startedBy    string
+	questions    []*TriviaQuestion
+	questionTime time.Duration
+	pause        time.Duration
+
+	store    TriviaStore
+	say      func(message string, a ...interface{})
+	logError func(err error)
+
+	guesses  chan *triviaGuess
+	stop     chan struct{}
+	stopOnce sync.Once
+	quit     <-chan struct{}
+	done     chan struct{}
+
+	// Only touched by run().
+	scores     map[string]*TriviaScore
+	streakNick string
+	streak     int
+}
+
+func newTriviaGame(server, channel string, questions []*TriviaQuestion, questionTime time.Duration) *triviaGame {
+	return &triviaGame{
+		server:       server,
+		channel:      channel,
+		questions:    questions,
+		questionTime: questionTime,
+		pause:        triviaPause,
+		guesses:      make(chan *triviaGuess, 16),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		scores:       make(map[string]*TriviaScore),
+	}
+}
+
+// halt stops the game; it's safe to call more than once.
+func (game *triviaGame) halt() {
+	game.stopOnce.Do(func() { close(game.stop) })
+}
+
+// guess passes a message to the game, unless it has finished.
+func (game *triviaGame) guess(guess *triviaGuess) {
+	select {
+	case game.guesses <- guess:
+	case <-game.done:
+	}
+}
+
+func (game *triviaGame) run() {
+	defer close(game.done)
+
+	for i, question := range game.questions {
+		if i > 0 {
+			select {
+			case <-time.After(game.pause):
+			case <-game.stop:
+				return
+			case <-game.quit:
+				return
+			}
+		}
+
+		if !game.ask(i, question) {
+			return
+		}
+	}
+
+	game.say("%s", game.summary())
+}
+
+// ask asks a question and waits for an answer, giving hints as time passes.
+// It returns false if the game was stopped.
+func (game *triviaGame) ask(i int, question *TriviaQuestion) bool {
+	var category string
+	if question.Category != "" {
+		category = " [" + question.Category + "]"
+	}
+	game.say("Trivia %d/%d%s: %s", i+1, len(game.questions), category, question.Question)
+	askedAt := time.Now()
+
+	answer := question.Answers[0]
+	order := rng.Perm(len(triviaLetters(answer)))
+	hints := 0
+
+	hintTicker := time.NewTicker(game.questionTime / (triviaHints + 1))
+	defer hintTicker.Stop()
+	deadline := time.NewTimer(game.questionTime)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-game.stop:
+			return false
+		case <-game.quit:
+			return false
+
+		case guess := <-game.guesses:
+			// Late answers to the last question.
+			if guess.at.Before(askedAt) || !triviaMatch(guess.text, question.Answers) {
+				continue
+			}
+			game.correct(guess, question, triviaHints+1-hints)
+			return true
+
+		case <-hintTicker.C:
+			if hints >= triviaHints {
+				continue
+			}
+			hints++
+			game.say("Hint: %s", triviaHint(answer, order, hints))
+
+		case <-deadline.C:
+			game.streakNick, game.streak = "", 0
+			game.say("Time's up! The answer was: %s", answer)
+			return true
+		}
+	}
+}
+
+// correct scores a right answer.
+func (game *triviaGame) correct(guess *triviaGuess, question *TriviaQuestion, points int) {
+	if strings.EqualFold(guess.nick, game.streakNick) {
+		game.streak++
+	} else {
+		game.streakNick, game.streak = guess.nick, 1
+	}
+
+	key := strings.ToLower(guess.nick)
+	if game.scores[key] == nil {
+		game.scores[key] = &TriviaScore{Nick: guess.nick}
+	}
+	game.scores[key].Points += points
+
+	err := game.store.AddTriviaAnswer(&TriviaAnswer{
+		Server:    game.server,
+		Channel:   game.channel,
+		Nick:      guess.nick,
+		Question:  question.Question,
+		Points:    points,
+		Streak:    game.streak,
+		CreatedAt: guess.at,
+	})
+	if err != nil {
+		game.logError(err)
+	}
+
+	message := fmt.Sprintf("%s got it: %s (+%d)", guess.nick, question.Answers[0], points)
+	if game.streak >= triviaStreakNotice {
+		message += fmt.Sprintf(" -- %d in a row!", game.streak)
+	}
+	game.say("%s", message)
+}
+
+// summary lists the game's scores, highest first.
+func (game *triviaGame) summary() string {
+	if len(game.scores) <= 0 {
+		return "Trivia over! Nobody scored."
+	}
+
+	var scores []*TriviaScore
+	for _, score := range game.scores {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Points != scores[j].Points {
+			return scores[i].Points > scores[j].Points
+		}
+		return strings.ToLower(scores[i].Nick) < strings.ToLower(scores[j].Nick)
+	})
+
+	var formatted []string
+	for _, score := range scores {
+		formatted = append(formatted, fmt.Sprintf("%s %d", score.Nick, score.Points))
+	}
+	return "Trivia over! " + strings.Join(formatted, ", ")
+}
+
+// triviaLetters returns the positions of the letters and digits in `answer`, in runes.
+func triviaLetters(answer string) []int {
+	var letters []int
+	for i, r := range []rune(answer) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			letters = append(letters, i)
+		}
+	}
+	return letters
+}
+
+// triviaHint masks the answer's letters with "_", revealing them in `order`
+// (a permutation of its letters) a quarter at a time with each hint.
+func triviaHint(answer string, order []int, hints int) string {
+	runes := []rune(answer)
+	letters := triviaLetters(answer)
+
+	shown := make(map[int]bool)
+	for _, i := range order[:len(letters)*hints/(triviaHints+1)] {
+		shown[letters[i]] = true
+	}
+
+	for _, i := range letters {
+		if !shown[i] {
+			runes[i] = '_'
+		}
+	}
+	return string(runes)
+}
+
+// triviaNormalize lowercases an answer and drops punctuation and a leading article.
+func triviaNormalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) > 1 {
+		switch words[0] {
+		case "a", "an", "the":
+			words = words[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// triviaMatch returns true if `guess` is one of the answers, allowing a typo
+// per five letters (up to two) in answers that aren't numbers.
+func triviaMatch(guess string, answers []string) bool {
+	guess = triviaNormalize(guess)
+	if guess == "" {
+		return false
+	}
+
+	for _, answer := range answers {
+		answer = triviaNormalize(answer)
+		if guess == answer {
+			return true
+		}
+
+		if _, err := strconv.Atoi(strings.ReplaceAll(answer, " ", "")); err == nil {
+			continue
+		}
+
+		typos := len([]rune(answer)) / 5
+		if typos > 2 {
+			typos = 2
+		}
+		if typos > 0 && levenshtein(guess, answer) <= typos {
+			return true
+		}
+	}
+	return false
+}
+
+// levenshtein returns the edit distance between `a` and `b`, in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// triviaGames are the running games, one per channel.
+type triviaGames struct {
+	sync.Mutex
+	games map[string]*triviaGame
+}
+
+func newTriviaGames() *triviaGames {
+	return &triviaGames{games: make(map[string]*triviaGame)}
+}
+
+// start runs `game` in a goroutine, and returns false if its channel already has one.
+func (g *triviaGames) start(game *triviaGame) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	key := game.server + " " + game.channel
+	if g.games[key] != nil {
+		return false
+	}
+	g.games[key] = game
+
+	go func() {
+		game.run()
+
+		g.Lock()
+		defer g.Unlock()
+		if g.games[key] == game {
+			delete(g.games, key)
+		}
+	}()
+	return true
+}
+
+// get returns the channel's game, or nil.
+func (g *triviaGames) get(server, channel string) *triviaGame {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.games[server+" "+channel]
+}
+
+// stopServer stops every game on the server.
+func (g *triviaGames) stopServer(server string) {
+	g.Lock()
+	defer g.Unlock()
+
+	for _, game := range g.games {
+		if game.server == server {
+			game.halt()
+		}
+	}
+}
+
+// triviaRounds returns how many questions a game asks by default.
+func (bot *Scumbag) triviaRounds() int {
+	if bot.Config.Trivia == nil || bot.Config.Trivia.Rounds <= 0 {
+		return triviaDefaultRounds
+	}
+	return bot.Config.Trivia.Rounds
+}
+
+// triviaQuestionTime returns how long each question is open.
+func (bot *Scumbag) triviaQuestionTime() time.Duration {
+	if bot.Config.Trivia == nil || bot.Config.Trivia.QuestionTime == "" {
+		return triviaDefaultQuestionTime
+	}
+
+	questionTime, err := time.ParseDuration(bot.Config.Trivia.QuestionTime)
+	if err != nil || questionTime <= 0 {
+		bot.LogError("triviaQuestionTime()", fmt.Errorf("Invalid Trivia.QuestionTime: %s", bot.Config.Trivia.QuestionTime))
+		return triviaDefaultQuestionTime
+	}
+	return questionTime
+}
+
+// TriviaLine is called from a goroutine to pass channel messages to the channel's game.
+func (bot *Scumbag) TriviaLine(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) <= 1 || !line.Public() || strings.HasPrefix(line.Args[1], cmdPrefix) {
+		return
+	}
+
+	game := bot.triviaGames.get(conn.Config().Server, line.Target())
+	if game == nil {
+		return
+	}
+
+	game.guess(&triviaGuess{nick: line.Nick, text: line.Args[1], at: line.Time})
+}
+
+// triviaHandler stops a channel's game when the bot parts or is kicked from it.
+func (bot *Scumbag) triviaHandler(conn *irc.Conn, line *irc.Line) {
+	me := conn.Me().Nick
+
+	var channel string
+	switch {
+	case line.Cmd == "PART" && len(line.Args) > 0 && line.Nick == me:
+		channel = line.Args[0]
+	case line.Cmd == "KICK" && len(line.Args) > 1 && line.Args[1] == me:
+		channel = line.Args[0]
+	default:
+		return
+	}
+
+	if game := bot.triviaGames.get(conn.Config().Server, channel); game != nil {
+		bot.Log.WithFields(log.Fields{"server": game.server, "channel": channel}).Debug("triviaHandler(): Stopping game.")
+		game.halt()
+	}
+}
+
+// TriviaCommand runs trivia games and shows the scores.
+type TriviaCommand struct {
+	BaseCommand
+
+	bot  *Scumbag
+	conn *irc.Conn
+	line *irc.Line
+}
+
+// NewTriviaCommand returns a new TriviaCommand instance.
+func NewTriviaCommand(bot *Scumbag, conn *irc.Conn, line *irc.Line) *TriviaCommand {
+	return &TriviaCommand{bot: bot, conn: conn, line: line}
+}
+
+// Run runs the command.
+func (cmd *TriviaCommand) Run(args ...string) {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("TriviaCommand.Run()", err)
+		return
+	}
+
+	if !cmd.line.Public() {
+		cmd.bot.Msg(cmd.conn, cmd.line.Nick, "Trivia only works in channels.")
+		return
+	}
+
+	var fields []string
+	if len(args) > 0 {
+		fields = strings.Fields(args[0])
+	}
+	if len(fields) <= 0 {
+		cmd.Help()
+		return
+	}
+
+	server := cmd.conn.Config().Server
+
+	switch fields[0] {
+	case triviaStart:
+		cmd.start(server, channel, fields[1:])
+
+	case triviaStop:
+		game := cmd.bot.triviaGames.get(server, channel)
+		if game == nil {
+			cmd.bot.Msg(cmd.conn, channel, "No trivia running in %s.", channel)
+			return
+		}
+		if !strings.EqualFold(cmd.line.Nick, game.startedBy) && !cmd.bot.Admin(cmd.line.Nick) {
+			cmd.bot.Msg(cmd.conn, channel, "Only %s or admins can stop this game.", game.startedBy)
+			return
+		}
+
+		game.halt()
+		cmd.bot.Msg(cmd.conn, channel, "Trivia stopped.")
+
+	case triviaPacks:
+		packs, ok := cmd.packs(channel)
+		if !ok {
+			return
+		}
+
+		var formatted []string
+		for _, name := range triviaPackNames(packs) {
+			formatted = append(formatted, fmt.Sprintf("%s (%d)", name, len(packs[name])))
+		}
+		if len(formatted) <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "No question packs.")
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Question packs: %s", strings.Join(formatted, ", "))
+
+	case triviaTop:
+		scores, err := cmd.bot.Trivia.TriviaScores(server, channel, triviaTopLimit)
+		if err != nil {
+			cmd.bot.LogError("TriviaCommand.Run()", err)
+			return
+		}
+		if len(scores) <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "No trivia scores in %s yet.", channel)
+			return
+		}
+
+		var formatted []string
+		for _, score := range scores {
+			formatted = append(formatted, fmt.Sprintf("%s %d", score.Nick, score.Points))
+		}
+		cmd.bot.Msg(cmd.conn, channel, "Trivia top: %s", strings.Join(formatted, ", "))
+
+	case triviaScore:
+		nick := cmd.line.Nick
+		if len(fields) > 1 {
+			nick = fields[1]
+		}
+
+		score, err := cmd.bot.Trivia.TriviaScore(server, channel, nick)
+		if err != nil {
+			cmd.bot.LogError("TriviaCommand.Run()", err)
+			return
+		}
+		if score.Answers <= 0 {
+			cmd.bot.Msg(cmd.conn, channel, "%s hasn't answered any trivia here.", nick)
+			return
+		}
+		cmd.bot.Msg(cmd.conn, channel, "%s has %d points from %d answers, best streak %d.", nick, score.Points, score.Answers, score.BestStreak)
+
+	default:
+		cmd.Help()
+	}
+}
+
+// start starts a game from "[pack] [rounds]".
+func (cmd *TriviaCommand) start(server, channel string, args []string) {
+	if cmd.bot.triviaGames.get(server, channel) != nil {
+		cmd.bot.Msg(cmd.conn, channel, "Trivia's already running in %s.", channel)
+		return
+	}
+
+	var pack string
+	rounds := cmd.bot.triviaRounds()
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			rounds = n
+		} else {
+			pack = strings.ToLower(arg)
+		}
+	}
+	if rounds <= 0 || rounds > triviaMaxRounds {
+		cmd.bot.Msg(cmd.conn, channel, "Pick 1 to %d rounds.", triviaMaxRounds)
+		return
+	}
+
+	packs, ok := cmd.packs(channel)
+	if !ok {
+		return
+	}
+
+	var questions []*TriviaQuestion
+	if pack != "" {
+		if _, ok := packs[pack]; !ok {
+			cmd.bot.Msg(cmd.conn, channel, "No %s pack; try %s %s.", pack, cmdTrivia, triviaPacks)
+			return
+		}
+		questions = append(questions, packs[pack]...)
+	} else {
+		for _, name := range triviaPackNames(packs) {
+			questions = append(questions, packs[name]...)
+		}
+	}
+	if len(questions) <= 0 {
+		cmd.bot.Msg(cmd.conn, channel, "No questions to ask.")
+		return
+	}
+
+	rng.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	if len(questions) > rounds {
+		questions = questions[:rounds]
+	}
+
+	questionTime := cmd.bot.triviaQuestionTime()
+	game := newTriviaGame(server, channel, questions, questionTime)
+	game.startedBy = cmd.line.Nick
+	game.store = cmd.bot.Trivia
+	game.quit = cmd.bot.quit
+	game.say = func(message string, a ...interface{}) { cmd.bot.Msg(cmd.conn, channel, message, a...) }
+	game.logError = func(err error) { cmd.bot.LogError("triviaGame.run()", err) }
+
+	cmd.bot.Msg(cmd.conn, channel, "Trivia! %d questions, %s each. Answer in the channel.", len(questions), questionTime)
+	if !cmd.bot.triviaGames.start(game) {
+		cmd.bot.Msg(cmd.conn, channel, "Trivia's already running in %s.", channel)
+	}
+}
+
+// packs loads the question packs, and returns false if trivia isn't set up or they can't be read.
+func (cmd *TriviaCommand) packs(channel string) (map[string][]*TriviaQuestion, bool) {
+	if cmd.bot.Config.Trivia == nil || cmd.bot.Config.Trivia.PacksDir == "" {
+		cmd.bot.Msg(cmd.conn, channel, "Trivia isn't set up.")
+		return nil, false
+	}
+
+	packs, err := loadTriviaPacks(cmd.bot.Config.Trivia.PacksDir)
+	if err != nil {
+		cmd.bot.LogError("TriviaCommand.packs()", err)
+		cmd.bot.Msg(cmd.conn, channel, "Couldn't load the question packs.")
+		return nil, false
+	}
+	return packs, true
+}
+
+// Help shows the command help.
+func (cmd *TriviaCommand) Help() {
+	channel, err := cmd.Channel(cmd.line)
+	if err != nil {
+		cmd.bot.LogError("TriviaCommand.Help()", err)
+		return
+	}
+
+	for _, helpText := range triviaHelp {
+		cmd.bot.Msg(cmd.conn, channel, helpText)
+	}
+}
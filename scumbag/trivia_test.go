@@ -0,0 +1,170 @@
+package scumbag
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTriviaMatch(t *testing.T) {
+	for _, tc := range []struct {
+		guess   string
+		answers []string
+		matches bool
+	}{
+		{"pizza", []string{"Pizza"}, true},
+		{"piza", []string{"Pizza"}, true},
+		{"pasta", []string{"Pizza"}, false},
+		{"red planet", []string{"Mars", "The Red Planet"}, true},
+		{"the red plant", []string{"Mars", "The Red Planet"}, true},
+		{"Mars!", []string{"Mars", "The Red Planet"}, true},
+		{"mar", []string{"Mars"}, false},
+		{"1969", []string{"1969"}, true},
+		{"19690", []string{"19690"}, true},
+		{"19691", []string{"19690"}, false},
+		{"", []string{"Mars"}, false},
+		{"a", []string{"A"}, true},
+	} {
+		if matches := triviaMatch(tc.guess, tc.answers); matches != tc.matches {
+			t.Errorf("%q matching %q: expected %v", tc.guess, tc.answers, tc.matches)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"pizza", "pizza", 0},
+		{"pizza", "piza", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"café", "cafe", 1},
+	} {
+		if distance := levenshtein(tc.a, tc.b); distance != tc.expected {
+			t.Errorf("%q, %q: expected %d, got %d", tc.a, tc.b, tc.expected, distance)
+		}
+	}
+}
+
+func TestTriviaHint(t *testing.T) {
+	answer := "Red Planet!"
+	order := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	for hints, expected := range []string{
+		"___ ______!",
+		"Re_ ______!",
+		"Red P_____!",
+		"Red Pla___!",
+	} {
+		if hint := triviaHint(answer, order, hints); hint != expected {
+			t.Errorf("%d hints: expected %q, got %q", hints, expected, hint)
+		}
+	}
+}
+
+// triviaTestGame returns a game with short timings, and a function returning what it said.
+func triviaTestGame(questions []*TriviaQuestion, questionTime time.Duration) (*triviaGame, func() []string) {
+	var lock sync.Mutex
+	var said []string
+
+	game := newTriviaGame("irc.example.com", "#scumbag", questions, questionTime)
+	game.pause = time.Millisecond
+	game.store = NewMemoryStore()
+	game.say = func(message string, a ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		said = append(said, fmt.Sprintf(message, a...))
+	}
+	game.logError = func(err error) { panic(err) }
+
+	return game, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), said...)
+	}
+}
+
+// waitForSaid waits for the game to say something starting with `prefix`.
+func waitForSaid(t *testing.T, said func() []string, prefix string) {
+	for i := 0; i < 600; i++ {
+		for _, message := range said() {
+			if strings.HasPrefix(message, prefix) {
+				return
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Never said %q: %q", prefix, said())
+}
+
+func TestTriviaGame(t *testing.T) {
+	questions := []*TriviaQuestion{
+		{Category: "Space", Question: "Which planet is red?", Answers: []string{"Mars"}},
+		{Question: "2+2?", Answers: []string{"4", "four"}},
+		{Question: "Nobody knows this", Answers: []string{"xyzzy"}},
+	}
+	game, said := triviaTestGame(questions, time.Second)
+	go game.run()
+
+	waitForSaid(t, said, "Trivia 1/3 [Space]: Which planet is red?")
+	game.guess(&triviaGuess{nick: "alice", text: "venus", at: time.Now()})
+	game.guess(&triviaGuess{nick: "bob", text: "mars", at: time.Now()})
+	waitForSaid(t, said, "bob got it: Mars (+4)")
+
+	waitForSaid(t, said, "Trivia 2/3: 2+2?")
+	game.guess(&triviaGuess{nick: "Bob", text: "four", at: time.Now()})
+	waitForSaid(t, said, "Bob got it: 4 (+4)")
+
+	waitForSaid(t, said, "Trivia 3/3: Nobody knows this")
+	waitForSaid(t, said, "Hint: ")
+	waitForSaid(t, said, "Time's up! The answer was: xyzzy")
+
+	<-game.done
+	waitForSaid(t, said, "Trivia over! bob 8")
+
+	score, err := game.store.TriviaScore("irc.example.com", "#scumbag", "BOB")
+	if err != nil || score.Points != 8 || score.Answers != 2 || score.BestStreak != 2 {
+		t.Errorf("Wrong score: %+v, %v", score, err)
+	}
+}
+
+func TestTriviaGamesStop(t *testing.T) {
+	questions := []*TriviaQuestion{{Question: "Which planet is red?", Answers: []string{"Mars"}}}
+	games := newTriviaGames()
+
+	game, said := triviaTestGame(questions, time.Minute)
+	if !games.start(game) {
+		t.Fatal("Game not started")
+	}
+	waitForSaid(t, said, "Trivia 1/1")
+
+	other, _ := triviaTestGame(questions, time.Minute)
+	if games.start(other) {
+		t.Error("Started a second game in the channel")
+	}
+	if games.get("irc.example.com", "#scumbag") != game {
+		t.Error("Wrong game running")
+	}
+
+	games.stopServer("irc.example.com")
+	select {
+	case <-game.done:
+	case <-time.After(time.Second):
+		t.Fatal("Game didn't stop")
+	}
+
+	// A late guess doesn't block.
+	game.guess(&triviaGuess{nick: "bob", text: "mars", at: time.Now()})
+
+	for i := 0; i < 100 && games.get("irc.example.com", "#scumbag") != nil; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if games.get("irc.example.com", "#scumbag") != nil {
+		t.Error("Stopped game still registered")
+	}
+}
@@ -0,0 +1,148 @@
+package scumbag
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TriviaQuestion is a question from a pack. The first answer is the one
+// hinted and shown; the rest are also accepted.
+type TriviaQuestion struct {
+	Category string
+	Question string
+	Answers  []string
+}
+
+// triviaPackQuestion is a question as written in a pack file, with alternative
+// answers separated by "|", e.g. "Mars|The Red Planet".
+type triviaPackQuestion struct {
+	Category string
+	Question string
+	Answer   string
+}
+
+// loadTriviaPacks reads every *.json and *.csv pack in `dir`, keyed by
+// lowercase file name without the extension.
+func loadTriviaPacks(dir string) (map[string][]*TriviaQuestion, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(map[string][]*TriviaQuestion)
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".json" && ext != ".csv" {
+			continue
+		}
+
+		questions, err := readTriviaPack(file, ext)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filepath.Base(file), err)
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		packs[name] = append(packs[name], questions...)
+	}
+
+	return packs, nil
+}
+
+// triviaPackNames returns the pack names, sorted.
+func triviaPackNames(packs map[string][]*TriviaQuestion) []string {
+	var names []string
+	for name := range packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readTriviaPack(file, ext string) ([]*TriviaQuestion, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	if ext == ".json" {
+		return readTriviaJSON(in)
+	}
+	return readTriviaCSV(in)
+}
+
+// readTriviaJSON reads a JSON array of {"Category", "Question", "Answer"} objects.
+func readTriviaJSON(r io.Reader) ([]*TriviaQuestion, error) {
+	var packQuestions []*triviaPackQuestion
+	if err := json.NewDecoder(r).Decode(&packQuestions); err != nil {
+		return nil, err
+	}
+
+	var questions []*TriviaQuestion
+	for i, packQuestion := range packQuestions {
+		question, err := newTriviaQuestion(packQuestion)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %s", i+1, err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+// readTriviaCSV reads "question,answer[,category]" rows. A first row of
+// "question,answer,..." is a header and skipped.
+func readTriviaCSV(r io.Reader) ([]*TriviaQuestion, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var questions []*TriviaQuestion
+	for i, record := range records {
+		if i == 0 && len(record) >= 2 && strings.EqualFold(record[0], "question") && strings.EqualFold(record[1], "answer") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: need a question and an answer", i+1)
+		}
+
+		packQuestion := &triviaPackQuestion{Question: record[0], Answer: record[1]}
+		if len(record) > 2 {
+			packQuestion.Category = record[2]
+		}
+
+		question, err := newTriviaQuestion(packQuestion)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+func newTriviaQuestion(packQuestion *triviaPackQuestion) (*TriviaQuestion, error) {
+	question := &TriviaQuestion{
+		Category: strings.TrimSpace(packQuestion.Category),
+		Question: strings.TrimSpace(packQuestion.Question),
+	}
+
+	for _, answer := range strings.Split(packQuestion.Answer, "|") {
+		if answer = strings.TrimSpace(answer); answer != "" {
+			question.Answers = append(question.Answers, answer)
+		}
+	}
+
+	if question.Question == "" || len(question.Answers) <= 0 {
+		return nil, fmt.Errorf("need a question and an answer")
+	}
+	return question, nil
+}
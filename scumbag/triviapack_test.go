@@ -0,0 +1,59 @@
+package scumbag
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadTriviaPacks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scumbag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"Space.json": `[{"Category": "Space", "Question": "Which planet is red?", "Answer": "Mars|The Red Planet"}]`,
+		"general.csv": "question,answer,category\n" +
+			"\"2+2, in words?\",four\n" +
+			"Capital of France?,Paris,Geography\n",
+		"README.txt": "not a pack",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	packs, err := loadTriviaPacks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := triviaPackNames(packs); !reflect.DeepEqual(names, []string{"general", "space"}) {
+		t.Errorf("Wrong packs: %v", names)
+	}
+
+	expected := []*TriviaQuestion{{Category: "Space", Question: "Which planet is red?", Answers: []string{"Mars", "The Red Planet"}}}
+	if !reflect.DeepEqual(packs["space"], expected) {
+		t.Errorf("Wrong space questions: %+v", packs["space"])
+	}
+
+	expected = []*TriviaQuestion{
+		{Question: "2+2, in words?", Answers: []string{"four"}},
+		{Category: "Geography", Question: "Capital of France?", Answers: []string{"Paris"}},
+	}
+	if !reflect.DeepEqual(packs["general"], expected) {
+		t.Errorf("Wrong general questions: %+v", packs["general"])
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.csv"), []byte("No answer?\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTriviaPacks(dir); err == nil {
+		t.Error("Expected an error for a question without an answer")
+	}
+}
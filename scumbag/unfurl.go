@@ -0,0 +1,251 @@
+package scumbag
+
+import (
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	irc "github.com/fluffle/goirc/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Only read this much of a page when looking for the <title>.
+	unfurlMaxBytes = 64 * 1024
+
+	unfurlMaxLength = 300
+
+	unfurlTimeout = 10 * time.Second
+)
+
+var (
+	titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+	unfurlers []*Unfurler
+
+	// errPrivateAddress is returned for links which resolve to a non-public address.
+	errPrivateAddress = errors.New("Refusing to fetch a private address.")
+
+	// privateNetworks are the loopback, private, link-local, shared, multicast and
+	// other reserved ranges links are never fetched from.
+	privateNetworks = parseCIDRs(
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/3",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"100::/64",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	)
+
+	// publicTransport checks the address of every connection, after DNS and on
+	// each redirect, so links pasted in a channel can't be used to reach the
+	// bot's own network.
+	publicTransport = &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: unfurlTimeout,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: unfurlTimeout,
+	}
+
+	unfurlClient = &http.Client{Timeout: unfurlTimeout, Transport: publicTransport}
+)
+
+// UnfurlFunc returns a summary for a URL matched by an Unfurler.
+// `match` is the result of Unfurler.Match.FindStringSubmatch on the URL.
+// An empty summary falls back to the page <title>.
+type UnfurlFunc func(bot *Scumbag, conn *irc.Conn, line *irc.Line, match []string) (string, error)
+
+// Unfurler turns a site-specific URL into a rich summary.
+type Unfurler struct {
+	Name   string
+	Match  *regexp.Regexp
+	Format UnfurlFunc
+}
+
+// RegisterUnfurler adds an Unfurler; the first one matching a URL wins.
+func RegisterUnfurler(unfurler *Unfurler) {
+	unfurlers = append(unfurlers, unfurler)
+}
+
+// UnfurlURLs is called from a goroutine to summarize links from `line`.
+func (bot *Scumbag) UnfurlURLs(conn *irc.Conn, line *irc.Line) {
+	if len(line.Args) <= 1 {
+		return
+	}
+
+	channel := line.Args[0]
+	server := conn.Config().Server
+
+	if !unfurlChannel(bot, server, channel) {
+		return
+	}
+
+	if ignoredNick(bot, server, line.Nick) {
+		bot.Log.WithFields(log.Fields{"server": server, "nick": line.Nick}).Debug("UnfurlURLs(): Ignored nick.")
+		return
+	}
+
+	for _, url := range findURLs(line.Args[1]) {
+		summary, err := bot.unfurl(conn, line, url)
+		if err != nil {
+			bot.LogError("UnfurlURLs()", err)
+			continue
+		}
+
+		if summary != "" {
+			bot.Msg(conn, channel, "%s", truncate(summary, unfurlMaxLength))
+		}
+	}
+}
+
+func (bot *Scumbag) unfurl(conn *irc.Conn, line *irc.Line, url string) (string, error) {
+	for _, unfurler := range unfurlers {
+		if match := unfurler.Match.FindStringSubmatch(url); match != nil {
+			bot.Log.WithFields(log.Fields{"unfurler": unfurler.Name, "url": url}).Debug("unfurl()")
+
+			summary, err := unfurler.Format(bot, conn, line, match)
+			if err != nil || summary != "" {
+				return summary, err
+			}
+			break
+		}
+	}
+
+	return pageTitle(url)
+}
+
+// pageTitle returns the HTML <title> of `url`, or an empty string for non-HTML
+// content, links which aren't http(s) and ones on private addresses.
+func pageTitle(url string) (string, error) {
+	if !IsHTTPURL(url) {
+		return "", nil
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", VersionString())
+
+	resp, err := unfurlClient.Do(req)
+	if errors.Is(err, errPrivateAddress) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, unfurlMaxBytes))
+	if err != nil {
+		return "", err
+	}
+
+	match := titleRegexp.FindSubmatch(content)
+	if match == nil {
+		return "", nil
+	}
+
+	title := html.UnescapeString(string(match[1]))
+	return strings.Join(strings.Fields(title), " "), nil
+}
+
+// IsHTTPURL reports whether `rawURL` is an http or https URL with a host.
+func IsHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// publicAddressOnly is a net.Dialer Control which refuses private addresses.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// publicIP reports whether `ip` is outside privateNetworks; IPv4-mapped IPv6
+// addresses are checked as IPv4.
+func publicIP(ip net.IP) bool {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func unfurlChannel(bot *Scumbag, server, channel string) bool {
+	serverConfig, err := bot.Config.Server(server)
+	if err != nil {
+		bot.LogError("unfurlChannel()", err)
+		return false
+	}
+
+	channelConfig, ok := serverConfig.Channels[channel]
+	return ok && channelConfig.UnfurlURLs
+}
+
+// truncate shortens `s` to `length` runes, adding an ellipsis if needed.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length-3]) + "..."
+}
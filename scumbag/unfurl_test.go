@@ -0,0 +1,110 @@
+package scumbag
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUnfurlerMatches(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/Oshuma/scumbago":                          "github",
+		"https://github.com/Oshuma/scumbago/pull/123":                 "github",
+		"https://github.com/Oshuma/scumbago/issues/12#issuecomment-1": "github",
+		"https://news.ycombinator.com/item?id=8863":                   "hackernews",
+		"https://www.reddit.com/r/golang/comments/abc123/some_title/": "reddit",
+		"https://redd.it/abc123":                                      "reddit",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)":     "wikipedia",
+		"https://github.com/Oshuma/scumbago/blob/master/main.go":      "",
+		"https://example.com/":                                        "",
+	}
+
+	for url, expected := range cases {
+		name := ""
+		for _, unfurler := range unfurlers {
+			if unfurler.Match.MatchString(url) {
+				name = unfurler.Name
+				break
+			}
+		}
+
+		if name != expected {
+			t.Errorf("%s: expected unfurler %q, got %q", url, expected, name)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if truncate("short", 10) != "short" {
+		t.Error("truncate() should not change short strings")
+	}
+
+	if truncate("this is too long", 10) != "this is..." {
+		t.Errorf("truncate() returned %q", truncate("this is too long", 10))
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>\n  Tom &amp; Jerry\n</title></head></html>"))
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, which is refused without an error.
+	if title, err := pageTitle(server.URL); title != "" || err != nil {
+		t.Errorf("Expected a loopback page to be skipped, got %q %v", title, err)
+	}
+
+	defer func(client *http.Client) { unfurlClient = client }(unfurlClient)
+	unfurlClient = server.Client()
+
+	if title, err := pageTitle(server.URL); title != "Tom & Jerry" || err != nil {
+		t.Errorf("Expected the page title, got %q %v", title, err)
+	}
+
+	for _, url := range []string{"ftp://example.com/", "mailto:alice@example.com", "file:///etc/passwd"} {
+		if title, err := pageTitle(url); title != "" || err != nil {
+			t.Errorf("%s: expected to be skipped, got %q %v", url, title, err)
+		}
+	}
+}
+
+func TestIsHTTPURL(t *testing.T) {
+	for url, expected := range map[string]bool{
+		"https://example.com/":     true,
+		"HTTP://example.com/a?b=c": true,
+		"ftp://example.com/":       false,
+		"mailto:alice@example.com": false,
+		"http:///path":             false,
+		"example.com":              false,
+	} {
+		if IsHTTPURL(url) != expected {
+			t.Errorf("%s: expected %v", url, expected)
+		}
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for address, expected := range map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"224.0.0.1":          false,
+		"::1":                false,
+		"::ffff:127.0.0.1":   false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+	} {
+		if publicIP(net.ParseIP(address)) != expected {
+			t.Errorf("%s: expected public to be %v", address, expected)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package scumbag
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	// Query params which only track where a link came from.
+	trackingParamPrefix = "utm_"
+
+	// Characters which end a sentence rather than a URL.
+	trailingPunctuation = `.,;:!?'"`
+)
+
+var (
+	trackingParams = map[string]bool{
+		"fbclid": true,
+		"gclid":  true,
+		"mc_cid": true,
+		"mc_eid": true,
+	}
+
+	defaultPorts = map[string]string{
+		"ftp":   "21",
+		"http":  "80",
+		"https": "443",
+	}
+
+	bracketPairs = map[byte]byte{
+		')': '(',
+		']': '[',
+	}
+)
+
+// CanonicalURL returns a normalized form of `rawURL` so different spellings of
+// the same link compare equal. http and https are treated as the same scheme,
+// the host is lowercased and punycoded with "www." and default ports dropped,
+// tracking params are removed and trailing slashes and punctuation are trimmed.
+// Unparseable URLs are returned trimmed but otherwise unchanged.
+func CanonicalURL(rawURL string) string {
+	rawURL = TrimURL(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+
+	if asciiHost, err := idna.ToASCII(host); err == nil {
+		host = asciiHost
+	}
+	host = strings.TrimPrefix(host, "www.")
+
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literal.
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for param := range query {
+			lowerParam := strings.ToLower(param)
+			if strings.HasPrefix(lowerParam, trackingParamPrefix) || trackingParams[lowerParam] {
+				query.Del(param)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// TrimURL removes trailing punctuation picked up by urlRegexp, e.g. from
+// "(see https://example.com/a)." Closing brackets are kept when they are
+// balanced inside the URL, like https://en.wikipedia.org/wiki/Go_(programming_language)
+func TrimURL(rawURL string) string {
+	for len(rawURL) > 0 {
+		last := rawURL[len(rawURL)-1]
+
+		if strings.IndexByte(trailingPunctuation, last) >= 0 {
+			rawURL = rawURL[:len(rawURL)-1]
+			continue
+		}
+
+		if open, ok := bracketPairs[last]; ok {
+			if strings.Count(rawURL, string(open)) < strings.Count(rawURL, string(last)) {
+				rawURL = rawURL[:len(rawURL)-1]
+				continue
+			}
+		}
+
+		break
+	}
+
+	return rawURL
+}
@@ -0,0 +1,50 @@
+package scumbag
+
+import (
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/a":                               "https://example.com/a",
+		"http://example.com/a":                                "https://example.com/a",
+		"https://www.example.com/a":                           "https://example.com/a",
+		"https://example.com/a/":                              "https://example.com/a",
+		"https://example.com/":                                "https://example.com",
+		"https://example.com/a?utm_source=x&utm_medium=email": "https://example.com/a",
+		"https://example.com/a?b=2&utm_source=x&a=1":          "https://example.com/a?a=1&b=2",
+		"ftp://example.com/file":                              "ftp://example.com/file",
+		"https://EXAMPLE.com/A":                               "https://example.com/A",
+		"https://example.com:443/a":                           "https://example.com/a",
+		"http://example.com:80/a":                             "https://example.com/a",
+		"https://example.com:8443/a":                          "https://example.com:8443/a",
+		"https://bücher.example/a":                            "https://xn--bcher-kva.example/a",
+		"https://example.com/a?fbclid=123":                    "https://example.com/a",
+		"https://example.com/a).":                             "https://example.com/a",
+		"https://[::1]:443/a":                                 "https://[::1]/a",
+	}
+
+	for rawURL, expected := range cases {
+		if actual := CanonicalURL(rawURL); actual != expected {
+			t.Errorf("CanonicalURL(%q): expected %q, got %q", rawURL, expected, actual)
+		}
+	}
+}
+
+func TestTrimURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/a":                                     "https://example.com/a",
+		"https://example.com/a.":                                    "https://example.com/a",
+		"https://example.com/a)":                                    "https://example.com/a",
+		"https://example.com/a!?":                                   "https://example.com/a",
+		"https://example.com/a\"":                                   "https://example.com/a",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)":   "https://en.wikipedia.org/wiki/Go_(programming_language)",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)),": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+	}
+
+	for rawURL, expected := range cases {
+		if actual := TrimURL(rawURL); actual != expected {
+			t.Errorf("TrimURL(%q): expected %q, got %q", rawURL, expected, actual)
+		}
+	}
+}
@@ -3,16 +3,27 @@ package scumbag
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strings"
 
 	irc "github.com/fluffle/goirc/client"
 )
 
 const (
-	wikiAPIURL = "https://en.wikipedia.org/w/api.php?action=opensearch&search=%s&format=json&limit=1&redirects=resolve"
-	wikiHelp   = cmdPrefix + "wp <phrase>"
+	wikiAPIURL     = "https://en.wikipedia.org/w/api.php?action=opensearch&search=%s&format=json&limit=1&redirects=resolve"
+	wikiSummaryURL = "https://en.wikipedia.org/api/rest_v1/page/summary/%s"
+	wikiHelp       = cmdPrefix + "wp <phrase>"
 )
 
+func init() {
+	RegisterUnfurler(&Unfurler{
+		Name:   "wikipedia",
+		Match:  regexp.MustCompile(`\Ahttps?://en\.(?:m\.)?wikipedia\.org/wiki/([^?#]+)`),
+		Format: unfurlWiki,
+	})
+}
+
 // WikiResult stores data returned from the API.
 type WikiResult struct {
 	Query   string
@@ -21,6 +32,12 @@ type WikiResult struct {
 	URL     []string
 }
 
+// WikiSummary stores a page summary returned from the REST API.
+type WikiSummary struct {
+	Title   string `json:"title"`
+	Extract string `json:"extract"`
+}
+
 // WikiCommand interacts with the Wikipedia API.
 type WikiCommand struct {
 	BaseCommand
@@ -49,26 +66,20 @@ func (cmd *WikiCommand) Run(args ...string) {
 		return
 	}
 
-	encodedQuery := strings.Replace(query, " ", "%20", -1)
-	requestURL := fmt.Sprintf(wikiAPIURL, encodedQuery)
-
-	content, err := getContent(requestURL)
-	if err != nil {
-		cmd.bot.LogError("WikiCommand.Run()", err)
-		return
-	}
-
-	var result WikiResult
-	resultArray := []interface{}{&result.Query, &result.Title, &result.Content, &result.URL}
-
-	err = json.Unmarshal(content, &resultArray)
+	result, err := cmd.search(query)
 	if err != nil {
 		cmd.bot.LogError("WikiCommand.Run()", err)
 		return
 	}
 
-	if len(result.Content) > 0 {
-		cmd.bot.Msg(cmd.conn, channel, result.Content[0])
+	// opensearch descriptions are always empty, so the extract comes from the page summary.
+	if len(result.Title) > 0 {
+		summary, err := wikiSummary(result.Title[0])
+		if err != nil {
+			cmd.bot.LogError("WikiCommand.Run()", err)
+		} else if summary.Extract != "" {
+			cmd.bot.Msg(cmd.conn, channel, truncate(summary.Extract, unfurlMaxLength))
+		}
 	}
 
 	if len(result.URL) > 0 {
@@ -86,3 +97,58 @@ func (cmd *WikiCommand) Help() {
 
 	cmd.bot.Msg(cmd.conn, channel, wikiHelp)
 }
+
+func (cmd *WikiCommand) search(query string) (*WikiResult, error) {
+	requestURL := fmt.Sprintf(wikiAPIURL, url.QueryEscape(query))
+
+	content, err := getContent(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var result WikiResult
+	resultArray := []interface{}{&result.Query, &result.Title, &result.Content, &result.URL}
+
+	err = json.Unmarshal(content, &resultArray)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func unfurlWiki(bot *Scumbag, conn *irc.Conn, line *irc.Line, match []string) (string, error) {
+	title, err := url.PathUnescape(match[1])
+	if err != nil {
+		return "", err
+	}
+
+	summary, err := wikiSummary(title)
+	if err != nil {
+		return "", err
+	}
+
+	if summary.Extract == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("%s: %s", summary.Title, summary.Extract), nil
+}
+
+// wikiSummary returns the summary of the page titled `title`; missing pages
+// have an empty Extract.
+func wikiSummary(title string) (*WikiSummary, error) {
+	requestURL := fmt.Sprintf(wikiSummaryURL, url.PathEscape(strings.Replace(title, " ", "_", -1)))
+
+	content, err := getContent(requestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary WikiSummary
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
@@ -41,6 +41,12 @@ func (cmd *WolframAlphaCommand) Run(args ...string) {
 		cmd.Help()
 		return
 	}
+
+	if cmd.bot.Config.WolframAlpha == nil || cmd.bot.Config.WolframAlpha.AppID == "" {
+		cmd.bot.Log.Debug("WolframAlphaCommand.Run(): No AppID; calculating locally")
+		cmd.calculate(channel, query)
+		return
+	}
 	query = url.QueryEscape(query)
 
 	requestURL := fmt.Sprintf(wolframAPIURL, cmd.bot.Config.WolframAlpha.AppID, query)
@@ -54,6 +60,23 @@ func (cmd *WolframAlphaCommand) Run(args ...string) {
 	cmd.bot.Msg(cmd.conn, channel, string(content[:]))
 }
 
+// calculate answers `query` with ?convert or ?calc instead of the API. A
+// conversion's error is only shown if it isn't a calculation either.
+func (cmd *WolframAlphaCommand) calculate(channel, query string) {
+	result, ok, err := convertQuery(query)
+	if !ok || err != nil {
+		if calculated, calcErr := cmd.bot.calc(cmd.conn.Config().Server, cmd.line.Nick, query); calcErr == nil || !ok {
+			result, err = calculated, calcErr
+		}
+	}
+	if err != nil {
+		cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, err)
+		return
+	}
+
+	cmd.bot.Msg(cmd.conn, channel, "%s: %s", cmd.line.Nick, result)
+}
+
 // Help displays the command help.
 func (cmd *WolframAlphaCommand) Help() {
 	channel, err := cmd.Channel(cmd.line)